@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"time"
+
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+)
+
+// agentConfig holds agent-wide settings, parsed from the attributes of the viam-agent subsystem's config.
+type agentConfig struct {
+	downloadBandwidthBPS int64
+
+	hardwareWatchdog    bool
+	watchdogDevice      string
+	watchdogPetInterval time.Duration
+
+	// ping systemd's watchdog (when the unit sets WatchdogSec) while viam-server is healthy
+	systemdWatchdog bool
+	// send READY=1 to systemd once viam-server is first healthy
+	systemdNotifyReady bool
+
+	unhealthyAction        UnhealthyAction
+	healthCheckConcurrency int
+	// see EscalationPolicy
+	healthCheckFailedInterval  time.Duration
+	healthCheckEscalateAfter   int
+	healthCheckDeescalateAfter int
+
+	// reap orphaned zombies, for when the agent is PID 1
+	reapOrphans bool
+}
+
+func agentConfigFromProto(logger logging.Logger, cfg *pb.DeviceSubsystemConfig) agentConfig {
+	attrs := cfg.GetAttributes().AsMap()
+	ret := agentConfig{
+		watchdogDevice:         DefaultWatchdogDevice,
+		watchdogPetInterval:    DefaultWatchdogPetInterval,
+		systemdWatchdog:        true,
+		unhealthyAction:        UnhealthyActionRestart,
+		healthCheckConcurrency: DefaultHealthCheckConcurrency,
+
+		healthCheckEscalateAfter:   1,
+		healthCheckDeescalateAfter: 1,
+	}
+
+	if raw, ok := attrs["global_download_bandwidth_bytes_per_sec"]; ok {
+		num, ok := raw.(float64)
+		if ok {
+			ret.downloadBandwidthBPS = int64(num)
+		} else {
+			logger.Warnf("invalid global_download_bandwidth_bytes_per_sec: %v", raw)
+		}
+	}
+
+	if raw, ok := attrs["hardware_watchdog"]; ok {
+		enabled, ok := raw.(bool)
+		if ok {
+			ret.hardwareWatchdog = enabled
+		} else {
+			logger.Warnf("invalid hardware_watchdog: %v", raw)
+		}
+	}
+	if raw, ok := attrs["watchdog_device"]; ok {
+		device, ok := raw.(string)
+		if ok && device != "" {
+			ret.watchdogDevice = device
+		} else {
+			logger.Warnf("invalid watchdog_device: %v", raw)
+		}
+	}
+	if raw, ok := attrs["watchdog_pet_interval"]; ok {
+		str, _ := raw.(string) //nolint:errcheck
+		interval, err := time.ParseDuration(str)
+		if err == nil && interval > 0 {
+			ret.watchdogPetInterval = interval
+		} else {
+			logger.Warnf("invalid watchdog_pet_interval: %v", raw)
+		}
+	}
+
+	if raw, ok := attrs["systemd_watchdog"]; ok {
+		enabled, ok := raw.(bool)
+		if ok {
+			ret.systemdWatchdog = enabled
+		} else {
+			logger.Warnf("invalid systemd_watchdog: %v", raw)
+		}
+	}
+	if raw, ok := attrs["systemd_notify_ready"]; ok {
+		enabled, ok := raw.(bool)
+		if ok {
+			ret.systemdNotifyReady = enabled
+		} else {
+			logger.Warnf("invalid systemd_notify_ready: %v", raw)
+		}
+	}
+
+	if raw, ok := attrs["unhealthy_action"]; ok {
+		action, _ := raw.(string) //nolint:errcheck
+		switch UnhealthyAction(action) {
+		case UnhealthyActionRestart, UnhealthyActionAlert, UnhealthyActionNone:
+			ret.unhealthyAction = UnhealthyAction(action)
+		default:
+			logger.Warnf("invalid unhealthy_action: %v", raw)
+		}
+	}
+
+	if raw, ok := attrs["health_check_concurrency"]; ok {
+		num, ok := raw.(float64)
+		if ok && num >= 1 {
+			ret.healthCheckConcurrency = int(num)
+		} else {
+			logger.Warnf("invalid health_check_concurrency: %v", raw)
+		}
+	}
+
+	if raw, ok := attrs["health_check_failed_interval"]; ok {
+		str, _ := raw.(string) //nolint:errcheck
+		interval, err := time.ParseDuration(str)
+		if err == nil && interval >= 0 {
+			ret.healthCheckFailedInterval = interval
+		} else {
+			logger.Warnf("invalid health_check_failed_interval: %v", raw)
+		}
+	}
+	if raw, ok := attrs["health_check_escalate_after"]; ok {
+		num, ok := raw.(float64)
+		if ok && num >= 1 {
+			ret.healthCheckEscalateAfter = int(num)
+		} else {
+			logger.Warnf("invalid health_check_escalate_after: %v", raw)
+		}
+	}
+	if raw, ok := attrs["health_check_deescalate_after"]; ok {
+		num, ok := raw.(float64)
+		if ok && num >= 1 {
+			ret.healthCheckDeescalateAfter = int(num)
+		} else {
+			logger.Warnf("invalid health_check_deescalate_after: %v", raw)
+		}
+	}
+
+	if raw, ok := attrs["reap_orphans"]; ok {
+		enabled, ok := raw.(bool)
+		if ok {
+			ret.reapOrphans = enabled
+		} else {
+			logger.Warnf("invalid reap_orphans: %v", raw)
+		}
+	}
+
+	return ret
+}
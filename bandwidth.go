@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// GlobalBandwidthLimiter is shared by all downloads, so that several subsystems updating at once
+// still stay within a single bandwidth budget. It is unlimited until SetLimitBPS is called.
+var GlobalBandwidthLimiter = &TokenBucket{}
+
+// TokenBucket is a simple rate limiter measured in bytes per second.
+type TokenBucket struct {
+	mu     sync.Mutex
+	limit  int64
+	tokens float64
+	last   time.Time
+}
+
+// SetLimitBPS sets the limit in bytes per second. Zero or less disables limiting.
+func (b *TokenBucket) SetLimitBPS(limit int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if limit == b.limit {
+		return
+	}
+	b.limit = limit
+	b.tokens = 0
+	b.last = time.Now()
+}
+
+// LimitBPS returns the current limit in bytes per second.
+func (b *TokenBucket) LimitBPS() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.limit
+}
+
+// Wait blocks until the given number of bytes may be transferred, or the context is cancelled.
+func (b *TokenBucket) Wait(ctx context.Context, bytes int64) error {
+	b.mu.Lock()
+	if b.limit <= 0 {
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+	b.refill()
+	// reserve the bytes now, so concurrent callers queue up behind each other
+	b.tokens -= float64(bytes)
+	delay := time.Duration(-b.tokens / float64(b.limit) * float64(time.Second))
+	b.mu.Unlock()
+
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// give back the unused reservation
+		b.mu.Lock()
+		b.tokens += float64(bytes)
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Allow is a non-blocking alternative to Wait. It takes the bytes and returns true if they're available now,
+// otherwise it takes nothing and returns false.
+func (b *TokenBucket) Allow(bytes int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limit <= 0 {
+		return true
+	}
+	b.refill()
+	if b.tokens < float64(bytes) {
+		return false
+	}
+	b.tokens -= float64(bytes)
+	return true
+}
+
+// refill adds tokens for the time since the last call, allowing at most one second of burst. Must be called with mu held.
+func (b *TokenBucket) refill() {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * float64(b.limit)
+	if b.tokens > float64(b.limit) {
+		b.tokens = float64(b.limit)
+	}
+	b.last = now
+}
+
+// limitedReader throttles reads from an underlying reader using a TokenBucket.
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *TokenBucket
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.Wait(r.ctx, int64(n)); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
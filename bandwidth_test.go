@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Run("unlimited", func(t *testing.T) {
+		bucket := &TokenBucket{}
+		start := time.Now()
+		test.That(t, bucket.Wait(context.Background(), 1<<30), test.ShouldBeNil)
+		test.That(t, time.Since(start), test.ShouldBeLessThan, 100*time.Millisecond)
+	})
+
+	t.Run("parallel-downloads", func(t *testing.T) {
+		const (
+			limit     = 256 * 1024
+			chunk     = 16 * 1024
+			perWorker = 128 * 1024
+		)
+		bucket := &TokenBucket{}
+		bucket.SetLimitBPS(limit)
+
+		var group errgroup.Group
+		start := time.Now()
+		for i := 0; i < 2; i++ {
+			group.Go(func() error {
+				for sent := 0; sent < perWorker; sent += chunk {
+					if err := bucket.Wait(context.Background(), chunk); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		test.That(t, group.Wait(), test.ShouldBeNil)
+
+		throughput := float64(2*perWorker) / time.Since(start).Seconds()
+		test.That(t, throughput, test.ShouldBeLessThanOrEqualTo, limit*1.1)
+		test.That(t, throughput, test.ShouldBeGreaterThanOrEqualTo, limit*0.9)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		bucket := &TokenBucket{}
+		bucket.SetLimitBPS(1)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		test.That(t, errors.Is(bucket.Wait(ctx, 1024), context.DeadlineExceeded), test.ShouldBeTrue)
+	})
+
+	t.Run("allow", func(t *testing.T) {
+		bucket := &TokenBucket{}
+		test.That(t, bucket.Allow(1<<30), test.ShouldBeTrue)
+		bucket.SetLimitBPS(1000)
+		time.Sleep(time.Millisecond * 200)
+		test.That(t, bucket.Allow(100), test.ShouldBeTrue)
+		// a refused request takes nothing
+		test.That(t, bucket.Allow(5000), test.ShouldBeFalse)
+		test.That(t, bucket.Allow(50), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond * 1500)
+		// burst is capped at one second
+		test.That(t, bucket.Allow(1001), test.ShouldBeFalse)
+		test.That(t, bucket.Allow(1000), test.ShouldBeTrue)
+	})
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WithBatchWindow allows AddBatchMatcher, whose matches are collected for window and then delivered together, so a
+// chatty process costs a channel send per window rather than per line. Zero or less leaves it disabled.
+func WithBatchWindow(window time.Duration) MatchingLoggerOption {
+	return func(l *MatchingLogger) {
+		if window <= 0 {
+			return
+		}
+		l.batchWindow = window
+	}
+}
+
+// matchBatch collects a batch matcher's matches until its timer fires.
+type matchBatch struct {
+	window time.Duration
+	out    chan [][]string
+	// closed by DeleteMatcher, like matcher.done
+	done chan struct{}
+
+	mu      sync.Mutex
+	pending [][]string
+	timer   *time.Timer
+
+	// held while sending, so batches arrive in order and out isn't closed mid-send
+	sendMu sync.Mutex
+	closed bool
+}
+
+// AddBatchMatcher is like AddMatcher, but matches are delivered in batches, as everything matched within the batch
+// window (from the first match) of WithBatchWindow. Unlike AddMatcher, writes never wait for the channel to be read,
+// matches accumulate until it is.
+func (l *MatchingLogger) AddBatchMatcher(name string, regex *regexp.Regexp, mask bool) (<-chan [][]string, error) {
+	if l.batchWindow <= 0 {
+		return nil, errors.New("batch matchers need WithBatchWindow")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.matchers == nil {
+		l.matchers = make(map[string]matcher)
+	}
+	if _, ok := l.matchers[name]; ok {
+		return nil, errors.Errorf("matcher already exists: %s", name)
+	}
+	done := make(chan struct{})
+	batch := &matchBatch{window: l.batchWindow, out: make(chan [][]string, 32), done: done}
+	l.matchers[name] = matcher{regex: regex, mask: mask, done: done, batch: batch}
+	l.doneMu.Lock()
+	defer l.doneMu.Unlock()
+	if l.matcherDones == nil {
+		l.matcherDones = make(map[string]chan struct{})
+	}
+	l.matcherDones[name] = done
+	return batch.out, nil
+}
+
+// add queues matches, starting the window if this is the first of a batch.
+func (b *matchBatch) add(matches []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, matches)
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+}
+
+// flush sends whatever is pending as a batch.
+func (b *matchBatch) flush() {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+	if b.closed || len(pending) == 0 {
+		return
+	}
+	select {
+	case b.out <- pending:
+	case <-b.done:
+	}
+}
+
+// close stops the batch and closes its channel, dropping anything pending. done must already be closed.
+func (b *matchBatch) close() {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+	b.mu.Lock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.pending = nil
+	b.mu.Unlock()
+	b.closed = true
+	close(b.out)
+}
+
+// flushBatches sends every batch matcher's pending matches now.
+func (l *MatchingLogger) flushBatches() {
+	l.mu.RLock()
+	var batches []*matchBatch
+	for _, m := range l.matchers {
+		if m.batch != nil {
+			batches = append(batches, m.batch)
+		}
+	}
+	l.mu.RUnlock()
+	for _, batch := range batches {
+		batch.flush()
+	}
+}
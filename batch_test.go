@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestBatchMatcher(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	_, err := NewMatchingLogger(logger, false, false).AddBatchMatcher("match", regexp.MustCompile(`match`), true)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	ml := NewMatchingLogger(logger, false, false, WithBatchWindow(time.Millisecond*50))
+	c, err := ml.AddBatchMatcher("match", regexp.MustCompile(`match (\d+)`), true)
+	test.That(t, err, test.ShouldBeNil)
+	_, err = ml.AddBatchMatcher("match", regexp.MustCompile(`match`), true)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	ml.Inject("match 1")
+	ml.Inject("no")
+	ml.Inject("match 2")
+	ml.Inject("match 3")
+	select {
+	case batch := <-c:
+		test.That(t, batch, test.ShouldResemble, [][]string{{"match 1", "1"}, {"match 2", "2"}, {"match 3", "3"}})
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for batch")
+	}
+
+	// the next batch starts a new window
+	ml.Inject("match 4")
+	test.That(t, <-c, test.ShouldResemble, [][]string{{"match 4", "4"}})
+
+	ml.DeleteMatcher("match")
+	_, ok := <-c
+	test.That(t, ok, test.ShouldBeFalse)
+}
+
+func TestBatchMatcherFlush(t *testing.T) {
+	ml := NewMatchingLogger(logging.NewTestLogger(t), false, false, WithBatchWindow(time.Hour))
+	c, err := ml.AddBatchMatcher("match", regexp.MustCompile(`match`), true)
+	test.That(t, err, test.ShouldBeNil)
+	ml.Inject("match")
+	ml.Inject("match")
+	ml.Flush()
+	test.That(t, <-c, test.ShouldHaveLength, 2)
+
+	// pending matches are dropped on delete, and a write never blocks on an unread channel
+	for i := 0; i < 100; i++ {
+		ml.Inject("match")
+	}
+	ml.DeleteMatcher("match")
+	_, ok := <-c
+	test.That(t, ok, test.ShouldBeFalse)
+}
+
+// compares a send per line with a send per batch window.
+func BenchmarkBatchMatcher(b *testing.B) {
+	line := []byte("2024-01-01T00:00:00.000Z\tINFO\tbench\tbench.go:1\tsome log line\n")
+	b.Run("unbatched", func(b *testing.B) {
+		ml := NewMatchingLogger(logging.NewBlankLogger("bench"), false, false)
+		c, err := ml.AddMatcher("all", regexp.MustCompile(`line`), true)
+		if err != nil {
+			b.Fatal(err)
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			//nolint:revive
+			for range c {
+			}
+		}()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			//nolint:errcheck
+			ml.Write(line)
+		}
+		b.StopTimer()
+		ml.DeleteMatcher("all")
+		<-done
+	})
+	b.Run("batched", func(b *testing.B) {
+		ml := NewMatchingLogger(logging.NewBlankLogger("bench"), false, false, WithBatchWindow(time.Millisecond*10))
+		c, err := ml.AddBatchMatcher("all", regexp.MustCompile(`line`), true)
+		if err != nil {
+			b.Fatal(err)
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			//nolint:revive
+			for range c {
+			}
+		}()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			//nolint:errcheck
+			ml.Write(line)
+		}
+		ml.Flush()
+		b.StopTimer()
+		ml.DeleteMatcher("all")
+		<-done
+	})
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"slices"
+	"strings"
+
+	pb "go.viam.com/api/app/agent/v1"
+)
+
+// DefaultBootPriority is the boot_priority of subsystems that don't set one. Lower priorities start first.
+const DefaultBootPriority = 100
+
+// setBootPriorities records each loaded subsystem's boot_priority attribute from cfg, and returns the resulting boot
+// order, logging it when it changes. Must be called with subsystemsMu held.
+func (m *Manager) setBootPriorities(cfg map[string]*pb.DeviceSubsystemConfig) []string {
+	if m.bootPriority == nil {
+		m.bootPriority = make(map[string]int)
+	}
+	for name := range m.loadedSubsystems {
+		priority := DefaultBootPriority
+		if raw, ok := cfg[name].GetAttributes().AsMap()["boot_priority"]; ok {
+			num, ok := raw.(float64)
+			if ok {
+				priority = int(num)
+			} else {
+				m.logger.Warnf("invalid boot_priority for %s: %v", name, raw)
+			}
+		}
+		m.bootPriority[name] = priority
+	}
+	order := m.bootOrder()
+	if !slices.Equal(order, m.lastBootOrder) {
+		m.logger.Infof("subsystem start order: %s", strings.Join(order, ", "))
+		m.lastBootOrder = order
+	}
+	return order
+}
+
+// bootOrder returns the names of the loaded subsystems, lowest boot_priority first, then by name so subsystems of
+// equal priority have a stable order. Each is started (waiting for it to be up) before the next, and they're stopped
+// in reverse. Must be called with subsystemsMu held.
+func (m *Manager) bootOrder() []string {
+	order := make([]string, 0, len(m.loadedSubsystems))
+	for name := range m.loadedSubsystems {
+		order = append(order, name)
+	}
+	slices.SortFunc(order, func(a, b string) int {
+		if priorityA, priorityB := m.priority(a), m.priority(b); priorityA != priorityB {
+			return priorityA - priorityB
+		}
+		return strings.Compare(a, b)
+	})
+	return order
+}
+
+func (m *Manager) priority(name string) int {
+	if priority, ok := m.bootPriority[name]; ok {
+		return priority
+	}
+	return DefaultBootPriority
+}
@@ -46,6 +46,7 @@ func main() {
 		Version bool   `description:"Show version"                          long:"version"                    short:"v"`
 		Install bool   `description:"Install systemd service"               long:"install"`
 		DevMode bool   `description:"Allow non-root and non-service"        env:"VIAM_AGENT_DEVMODE"          long:"dev-mode"`
+		Dirs    bool   `description:"Create the viam directories and exit"  long:"create-dirs"`
 	}
 
 	parser := flags.NewParser(&opts, flags.IgnoreUnknown)
@@ -88,6 +89,11 @@ func main() {
 		return
 	}
 
+	if opts.Dirs {
+		exitIfError(agent.EnsureViamDirs(agent.ViamDirs))
+		return
+	}
+
 	if !opts.DevMode {
 		// confirm that we're running from a proper install
 		if !strings.HasPrefix(os.Args[0], agent.ViamDirs["viam"]) {
@@ -123,6 +129,7 @@ func main() {
 	// main manager structure
 	manager, err := agent.NewManager(ctx, globalLogger)
 	exitIfError(err)
+	handleDiagnosticSignal(ctx, manager)
 
 	err = manager.LoadConfig(absConfigPath)
 	//nolint:nestif
@@ -269,6 +276,32 @@ func setupExitSignalHandling() context.Context {
 	return ctx
 }
 
+// handleDiagnosticSignal writes a diagnostic snapshot (see Manager.CollectDiagnostics) to the tmp directory each time
+// the agent gets SIGUSR1, e.g. from "systemctl kill -s USR1 viam-agent", for attaching to support tickets.
+func handleDiagnosticSignal(ctx context.Context, manager *agent.Manager) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1)
+	activeBackgroundWorkers.Add(1)
+	go func() {
+		defer activeBackgroundWorkers.Done()
+		defer signal.Stop(sigChan)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigChan:
+			}
+			destPath := filepath.Join(agent.ViamDirs["tmp"],
+				"viam-diag-"+time.Now().UTC().Format("20060102T150405Z")+".tar.gz")
+			if err := manager.CollectDiagnostics(ctx, destPath); err != nil {
+				globalLogger.Error(errors.Wrap(err, "collecting diagnostics"))
+				continue
+			}
+			globalLogger.Infof("wrote diagnostic snapshot to %s", destPath)
+		}
+	}()
+}
+
 func exitIfError(err error) {
 	if err != nil {
 		globalLogger.Fatal(err)
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// matches $$, ${VAR}, ${VAR:-default}, and $VAR.
+var envVarRegex = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// ErrUnresolvedVariable is returned (joined with any others) by ExpandEnvInJSON for each referenced variable
+// that is unset and has no default. It is a warning: the expanded config is still returned, with the reference left as-is.
+type ErrUnresolvedVariable struct {
+	Name string
+}
+
+func (e ErrUnresolvedVariable) Error() string {
+	return fmt.Sprintf("environment variable %s is not set", e.Name)
+}
+
+// IsUnresolvedOnly returns true if err is non-nil and consists only of ErrUnresolvedVariable warnings.
+func IsUnresolvedOnly(err error) bool {
+	if err == nil {
+		return false
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if !IsUnresolvedOnly(e) {
+				return false
+			}
+		}
+		return true
+	}
+	var unresolved ErrUnresolvedVariable
+	return errors.As(err, &unresolved)
+}
+
+// ExpandEnvInJSON substitutes environment variables referenced in the string values of a JSON document.
+// ${VAR}, $VAR, and ${VAR:-default} are supported, with the default used if VAR is unset or empty, and $$ is a literal $.
+// Object keys and non-string values are left untouched.
+func ExpandEnvInJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var unresolved []error
+	seen := make(map[string]bool)
+	doc = expandValue(doc, func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			unresolved = append(unresolved, ErrUnresolvedVariable{Name: name})
+		}
+	})
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), errors.Join(unresolved...)
+}
+
+func expandValue(val any, onUnresolved func(string)) any {
+	switch v := val.(type) {
+	case string:
+		return expandString(v, onUnresolved)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = expandValue(item, onUnresolved)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = expandValue(item, onUnresolved)
+		}
+	}
+	return val
+}
+
+func expandString(s string, onUnresolved func(string)) string {
+	return envVarRegex.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		groups := envVarRegex.FindStringSubmatch(match)
+		name, hasDefault, def := groups[1], groups[2] != "", groups[3]
+		if name == "" {
+			name = groups[4]
+		}
+		if value, ok := os.LookupEnv(name); ok && (value != "" || !hasDefault) {
+			return value
+		}
+		if hasDefault {
+			return def
+		}
+		onUnresolved(name)
+		return match
+	})
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestExpandEnvInJSON(t *testing.T) {
+	t.Setenv("VIAM_API_KEY", "abc123")
+	t.Setenv("VIAM_EMPTY", "")
+
+	in := `{"api_key": "${VIAM_API_KEY}", "url": "https://$VIAM_API_KEY/x?a=1&b=2", ` +
+		`"nested": {"list": ["${VIAM_EMPTY:-fallback}", "${VIAM_UNSET:-}", 5, true, null]}, "$VIAM_API_KEY": 1.50}`
+	out, err := ExpandEnvInJSON([]byte(in))
+	test.That(t, err, test.ShouldBeNil)
+
+	var got map[string]any
+	test.That(t, json.Unmarshal(out, &got), test.ShouldBeNil)
+	test.That(t, got["api_key"], test.ShouldEqual, "abc123")
+	test.That(t, got["url"], test.ShouldEqual, "https://abc123/x?a=1&b=2")
+	test.That(t, got["nested"], test.ShouldResemble, map[string]any{"list": []any{"fallback", "", 5.0, true, nil}})
+	// keys aren't expanded, and numbers keep their formatting
+	test.That(t, got["$VIAM_API_KEY"], test.ShouldEqual, 1.5)
+	test.That(t, string(out), test.ShouldContainSubstring, "1.50")
+
+	t.Run("unresolved", func(t *testing.T) {
+		out, err := ExpandEnvInJSON([]byte(`{"a": "${VIAM_UNSET}", "b": "$VIAM_UNSET and $VIAM_OTHER_UNSET"}`))
+		test.That(t, IsUnresolvedOnly(err), test.ShouldBeTrue)
+		var unresolved ErrUnresolvedVariable
+		test.That(t, errors.As(err, &unresolved), test.ShouldBeTrue)
+		test.That(t, unresolved.Name, test.ShouldEqual, "VIAM_UNSET")
+		test.That(t, err.Error(), test.ShouldContainSubstring, "VIAM_OTHER_UNSET")
+		test.That(t, string(out), test.ShouldEqual, `{"a":"${VIAM_UNSET}","b":"$VIAM_UNSET and $VIAM_OTHER_UNSET"}`)
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		out, err := ExpandEnvInJSON([]byte(`{"a": "$$VIAM_API_KEY", "b": "pa$$word $$$VIAM_API_KEY", "c": "$${VIAM_UNSET}"}`))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, string(out), test.ShouldEqual, `{"a":"$VIAM_API_KEY","b":"pa$word $abc123","c":"${VIAM_UNSET}"}`)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ExpandEnvInJSON([]byte(`{"a": `))
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, IsUnresolvedOnly(err), test.ShouldBeFalse)
+	})
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ApplyMergePatch applies a JSON Merge Patch (RFC 7396) to original, returning the patched document.
+// Objects in the patch are merged recursively, null values delete keys, and anything else replaces the original value.
+func ApplyMergePatch(original, patch []byte) ([]byte, error) {
+	var target any
+	if len(bytes.TrimSpace(original)) > 0 {
+		if err := decodeJSON(original, &target); err != nil {
+			return nil, err
+		}
+	}
+	var patchDoc any
+	if err := decodeJSON(patch, &patchDoc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, patchDoc))
+}
+
+// decodeJSON decodes with numbers kept as-is, so they aren't reformatted by a round trip.
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	original := `{"a":"b","c":{"d":"e","f":"g"},"list":[1,2],"n":1.50}`
+	for _, tc := range []struct {
+		name     string
+		original string
+		patch    string
+		expected string
+	}{
+		{"null deletion", original, `{"a":null,"missing":null}`, `{"c":{"d":"e","f":"g"},"list":[1,2],"n":1.50}`},
+		{"nested", original, `{"c":{"d":"x","f":null,"h":{"i":true}}}`, `{"a":"b","c":{"d":"x","h":{"i":true}},"list":[1,2],"n":1.50}`},
+		{"partial", original, `{"a":"z","list":[3]}`, `{"a":"z","c":{"d":"e","f":"g"},"list":[3],"n":1.50}`},
+		{"replace object", original, `{"c":"flat"}`, `{"a":"b","c":"flat","list":[1,2],"n":1.50}`},
+		{"non-object patch", original, `["x"]`, `["x"]`},
+		{"empty original", "", `{"a":{"b":null,"c":1}}`, `{"a":{"c":1}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			patched, err := ApplyMergePatch([]byte(tc.original), []byte(tc.patch))
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, string(patched), test.ShouldEqual, tc.expected)
+		})
+	}
+
+	_, err := ApplyMergePatch([]byte(original), []byte(`{"a":`))
+	test.That(t, err, test.ShouldNotBeNil)
+}
@@ -0,0 +1,115 @@
+// Package config contains helpers for working with subsystem configs.
+package config
+
+import (
+	"strings"
+
+	pb "go.viam.com/api/app/agent/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Redacted replaces the value of sensitive fields.
+const Redacted = "[REDACTED]"
+
+// field and attribute names containing any of these are treated as sensitive.
+var redactedFields = []string{
+	"password",
+	"secret",
+	"token",
+	"api_key",
+	"apikey",
+	"private_key",
+	"psk",
+}
+
+// RedactedFields returns the name fragments that mark a field or attribute as sensitive.
+func RedactedFields() []string {
+	return append([]string(nil), redactedFields...)
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range redactedFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitize returns a deep copy of cfg with sensitive values replaced by Redacted, safe for logging.
+func Sanitize(cfg *pb.DeviceSubsystemConfig) *pb.DeviceSubsystemConfig {
+	if cfg == nil {
+		return nil
+	}
+	//nolint:forcetypeassert
+	return SanitizeMessage(cfg).(*pb.DeviceSubsystemConfig)
+}
+
+// SanitizeMessage is Sanitize for any proto message, such as a whole config response.
+func SanitizeMessage(msg proto.Message) proto.Message {
+	clone := proto.Clone(msg)
+	redactMessage(clone.ProtoReflect())
+	return clone
+}
+
+func redactMessage(msg protoreflect.Message) {
+	// attributes are free-form, so they're matched by key instead of by field
+	if st, ok := msg.Interface().(*structpb.Struct); ok {
+		redactStruct(st)
+		return
+	}
+	msg.Range(func(fd protoreflect.FieldDescriptor, val protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			if fd.Message() != nil {
+				list := val.List()
+				for i := 0; i < list.Len(); i++ {
+					redactMessage(list.Get(i).Message())
+				}
+			} else if fd.Kind() == protoreflect.StringKind && isSensitive(string(fd.Name())) {
+				list := val.List()
+				for i := 0; i < list.Len(); i++ {
+					list.Set(i, protoreflect.ValueOfString(Redacted))
+				}
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				val.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					redactMessage(v.Message())
+					return true
+				})
+			}
+		case fd.Message() != nil:
+			redactMessage(val.Message())
+		case fd.Kind() == protoreflect.StringKind && isSensitive(string(fd.Name())):
+			msg.Set(fd, protoreflect.ValueOfString(Redacted))
+		case fd.Kind() == protoreflect.BytesKind && isSensitive(string(fd.Name())):
+			msg.Set(fd, protoreflect.ValueOfBytes([]byte(Redacted)))
+		}
+		return true
+	})
+}
+
+func redactStruct(st *structpb.Struct) {
+	for key, val := range st.GetFields() {
+		if isSensitive(key) {
+			st.Fields[key] = structpb.NewStringValue(Redacted)
+			continue
+		}
+		redactValue(val)
+	}
+}
+
+func redactValue(val *structpb.Value) {
+	switch kind := val.GetKind().(type) {
+	case *structpb.Value_StructValue:
+		redactStruct(kind.StructValue)
+	case *structpb.Value_ListValue:
+		for _, item := range kind.ListValue.GetValues() {
+			redactValue(item)
+		}
+	}
+}
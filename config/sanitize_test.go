@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestSanitize(t *testing.T) {
+	attrs, err := structpb.NewStruct(map[string]any{
+		"start_timeout":          "5m",
+		"healthcheck_auth_token": "hunter2",
+		"networks": []any{
+			map[string]any{"ssid": "home", "psk": "wifipass"},
+		},
+		"cloud": map[string]any{"id": "abc", "Secret": "shh"},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	cfg := &pb.DeviceSubsystemConfig{
+		UpdateInfo: &pb.SubsystemUpdateInfo{Url: "https://example.com/viam-server", Version: "0.30.0"},
+		Attributes: attrs,
+	}
+
+	sanitized := Sanitize(cfg).GetAttributes().AsMap()
+	test.That(t, sanitized["start_timeout"], test.ShouldEqual, "5m")
+	test.That(t, sanitized["healthcheck_auth_token"], test.ShouldEqual, Redacted)
+	network := sanitized["networks"].([]any)[0].(map[string]any)
+	test.That(t, network["ssid"], test.ShouldEqual, "home")
+	test.That(t, network["psk"], test.ShouldEqual, Redacted)
+	cloud := sanitized["cloud"].(map[string]any)
+	test.That(t, cloud["id"], test.ShouldEqual, "abc")
+	test.That(t, cloud["Secret"], test.ShouldEqual, Redacted)
+
+	// the original is untouched
+	test.That(t, cfg.GetAttributes().AsMap()["healthcheck_auth_token"], test.ShouldEqual, "hunter2")
+	test.That(t, Sanitize(cfg).GetUpdateInfo().GetUrl(), test.ShouldEqual, "https://example.com/viam-server")
+
+	test.That(t, Sanitize(nil), test.ShouldBeNil)
+	test.That(t, RedactedFields(), test.ShouldContain, "password")
+}
@@ -0,0 +1,260 @@
+package agent
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent/subsystems"
+	"golang.org/x/sys/unix"
+)
+
+// how long each system command in a diagnostic snapshot may take.
+const diagnosticCommandTimeout = time.Second * 10
+
+// config keys whose values are replaced in a diagnostic snapshot, matched case-insensitively as substrings.
+var sensitiveConfigKeys = []string{"secret", "token", "password", "key"}
+
+const redacted = "<redacted>"
+
+// subsystemDiagnostics is a subsystem's entry in a diagnostic snapshot's status.json.
+type subsystemDiagnostics struct {
+	Version string `json:"version"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+	// only for subsystems that report them, see subsystems.StartKindReporter, subsystems.ExitSignalReporter and
+	// subsystems.CheckURLReporter
+	LastStartKind  string   `json:"last_start_kind,omitempty"`
+	LastExitSignal string   `json:"last_exit_signal,omitempty"`
+	CheckURLs      []string `json:"check_urls,omitempty"`
+}
+
+// CollectDiagnostics writes a .tar.gz to destPath with what's usually needed for a support ticket: the logs in
+// ViamDirs["log"] (if there is one), the cloud and cached subsystem configs with secrets redacted, every subsystem's
+// version, health, last start kind, last exit signal and check URLs, the HealthSummary rollup, startup banners and
+// timelines (for subsystems that keep them), and the output of uname, free and df. Everything is under a single
+// timestamped directory.
+// Anything that can't be collected is noted in errors.txt rather than failing the snapshot.
+func (m *Manager) CollectDiagnostics(ctx context.Context, destPath string) (errRet error) {
+	//nolint:gosec
+	out, err := os.Create(destPath)
+	if err != nil {
+		return errw.Wrap(err, "creating diagnostic snapshot")
+	}
+	defer func() {
+		errRet = errors.Join(errRet, out.Close())
+	}()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	defer func() {
+		errRet = errors.Join(errRet, tw.Close(), gz.Close())
+	}()
+
+	now := time.Now()
+	dir := "viam-diag-" + now.UTC().Format("20060102T150405Z")
+	var problems []string
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: dir + "/" + name, Mode: 0o644, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if logDir, ok := ViamDirs["log"]; ok {
+		err := filepath.WalkDir(logDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				problems = append(problems, err.Error())
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			//nolint:gosec
+			data, err := os.ReadFile(path)
+			if err != nil {
+				problems = append(problems, err.Error())
+				return nil
+			}
+			rel, err := filepath.Rel(logDir, path)
+			if err != nil {
+				return err
+			}
+			return add("logs/"+filepath.ToSlash(rel), data)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	m.connMu.RLock()
+	var cloud map[string]any
+	if m.cloudConfig != nil {
+		cloud = map[string]any{"app_address": m.cloudConfig.AppAddress, "id": m.cloudConfig.ID, "secret": m.cloudConfig.Secret}
+	}
+	m.connMu.RUnlock()
+	if err := addJSON(add, "cloud-config.json", redactConfig(cloud)); err != nil {
+		return err
+	}
+
+	//nolint:gosec
+	cached, err := os.ReadFile(filepath.Join(ViamDirs["cache"], agentCachePath))
+	if err == nil {
+		var cachedConfig any
+		if err := json.Unmarshal(cached, &cachedConfig); err != nil {
+			problems = append(problems, errw.Wrap(err, "parsing cached config").Error())
+		} else if err := addJSON(add, "cached-config.json", redactConfig(cachedConfig)); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		problems = append(problems, errw.Wrap(err, "reading cached config").Error())
+	}
+
+	versions := m.getSubsystemVersions()
+	status := make(map[string]subsystemDiagnostics, len(versions))
+	report := m.AggregateHealth(ctx)
+	for _, health := range report {
+		entry := subsystemDiagnostics{Version: versions[health.Name], Healthy: health.Err == nil, Latency: health.Latency.String()}
+		if health.Err != nil {
+			entry.Error = health.Err.Error()
+		}
+		status[health.Name] = entry
+	}
+
+	m.subsystemsMu.Lock()
+	banners := make(map[string]string)
+	timelines := make(map[string][]subsystems.StateEvent)
+	for name, sub := range m.loadedSubsystems {
+		if reporter, ok := sub.(subsystems.StartKindReporter); ok {
+			entry := status[name]
+			entry.LastStartKind = string(reporter.LastStartKind())
+			status[name] = entry
+		}
+		if reporter, ok := sub.(subsystems.CheckURLReporter); ok {
+			entry := status[name]
+			entry.CheckURLs = reporter.CheckURLs()
+			status[name] = entry
+		}
+		if reporter, ok := sub.(subsystems.ExitSignalReporter); ok {
+			if sig, ok := reporter.LastExitSignal(); ok {
+				entry := status[name]
+				entry.LastExitSignal = unix.SignalName(sig)
+				status[name] = entry
+			}
+		}
+		if reporter, ok := sub.(subsystems.StartupBannerReporter); ok {
+			if banner := reporter.StartupBanner(); banner != "" {
+				banners[name] = banner
+			}
+		}
+		if reporter, ok := sub.(subsystems.TimelineReporter); ok {
+			if timeline := reporter.Timeline(); len(timeline) > 0 {
+				timelines[name] = timeline
+			}
+		}
+	}
+	m.subsystemsMu.Unlock()
+	if err := addJSON(add, "status.json", status); err != nil {
+		return err
+	}
+	if err := addJSON(add, "health-summary.json", m.summarizeHealth(ctx, report)); err != nil {
+		return err
+	}
+	for name, banner := range banners {
+		if err := add("banners/"+name+".txt", []byte(banner+"\n")); err != nil {
+			return err
+		}
+	}
+	for name, timeline := range timelines {
+		if err := addJSON(add, "timelines/"+name+".json", timeline); err != nil {
+			return err
+		}
+	}
+
+	dirs := make([]string, 0, len(ViamDirs))
+	for _, path := range ViamDirs {
+		dirs = append(dirs, path)
+	}
+	sort.Strings(dirs)
+	for _, command := range []struct {
+		file string
+		args []string
+	}{
+		{"uname.txt", []string{"uname", "-a"}},
+		{"free.txt", []string{"free", "-h"}},
+		{"df.txt", append([]string{"df", "-h"}, dirs...)},
+	} {
+		cmdCtx, cancel := context.WithTimeout(ctx, diagnosticCommandTimeout)
+		//nolint:gosec
+		output, err := exec.CommandContext(cmdCtx, command.args[0], command.args[1:]...).CombinedOutput()
+		cancel()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("running %s: %s", strings.Join(command.args, " "), err))
+		}
+		if err := add(command.file, output); err != nil {
+			return err
+		}
+	}
+
+	if len(problems) > 0 {
+		return add("errors.txt", []byte(strings.Join(problems, "\n")+"\n"))
+	}
+	return nil
+}
+
+func addJSON(add func(string, []byte) error, name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return add(name, data)
+}
+
+// redactConfig returns a copy of a decoded JSON value with the values of any sensitiveConfigKeys replaced.
+func redactConfig(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		ret := make(map[string]any, len(val))
+		for k, inner := range val {
+			if isSensitiveConfigKey(k) {
+				if _, ok := inner.(string); ok {
+					ret[k] = redacted
+					continue
+				}
+			}
+			ret[k] = redactConfig(inner)
+		}
+		return ret
+	case []any:
+		ret := make([]any, len(val))
+		for i, inner := range val {
+			ret[i] = redactConfig(inner)
+		}
+		return ret
+	default:
+		return v
+	}
+}
+
+func isSensitiveConfigKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveConfigKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent/subsystems"
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCollectDiagnostics(t *testing.T) {
+	prevDirs := make(map[string]string, len(ViamDirs))
+	for k, v := range ViamDirs {
+		prevDirs[k] = v
+	}
+	t.Cleanup(func() {
+		for k := range ViamDirs {
+			delete(ViamDirs, k)
+		}
+		for k, v := range prevDirs {
+			ViamDirs[k] = v
+		}
+	})
+	ViamDirs["cache"] = t.TempDir()
+	ViamDirs["log"] = t.TempDir()
+	test.That(t, os.WriteFile(filepath.Join(ViamDirs["log"], "viam-server.log"), []byte("a log line\n"), 0o600),
+		test.ShouldBeNil)
+
+	m := &Manager{
+		logger: logging.NewTestLogger(t),
+		loadedSubsystems: map[string]subsystems.Subsystem{
+			"good": &fakeSubsystem{
+				banner:    "viam-server v1.2.3\nconfig: 4 components",
+				startKind: "first_start",
+				checkURLs: []string{"https://robot.local:8080", "https://127.0.0.1:8080"},
+				timeline:  []subsystems.StateEvent{{Time: time.Unix(0, 0).UTC(), State: "started", Detail: "first_start"}},
+			},
+			"bad": &fakeSubsystem{healthErr: errors.New("broken"), exitSignal: syscall.SIGKILL},
+		},
+		cloudConfig: &logging.CloudConfig{AppAddress: "https://app.viam.com", ID: "robot-id", Secret: "hunter2"},
+	}
+	attrs, err := structpb.NewStruct(map[string]any{"healthcheck_auth_token": "hunter2", "start_timeout": "1m"})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, m.saveCachedConfig(map[string]*pb.DeviceSubsystemConfig{"good": {Attributes: attrs}}), test.ShouldBeNil)
+
+	dest := filepath.Join(t.TempDir(), "viam-diag.tar.gz")
+	test.That(t, m.CollectDiagnostics(context.Background(), dest), test.ShouldBeNil)
+
+	//nolint:gosec
+	f, err := os.Open(dest)
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	test.That(t, err, test.ShouldBeNil)
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	var topDir string
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		test.That(t, err, test.ShouldBeNil)
+		dir, name, _ := strings.Cut(hdr.Name, "/")
+		if topDir == "" {
+			topDir = dir
+		}
+		test.That(t, dir, test.ShouldEqual, topDir)
+		data, err := io.ReadAll(tr)
+		test.That(t, err, test.ShouldBeNil)
+		files[name] = string(data)
+	}
+	test.That(t, topDir, test.ShouldStartWith, "viam-diag-")
+
+	test.That(t, files["logs/viam-server.log"], test.ShouldEqual, "a log line\n")
+	for _, data := range files {
+		test.That(t, data, test.ShouldNotContainSubstring, "hunter2")
+	}
+	test.That(t, files["cloud-config.json"], test.ShouldContainSubstring, "robot-id")
+	test.That(t, files["cached-config.json"], test.ShouldContainSubstring, `"start_timeout": "1m"`)
+	test.That(t, files, test.ShouldContainKey, "uname.txt")
+	test.That(t, files, test.ShouldContainKey, "df.txt")
+
+	var status map[string]subsystemDiagnostics
+	test.That(t, json.Unmarshal([]byte(files["status.json"]), &status), test.ShouldBeNil)
+	test.That(t, status["good"].Healthy, test.ShouldBeTrue)
+	test.That(t, status["bad"].Healthy, test.ShouldBeFalse)
+	test.That(t, status["bad"].Error, test.ShouldEqual, "broken")
+	test.That(t, status["bad"].LastExitSignal, test.ShouldEqual, "SIGKILL")
+	test.That(t, status["good"].LastExitSignal, test.ShouldBeEmpty)
+	test.That(t, status["good"].LastStartKind, test.ShouldEqual, "first_start")
+	test.That(t, status["good"].CheckURLs, test.ShouldResemble, []string{"https://robot.local:8080", "https://127.0.0.1:8080"})
+	test.That(t, status["bad"].CheckURLs, test.ShouldBeEmpty)
+
+	var summary HealthSummary
+	test.That(t, json.Unmarshal([]byte(files["health-summary.json"]), &summary), test.ShouldBeNil)
+	test.That(t, summary.TotalSubsystems, test.ShouldEqual, 2)
+	test.That(t, summary.UnhealthySubsystems, test.ShouldResemble, []string{"bad"})
+	test.That(t, summary.OverallHealthy, test.ShouldBeFalse)
+
+	test.That(t, files["banners/good.txt"], test.ShouldEqual, "viam-server v1.2.3\nconfig: 4 components\n")
+	test.That(t, files, test.ShouldNotContainKey, "banners/bad.txt")
+
+	var timeline []subsystems.StateEvent
+	test.That(t, json.Unmarshal([]byte(files["timelines/good.json"]), &timeline), test.ShouldBeNil)
+	test.That(t, timeline, test.ShouldResemble,
+		[]subsystems.StateEvent{{Time: time.Unix(0, 0).UTC(), State: "started", Detail: "first_start"}})
+	test.That(t, files, test.ShouldNotContainKey, "timelines/bad.json")
+}
+
+func TestRedactConfig(t *testing.T) {
+	redactedCfg := redactConfig(map[string]any{
+		"cloud":  map[string]any{"id": "a", "secret": "b"},
+		"list":   []any{map[string]any{"API_KEY": "c", "n": 1.0}},
+		"tokens": map[string]any{"inner": "d"},
+	})
+	test.That(t, redactedCfg, test.ShouldResemble, map[string]any{
+		"cloud":  map[string]any{"id": "a", "secret": redacted},
+		"list":   []any{map[string]any{"API_KEY": redacted, "n": 1.0}},
+		"tokens": map[string]any{"inner": "d"},
+	})
+}
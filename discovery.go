@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/edaniels/zeroconf"
+	errw "github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// MDNSServiceType is the mDNS service that robots advertise their capabilities under.
+const MDNSServiceType = "_viam-robot._tcp"
+
+// RobotInfo describes a robot found by DiscoverRobots.
+type RobotInfo struct {
+	Instance   string
+	HostName   string
+	Port       int
+	Addrs      []net.IP
+	Version    string
+	Subsystems []string
+	CheckURL   string
+}
+
+// AdvertiseCapabilities registers this robot under MDNSServiceType on the local network, until Shutdown is
+// called on the returned server.
+func AdvertiseCapabilities(port int, version string, subsystems []string, checkURL string) (*zeroconf.Server, error) {
+	instance, err := os.Hostname()
+	if err != nil {
+		return nil, errw.Wrap(err, "getting hostname")
+	}
+	text := []string{
+		"version=" + version,
+		"subsystems=" + strings.Join(subsystems, ","),
+		"checkURL=" + checkURL,
+	}
+	server, err := zeroconf.Register(instance, MDNSServiceType, "local.", port, text, nil, zap.NewNop().Sugar())
+	if err != nil {
+		return nil, errw.Wrap(err, "registering mDNS service")
+	}
+	return server, nil
+}
+
+// DiscoverRobots browses the local network for robots advertising MDNSServiceType, for up to timeout.
+func DiscoverRobots(ctx context.Context, timeout time.Duration) ([]RobotInfo, error) {
+	resolver, err := zeroconf.NewResolver(zap.NewNop().Sugar())
+	if err != nil {
+		return nil, errw.Wrap(err, "creating mDNS resolver")
+	}
+	defer resolver.Shutdown()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	entries := make(chan *zeroconf.ServiceEntry)
+	if err := resolver.Browse(ctx, MDNSServiceType, "local.", entries); err != nil {
+		return nil, errw.Wrap(err, "browsing for robots")
+	}
+
+	// the same robot may answer more than once, or on more than one interface
+	found := make(map[string]RobotInfo)
+	for entry := range entries {
+		found[entry.Instance] = robotInfoFromEntry(entry)
+	}
+	robots := make([]RobotInfo, 0, len(found))
+	for _, info := range found {
+		robots = append(robots, info)
+	}
+	return robots, nil
+}
+
+func robotInfoFromEntry(entry *zeroconf.ServiceEntry) RobotInfo {
+	info := RobotInfo{
+		Instance: entry.Instance,
+		HostName: entry.HostName,
+		Port:     entry.Port,
+		Addrs:    append(append([]net.IP{}, entry.AddrIPv4...), entry.AddrIPv6...),
+	}
+	for _, txt := range entry.Text {
+		key, val, ok := strings.Cut(txt, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "version":
+			info.Version = val
+		case "subsystems":
+			if val != "" {
+				info.Subsystems = strings.Split(val, ",")
+			}
+		case "checkURL":
+			info.CheckURL = val
+		}
+	}
+	return info
+}
@@ -0,0 +1,26 @@
+package agent
+
+import (
+	"net"
+	"testing"
+
+	"github.com/edaniels/zeroconf"
+	"go.viam.com/test"
+)
+
+func TestRobotInfoFromEntry(t *testing.T) {
+	entry := zeroconf.NewServiceEntry("robot1", MDNSServiceType, "local.")
+	entry.HostName = "robot1.local."
+	entry.Port = 8080
+	entry.AddrIPv4 = []net.IP{net.IPv4(192, 168, 1, 5)}
+	entry.Text = []string{"version=0.30.0", "subsystems=viam-server", "checkURL=http://localhost:8080", "malformed"}
+
+	info := robotInfoFromEntry(entry)
+	test.That(t, info.Instance, test.ShouldEqual, "robot1")
+	test.That(t, info.HostName, test.ShouldEqual, "robot1.local.")
+	test.That(t, info.Port, test.ShouldEqual, 8080)
+	test.That(t, info.Addrs, test.ShouldHaveLength, 1)
+	test.That(t, info.Version, test.ShouldEqual, "0.30.0")
+	test.That(t, info.Subsystems, test.ShouldResemble, []string{"viam-server"})
+	test.That(t, info.CheckURL, test.ShouldEqual, "http://localhost:8080")
+}
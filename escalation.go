@@ -0,0 +1,74 @@
+package agent
+
+import "time"
+
+// EscalationPolicy shortens the time between rounds of subsystem health checks while they're failing, so recovery
+// (or a restart) is noticed sooner, without checking that often while everything is healthy.
+type EscalationPolicy struct {
+	// the normal time between rounds, the update check interval for the manager
+	BaseInterval time.Duration
+	// the time between rounds while escalated, zero to never escalate
+	FailedInterval time.Duration
+	// consecutive failed rounds before escalating, and passing rounds before returning to BaseInterval
+	EscalateAfterFailures    int
+	DeescalateAfterSuccesses int
+
+	failures  int
+	successes int
+	escalated bool
+}
+
+// Next records whether the last round of health checks all passed, and returns how long to wait until the next.
+func (p *EscalationPolicy) Next(healthy bool) time.Duration {
+	if healthy {
+		p.failures = 0
+		p.successes++
+		if p.escalated && p.successes >= max(p.DeescalateAfterSuccesses, 1) {
+			p.escalated = false
+		}
+	} else {
+		p.successes = 0
+		p.failures++
+		if !p.escalated && p.failures >= max(p.EscalateAfterFailures, 1) {
+			p.escalated = true
+		}
+	}
+	return p.Interval()
+}
+
+// Interval returns the current time between rounds.
+func (p *EscalationPolicy) Interval() time.Duration {
+	if p.escalated && p.FailedInterval > 0 && p.FailedInterval < p.BaseInterval {
+		return p.FailedInterval
+	}
+	return p.BaseInterval
+}
+
+// Escalated returns true if health checks are currently at FailedInterval.
+func (p *EscalationPolicy) Escalated() bool {
+	return p.escalated
+}
+
+// nextHealthCheck records the result of the last round of health checks, and returns how long to wait until the next,
+// with baseInterval as the interval while healthy.
+func (m *Manager) nextHealthCheck(baseInterval time.Duration) time.Duration {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	healthy := true
+	for _, err := range m.healthStatus {
+		if err != nil {
+			healthy = false
+		}
+	}
+	wasEscalated := m.escalation.Escalated()
+	m.escalation.BaseInterval = baseInterval
+	interval := m.escalation.Next(healthy)
+	if escalated := m.escalation.Escalated(); escalated != wasEscalated {
+		if escalated {
+			m.logger.Infof("subsystem health checks failing, checking every %s until they recover", interval)
+		} else {
+			m.logger.Infof("subsystem health checks recovered, checking every %s", interval)
+		}
+	}
+	return interval
+}
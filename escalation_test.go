@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestEscalationPolicy(t *testing.T) {
+	p := EscalationPolicy{
+		BaseInterval:             time.Second * 30,
+		FailedInterval:           time.Second * 5,
+		EscalateAfterFailures:    2,
+		DeescalateAfterSuccesses: 3,
+	}
+	test.That(t, p.Next(true), test.ShouldEqual, time.Second*30)
+	test.That(t, p.Next(false), test.ShouldEqual, time.Second*30)
+	test.That(t, p.Next(false), test.ShouldEqual, time.Second*5)
+	test.That(t, p.Escalated(), test.ShouldBeTrue)
+	// a pass resets the count before returning to the base interval
+	test.That(t, p.Next(true), test.ShouldEqual, time.Second*5)
+	test.That(t, p.Next(true), test.ShouldEqual, time.Second*5)
+	test.That(t, p.Next(false), test.ShouldEqual, time.Second*5)
+	test.That(t, p.Next(true), test.ShouldEqual, time.Second*5)
+	test.That(t, p.Next(true), test.ShouldEqual, time.Second*5)
+	test.That(t, p.Next(true), test.ShouldEqual, time.Second*30)
+	test.That(t, p.Escalated(), test.ShouldBeFalse)
+
+	// never longer than the base interval, and disabled without a failed interval
+	p.FailedInterval = time.Minute
+	p.Next(false)
+	test.That(t, p.Next(false), test.ShouldEqual, time.Second*30)
+	p = EscalationPolicy{BaseInterval: time.Second * 30}
+	test.That(t, p.Next(false), test.ShouldEqual, time.Second*30)
+}
+
+func TestNextHealthCheck(t *testing.T) {
+	m := &Manager{logger: logging.NewTestLogger(t), healthStatus: map[string]error{"a": nil}}
+	attrs, err := structpb.NewStruct(map[string]any{"health_check_failed_interval": "5s"})
+	test.That(t, err, test.ShouldBeNil)
+	m.applyAgentConfig(&pb.DeviceSubsystemConfig{Attributes: attrs})
+
+	test.That(t, m.nextHealthCheck(time.Minute), test.ShouldEqual, time.Minute)
+	m.healthStatus["a"] = errors.New("unhealthy")
+	test.That(t, m.nextHealthCheck(time.Minute), test.ShouldEqual, time.Second*5)
+	m.healthStatus["a"] = nil
+	test.That(t, m.nextHealthCheck(time.Minute), test.ShouldEqual, time.Minute)
+}
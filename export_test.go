@@ -0,0 +1,12 @@
+package agent
+
+import "strings"
+
+// Inject processes a synthetic line as if it had been written by the process, so matchers can be tested without one.
+func (l *MatchingLogger) Inject(line string) {
+	if l.lineBuffering && !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	//nolint:errcheck
+	l.Write([]byte(line))
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	errw "github.com/pkg/errors"
+	pb "go.viam.com/api/app/agent/v1"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultHealthCheckConcurrency is how many subsystem healthchecks AggregateHealth runs at once.
+	DefaultHealthCheckConcurrency = 4
+	healthCheckTimeout            = time.Second * 15
+)
+
+// SubsystemHealth is one subsystem's entry in an AggregateHealth report.
+type SubsystemHealth struct {
+	Name    string
+	Err     error
+	Latency time.Duration
+}
+
+// AggregateHealth healthchecks every loaded subsystem, a few at a time, and reports the results sorted by name.
+// Unlike SubsystemHealthChecks, it never restarts anything.
+func (m *Manager) AggregateHealth(ctx context.Context) []SubsystemHealth {
+	m.healthMu.Lock()
+	concurrency := m.healthConcurrency
+	m.healthMu.Unlock()
+	if concurrency <= 0 {
+		concurrency = DefaultHealthCheckConcurrency
+	}
+
+	m.subsystemsMu.Lock()
+	names := make([]string, 0, len(m.loadedSubsystems))
+	checks := make([]func(context.Context) error, 0, len(m.loadedSubsystems))
+	for name, sub := range m.loadedSubsystems {
+		names = append(names, name)
+		checks = append(checks, sub.HealthCheck)
+	}
+	m.subsystemsMu.Unlock()
+
+	var mu sync.Mutex
+	report := make([]SubsystemHealth, 0, len(names))
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for i := range names {
+		name, check := names[i], checks[i]
+		group.Go(func() error {
+			start := time.Now()
+			err := checkWithTimeout(ctx, check, healthCheckTimeout)
+			mu.Lock()
+			defer mu.Unlock()
+			report = append(report, SubsystemHealth{Name: name, Err: err, Latency: time.Since(start)})
+			return nil
+		})
+	}
+	//nolint:errcheck
+	group.Wait()
+
+	sort.Slice(report, func(i, j int) bool { return report[i].Name < report[j].Name })
+	return report
+}
+
+// HealthSummary is a single rollup of subsystem health, see Manager.HealthSummary.
+type HealthSummary struct {
+	TotalSubsystems     int      `json:"total_subsystems"`
+	HealthySubsystems   int      `json:"healthy_subsystems"`
+	UnhealthySubsystems []string `json:"unhealthy_subsystems"`
+	// healthy subsystems whose readiness check fails, e.g. viam-server before it's been healthy for its steady state
+	NotReadySubsystems []string `json:"not_ready_subsystems"`
+	OverallHealthy     bool     `json:"overall_healthy"`
+}
+
+// HealthSummary healthchecks every loaded subsystem (like AggregateHealth) and rolls the results up into a single answer.
+// OverallHealthy is true when every subsystem with the "required" attribute is healthy, or when every subsystem is
+// healthy if none are marked required. Readiness is reported, but doesn't affect OverallHealthy.
+func (m *Manager) HealthSummary(ctx context.Context) HealthSummary {
+	return m.summarizeHealth(ctx, m.AggregateHealth(ctx))
+}
+
+// summarizeHealth rolls up an AggregateHealth report, see HealthSummary.
+func (m *Manager) summarizeHealth(ctx context.Context, report []SubsystemHealth) HealthSummary {
+	m.healthMu.Lock()
+	required := make(map[string]bool, len(m.required))
+	for name, isRequired := range m.required {
+		required[name] = isRequired
+	}
+	m.healthMu.Unlock()
+	anyRequired := false
+	for _, health := range report {
+		anyRequired = anyRequired || required[health.Name]
+	}
+
+	summary := HealthSummary{TotalSubsystems: len(report), OverallHealthy: true}
+	var healthy []string
+	for _, health := range report {
+		if health.Err == nil {
+			summary.HealthySubsystems++
+			healthy = append(healthy, health.Name)
+			continue
+		}
+		summary.UnhealthySubsystems = append(summary.UnhealthySubsystems, health.Name)
+		if required[health.Name] || !anyRequired {
+			summary.OverallHealthy = false
+		}
+	}
+	summary.NotReadySubsystems = m.notReady(ctx, healthy)
+	return summary
+}
+
+// notReady runs the readiness check of each named subsystem that has one, a few at a time, and returns the names of
+// those that aren't ready, sorted.
+func (m *Manager) notReady(ctx context.Context, names []string) []string {
+	m.healthMu.Lock()
+	concurrency := m.healthConcurrency
+	m.healthMu.Unlock()
+	if concurrency <= 0 {
+		concurrency = DefaultHealthCheckConcurrency
+	}
+
+	m.subsystemsMu.Lock()
+	checkers := make(map[string]readinessChecker, len(names))
+	for _, name := range names {
+		if checker, ok := m.loadedSubsystems[name].(readinessChecker); ok {
+			checkers[name] = checker
+		}
+	}
+	m.subsystemsMu.Unlock()
+
+	var mu sync.Mutex
+	var ret []string
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for name, checker := range checkers {
+		name, checker := name, checker
+		group.Go(func() error {
+			if err := checkWithTimeout(ctx, checker.Readiness, healthCheckTimeout); err != nil {
+				m.logger.Debugw("subsystem not ready", "subsystem", name, "error", err)
+				mu.Lock()
+				defer mu.Unlock()
+				ret = append(ret, name)
+			}
+			return nil
+		})
+	}
+	//nolint:errcheck
+	group.Wait()
+
+	sort.Strings(ret)
+	return ret
+}
+
+// setRequired records whether a subsystem's config marks it as required for overall health.
+func (m *Manager) setRequired(name string, cfg *pb.DeviceSubsystemConfig) {
+	isRequired, _ := cfg.GetAttributes().AsMap()["required"].(bool) //nolint:errcheck
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	if m.required == nil {
+		m.required = make(map[string]bool)
+	}
+	m.required[name] = isRequired
+}
+
+// checkWithTimeout runs check, but gives up after timeout even if check ignores its context,
+// so a hung check can't hold a worker forever.
+func checkWithTimeout(ctx context.Context, check func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return errw.Wrap(ctx.Err(), "healthcheck abandoned")
+	}
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent/subsystems"
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type slowSubsystem struct {
+	fakeSubsystem
+	delay            time.Duration
+	running, maxSeen *atomic.Int32
+}
+
+func (s *slowSubsystem) HealthCheck(ctx context.Context) error {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		seen := s.maxSeen.Load()
+		if n <= seen || s.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	time.Sleep(s.delay)
+	return s.healthErr
+}
+
+func TestAggregateHealth(t *testing.T) {
+	var running, maxSeen atomic.Int32
+	errUnhealthy := errors.New("unhealthy")
+	loaded := make(map[string]subsystems.Subsystem)
+	for i := 0; i < 8; i++ {
+		sub := &slowSubsystem{delay: time.Millisecond * 50, running: &running, maxSeen: &maxSeen}
+		if i == 3 {
+			sub.healthErr = errUnhealthy
+		}
+		loaded[fmt.Sprintf("sub%d", i)] = sub
+	}
+	m := &Manager{logger: logging.NewTestLogger(t), loadedSubsystems: loaded, healthConcurrency: 3}
+
+	report := m.AggregateHealth(context.Background())
+	test.That(t, report, test.ShouldHaveLength, 8)
+	test.That(t, maxSeen.Load(), test.ShouldEqual, 3)
+	for i, entry := range report {
+		test.That(t, entry.Name, test.ShouldEqual, fmt.Sprintf("sub%d", i))
+		test.That(t, entry.Latency, test.ShouldBeGreaterThanOrEqualTo, time.Millisecond*50)
+		if i == 3 {
+			test.That(t, entry.Err, test.ShouldEqual, errUnhealthy)
+		} else {
+			test.That(t, entry.Err, test.ShouldBeNil)
+		}
+	}
+}
+
+func TestSubsystemHealthChecksConcurrency(t *testing.T) {
+	var running, maxSeen atomic.Int32
+	loaded := make(map[string]subsystems.Subsystem)
+	subs := make([]*slowSubsystem, 0, 6)
+	for i := 0; i < 6; i++ {
+		sub := &slowSubsystem{delay: time.Millisecond * 50, running: &running, maxSeen: &maxSeen}
+		if i == 2 {
+			sub.healthErr = errors.New("unhealthy")
+		}
+		subs = append(subs, sub)
+		loaded[fmt.Sprintf("sub%d", i)] = sub
+	}
+	m := &Manager{
+		logger:            logging.NewTestLogger(t),
+		loadedSubsystems:  loaded,
+		healthStatus:      map[string]error{},
+		healthConcurrency: 2,
+	}
+
+	m.SubsystemHealthChecks(context.Background())
+	test.That(t, maxSeen.Load(), test.ShouldEqual, 2)
+	// only the unhealthy one is restarted
+	for i, sub := range subs {
+		if i == 2 {
+			test.That(t, sub.starts, test.ShouldEqual, 1)
+			test.That(t, m.healthStatus["sub2"], test.ShouldNotBeNil)
+		} else {
+			test.That(t, sub.starts, test.ShouldEqual, 0)
+		}
+	}
+}
+
+func TestCheckWithTimeout(t *testing.T) {
+	hung := make(chan struct{})
+	defer close(hung)
+	start := time.Now()
+	err := checkWithTimeout(context.Background(), func(context.Context) error {
+		// ignores its context entirely
+		<-hung
+		return nil
+	}, time.Millisecond*50)
+	test.That(t, errors.Is(err, context.DeadlineExceeded), test.ShouldBeTrue)
+	test.That(t, time.Since(start), test.ShouldBeLessThan, time.Second)
+}
+
+func TestHealthSummary(t *testing.T) {
+	ctx := context.Background()
+	errUnhealthy := errors.New("unhealthy")
+	healthy := &fakeSubsystem{}
+	unhealthy := &fakeSubsystem{healthErr: errUnhealthy}
+	m := &Manager{
+		logger:           logging.NewTestLogger(t),
+		loadedSubsystems: map[string]subsystems.Subsystem{"healthy": healthy, "unhealthy": unhealthy},
+	}
+
+	// with nothing marked required, everything is
+	summary := m.HealthSummary(ctx)
+	test.That(t, summary, test.ShouldResemble, HealthSummary{
+		TotalSubsystems:     2,
+		HealthySubsystems:   1,
+		UnhealthySubsystems: []string{"unhealthy"},
+		OverallHealthy:      false,
+	})
+
+	attrs, err := structpb.NewStruct(map[string]any{"required": true})
+	test.That(t, err, test.ShouldBeNil)
+	m.setRequired("healthy", &pb.DeviceSubsystemConfig{Attributes: attrs})
+	m.setRequired("unhealthy", &pb.DeviceSubsystemConfig{})
+	summary = m.HealthSummary(ctx)
+	test.That(t, summary.OverallHealthy, test.ShouldBeTrue)
+	test.That(t, summary.UnhealthySubsystems, test.ShouldResemble, []string{"unhealthy"})
+
+	// not ready is reported, but only for healthy subsystems, and doesn't affect overall health
+	healthy.readyErr = errors.New("not ready")
+	unhealthy.readyErr = errors.New("not ready")
+	summary = m.HealthSummary(ctx)
+	test.That(t, summary.NotReadySubsystems, test.ShouldResemble, []string{"healthy"})
+	test.That(t, summary.OverallHealthy, test.ShouldBeTrue)
+
+	healthy.healthErr = errUnhealthy
+	summary = m.HealthSummary(ctx)
+	test.That(t, summary.OverallHealthy, test.ShouldBeFalse)
+	test.That(t, summary.HealthySubsystems, test.ShouldEqual, 0)
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"go.viam.com/rdk/logging"
+)
+
+const (
+	DefaultHookTimeout = time.Second * 30
+	// how long to wait for output to be drained after a hook exits or is killed.
+	hookWaitDelay = time.Second * 5
+)
+
+// RunHook runs a hook command (argv) to completion, with its output sent to the logger. It does nothing if argv is empty.
+// If the hook runs longer than timeout, its whole process group is killed so no hook children are left behind.
+func RunHook(ctx context.Context, logger logging.Logger, name string, argv []string, timeout time.Duration) error {
+	if len(argv) == 0 {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	logger.Infof("running %s hook: %v", name, argv)
+	//nolint:gosec
+	cmd := exec.CommandContext(timeoutCtx, argv[0], argv[1:]...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return KillProcessGroup(cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = hookWaitDelay
+	cmd.Stdout = NewMatchingLogger(logger, false, false)
+	cmd.Stderr = NewMatchingLogger(logger, true, false)
+
+	err := cmd.Run()
+	if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+		return errw.Errorf("%s hook timed out after %s", name, timeout)
+	}
+	if err != nil {
+		return errw.Wrapf(err, "running %s hook", name)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+// processGone returns true if pid no longer exists, or is only a zombie waiting to be reaped.
+func processGone(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
+
+func TestRunHook(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+
+	t.Run("success", func(t *testing.T) {
+		test.That(t, RunHook(context.Background(), logger, "test", []string{"true"}, time.Second), test.ShouldBeNil)
+		test.That(t, RunHook(context.Background(), logger, "test", nil, time.Second), test.ShouldBeNil)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		err := RunHook(context.Background(), logger, "test", []string{"false"}, time.Second)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("timeout-kills-children", func(t *testing.T) {
+		pidFile := filepath.Join(t.TempDir(), "child.pid")
+		start := time.Now()
+		err := RunHook(context.Background(), logger, "test",
+			[]string{"sh", "-c", fmt.Sprintf("sleep 30 & echo $! > %s; wait", pidFile)}, time.Millisecond*500)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "timed out")
+		test.That(t, time.Since(start), test.ShouldBeLessThan, hookWaitDelay)
+
+		pidBytes, err := os.ReadFile(pidFile)
+		test.That(t, err, test.ShouldBeNil)
+		pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes)))
+		test.That(t, err, test.ShouldBeNil)
+		for i := 0; i < 100 && !processGone(pid); i++ {
+			time.Sleep(time.Millisecond * 10)
+		}
+		test.That(t, processGone(pid), test.ShouldBeTrue)
+	})
+}
@@ -2,12 +2,15 @@ package agent
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -34,11 +37,94 @@ type matcher struct {
 	regex   *regexp.Regexp
 	channel chan ([]string)
 	mask    bool
+	// closed by DeleteMatcher, to release a write blocked on a full channel that's no longer read
+	done chan struct{}
+	// set instead of channel for AddBatchMatcher
+	batch *matchBatch
+}
+
+// MatchingLoggerOption configures optional MatchingLogger behavior.
+type MatchingLoggerOption func(*MatchingLogger)
+
+// WithSampling enables sampling of lines that are below warn level and don't hit a matcher. Within each tick,
+// the first lines are logged, and after that only every thereafter-th line. Zero values leave sampling disabled.
+func WithSampling(tick time.Duration, first, thereafter int) MatchingLoggerOption {
+	return func(l *MatchingLogger) {
+		if tick <= 0 || first < 0 || thereafter <= 0 {
+			return
+		}
+		l.sampler = &sampler{tick: tick, first: first, thereafter: thereafter}
+	}
+}
+
+// WithLineBuffering buffers partial writes until a newline arrives, so matchers and logging only ever see
+// complete lines. Partial lines longer than maxBytes are processed as they are, to bound memory.
+func WithLineBuffering(maxBytes int) MatchingLoggerOption {
+	return func(l *MatchingLogger) {
+		if maxBytes <= 0 {
+			return
+		}
+		l.lineBuffering = true
+		l.maxLineBytes = maxBytes
+	}
+}
+
+// WithLineSink calls fn with every line that is logged (not masked or sampled out), such as to forward them elsewhere.
+func WithLineSink(fn func(level zapcore.Level, line string)) MatchingLoggerOption {
+	return func(l *MatchingLogger) { l.sink = fn }
+}
+
+// WithRawLineSink calls fn with every line written, before matching, masking or sampling, such as to keep the first
+// lines of output. It must not block.
+func WithRawLineSink(fn func(line string)) MatchingLoggerOption {
+	return func(l *MatchingLogger) { l.rawSink = fn }
+}
+
+// WithJSONOutput writes the lines that would otherwise be printed as they are (structured lines that aren't uploaded)
+// to w as JSON records instead, tagged with subsystem and stream, so log aggregators get a consistent structure
+// whatever the agent's own console encoding. Lines passed on to the agent's logger (for upload) are unaffected.
+func WithJSONOutput(w io.Writer, subsystem, stream string) MatchingLoggerOption {
+	return func(l *MatchingLogger) {
+		l.jsonOut = w
+		l.jsonSubsystem = subsystem
+		l.jsonStream = stream
+	}
+}
+
+// jsonRecord is a line as written by WithJSONOutput.
+type jsonRecord struct {
+	Time      string `json:"ts"`
+	Level     string `json:"level"`
+	Subsystem string `json:"subsystem"`
+	Stream    string `json:"stream"`
+	Message   string `json:"msg"`
+}
+
+// writeJSON wraps a raw line in a jsonRecord.
+func (l *MatchingLogger) writeJSON(level zapcore.Level, p []byte) (int, error) {
+	out, err := json.Marshal(jsonRecord{
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Level:     level.String(),
+		Subsystem: l.jsonSubsystem,
+		Stream:    l.jsonStream,
+		Message:   strings.TrimSpace(string(p)),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := l.jsonOut.Write(append(out, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
 
 // NewMatchingLogger returns a MatchingLogger.
-func NewMatchingLogger(logger logging.Logger, isError, uploadAll bool) *MatchingLogger {
-	return &MatchingLogger{logger: logger, defaultError: isError, uploadAll: uploadAll}
+func NewMatchingLogger(logger logging.Logger, isError, uploadAll bool, opts ...MatchingLoggerOption) *MatchingLogger {
+	l := &MatchingLogger{logger: logger, defaultError: isError, uploadAll: uploadAll}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
 // MatchingLogger provides a logger that also allows sending regex matched lines to a channel.
@@ -47,8 +133,69 @@ type MatchingLogger struct {
 	logger       logging.Logger
 	matchers     map[string]matcher
 	defaultError bool
+	// a copy of each matcher's done channel, so DeleteMatcher can close it without waiting on mu, which a blocked
+	// write holds
+	doneMu       sync.Mutex
+	matcherDones map[string]chan struct{}
 	// if uploadAll is false, only send unstructured log lines to the logger, and just print structured ones.
 	uploadAll bool
+	// optional, drops a portion of low level lines on chatty subprocesses.
+	sampler *sampler
+	// optional, holds incomplete lines between writes.
+	lineBuffering bool
+	maxLineBytes  int
+	partialMu     sync.Mutex
+	partial       []byte
+	// optional, makes written bytes available to Read.
+	tap *ringBuffer
+	// optional, receives the matches of every matcher.
+	aggregated chan NamedMatch
+	// matches not sent to aggregated as it was full, see AggregatedDropped
+	aggregatedDropped atomic.Uint64
+	// optional, receives every logged line.
+	sink func(level zapcore.Level, line string)
+	// optional, receives every written line, see WithRawLineSink.
+	rawSink func(line string)
+	// optional, decouples writers from processing, see WithHighThroughputMode.
+	ring *mpscRing
+	// optional, see WithJSONOutput.
+	jsonOut       io.Writer
+	jsonSubsystem string
+	jsonStream    string
+	// optional, see WithBatchWindow.
+	batchWindow time.Duration
+}
+
+// NamedMatch is a match from any matcher, as delivered by AggregatedMatches.
+type NamedMatch struct {
+	Name    string
+	Matches []string
+}
+
+// sampler works like zap's sampling, counting lines per tick.
+type sampler struct {
+	mu          sync.Mutex
+	tick        time.Duration
+	first       int
+	thereafter  int
+	windowStart time.Time
+	count       int
+}
+
+// allow returns true if the next line should be logged.
+func (s *sampler) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.tick {
+		s.windowStart = now
+		s.count = 0
+	}
+	s.count++
+	if s.count <= s.first {
+		return true
+	}
+	return (s.count-s.first)%s.thereafter == 0
 }
 
 // AddMatcher adds a named regex to filter from results and return to a channel, optionally masking it from normal logging.
@@ -63,32 +210,157 @@ func (l *MatchingLogger) AddMatcher(name string, regex *regexp.Regexp, mask bool
 		return nil, errors.Errorf("matcher already exists: %s", name)
 	}
 	c := make(chan []string, 32)
-	l.matchers[name] = matcher{regex: regex, channel: c, mask: mask}
+	done := make(chan struct{})
+	l.matchers[name] = matcher{regex: regex, channel: c, mask: mask, done: done}
+	l.doneMu.Lock()
+	defer l.doneMu.Unlock()
+	if l.matcherDones == nil {
+		l.matcherDones = make(map[string]chan struct{})
+	}
+	l.matcherDones[name] = done
 	return c, nil
 }
 
-// DeleteMatcher removes a previously added matcher.
+// DeleteMatcher removes a previously added matcher. Writes blocked on sending it a match (because its channel is full
+// and no longer read) give up, so callers may abandon the channel as long as they delete the matcher.
 func (l *MatchingLogger) DeleteMatcher(name string) {
+	l.doneMu.Lock()
+	if done, ok := l.matcherDones[name]; ok {
+		close(done)
+		delete(l.matcherDones, name)
+	}
+	l.doneMu.Unlock()
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	m, ok := l.matchers[name]
 	if ok {
-		close(m.channel)
+		if m.batch != nil {
+			m.batch.close()
+		} else {
+			close(m.channel)
+		}
 		delete(l.matchers, name)
 	}
 }
 
+// AggregatedMatches returns a channel that receives the matches of all matchers, tagged with the matcher name,
+// in addition to their own channels. Unlike the per-matcher channels, writes never wait for it to be read, matches
+// that don't fit in its buffer are dropped and counted by AggregatedDropped.
+func (l *MatchingLogger) AggregatedMatches() <-chan NamedMatch {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.aggregated == nil {
+		l.aggregated = make(chan NamedMatch, 32)
+	}
+	return l.aggregated
+}
+
+// AggregatedDropped returns how many matches weren't sent to AggregatedMatches, as its buffer was full.
+func (l *MatchingLogger) AggregatedDropped() uint64 {
+	return l.aggregatedDropped.Load()
+}
+
+// CloseAggregatedMatches closes the channel returned by AggregatedMatches, if any.
+func (l *MatchingLogger) CloseAggregatedMatches() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.aggregated != nil {
+		close(l.aggregated)
+		l.aggregated = nil
+	}
+}
+
 // Write takes input and filters it against each defined matcher, before logging it.
 func (l *MatchingLogger) Write(p []byte) (int, error) {
-	var mask bool
+	if l.tap != nil {
+		if n, err := l.tap.Write(p); err != nil {
+			return n, err
+		}
+	}
+	if l.ring != nil {
+		l.enqueue(p)
+		return len(p), nil
+	}
+	return l.process(p)
+}
+
+// process does the line buffering (if enabled), matching, and logging for a write.
+func (l *MatchingLogger) process(p []byte) (int, error) {
+	if !l.lineBuffering {
+		return l.writeLine(p)
+	}
+
+	l.partialMu.Lock()
+	defer l.partialMu.Unlock()
+	buf := append(l.partial, p...)
+	for {
+		idx := bytes.IndexByte(buf, '\n')
+		if idx < 0 {
+			break
+		}
+		//nolint:errcheck
+		l.writeLine(buf[:idx+1])
+		buf = buf[idx+1:]
+	}
+	for len(buf) >= l.maxLineBytes {
+		//nolint:errcheck
+		l.writeLine(buf[:l.maxLineBytes])
+		buf = buf[l.maxLineBytes:]
+	}
+	// copied so the remainder doesn't pin an ever growing backing array
+	l.partial = append([]byte(nil), buf...)
+	return len(p), nil
+}
+
+// Flush processes any buffered partial line, such as the last output of a process that has exited, and sends any
+// pending batches.
+func (l *MatchingLogger) Flush() {
+	if l.ring != nil {
+		l.drain()
+	}
+	l.partialMu.Lock()
+	if len(l.partial) > 0 {
+		//nolint:errcheck
+		l.writeLine(l.partial)
+		l.partial = nil
+	}
+	l.partialMu.Unlock()
+	l.flushBatches()
+}
+
+// writeLine does the matching and logging for a single write, or a single line if line buffering is enabled.
+func (l *MatchingLogger) writeLine(p []byte) (int, error) {
+	var mask, matched bool
+
+	if l.rawSink != nil {
+		l.rawSink(strings.TrimSpace(string(p)))
+	}
 
 	// send matches to channel(s)
 	l.mu.RLock()
 	defer l.mu.RUnlock()
-	for _, m := range l.matchers {
+	for name, m := range l.matchers {
 		matches := m.regex.FindStringSubmatch(string(p))
 		if matches != nil {
-			m.channel <- matches
+			matched = true
+			if m.batch != nil {
+				m.batch.add(matches)
+			} else {
+				select {
+				case m.channel <- matches:
+				case <-m.done:
+					// deleted while full, nothing will read this
+				}
+			}
+			if l.aggregated != nil {
+				select {
+				case l.aggregated <- NamedMatch{Name: name, Matches: matches}:
+				default:
+					// a stalled consumer mustn't stall the process's output
+					l.aggregatedDropped.Add(1)
+				}
+			}
 			if m.mask {
 				mask = true
 			}
@@ -103,6 +375,22 @@ func (l *MatchingLogger) Write(p []byte) (int, error) {
 
 	// TODO(RSDK-7895): the lines from subprocess stdout are sometimes multi-line.
 	dateMatched := dateRegex.Match(p)
+
+	// unstructured lines are always logged at warn or error, so only structured lines are sampled
+	if dateMatched && !matched && l.sampler != nil && parseLog(p).zapLevel() < zapcore.WarnLevel && !l.sampler.allow() {
+		return len(p), nil
+	}
+
+	if l.sink != nil {
+		level := zapcore.WarnLevel
+		if dateMatched {
+			level = parseLog(p).zapLevel()
+		} else if l.defaultError {
+			level = zapcore.ErrorLevel
+		}
+		l.sink(level, strings.TrimSpace(string(p)))
+	}
+
 	if !dateMatched { //nolint:gocritic
 		// this case is the 'unstructured error' case; we were unable to parse a date.
 		lines := strings.ReplaceAll(strings.TrimSpace(string(p)), "\n", "\n\t")
@@ -125,6 +413,9 @@ func (l *MatchingLogger) Write(p []byte) (int, error) {
 		l.logger.Write(&logging.LogEntry{Entry: entry})
 	} else {
 		// this case is already-structured logging from non-uploadAll; we print it but don't upload it.
+		if l.jsonOut != nil {
+			return l.writeJSON(parseLog(p).zapLevel(), p)
+		}
 		return os.Stdout.Write(p)
 	}
 	// note: this return isn't quite right; we don't know how many bytes we wrote, it can be greater
@@ -154,12 +445,18 @@ func stripAnsiColorCodes(raw []byte) []byte {
 	return colorCodeRegexp.ReplaceAll(raw, nil)
 }
 
-// entry converts a parsedLog to a zapcore.Entry which can be NetAppender'd.
-func (p parsedLog) entry() zapcore.Entry {
+// zapLevel returns the parsed level, defaulting to warn if unknown.
+func (p parsedLog) zapLevel() zapcore.Level {
 	level, ok := levels[string(p.level)]
 	if !ok {
-		level = zapcore.WarnLevel
+		return zapcore.WarnLevel
 	}
+	return level
+}
+
+// entry converts a parsedLog to a zapcore.Entry which can be NetAppender'd.
+func (p parsedLog) entry() zapcore.Entry {
+	level := p.zapLevel()
 	file, rawLine, defined := bytes.Cut(p.location, []byte{':'})
 	line, _ := strconv.ParseUint(string(rawLine), 10, 64) //nolint:errcheck
 	return zapcore.Entry{
@@ -1,9 +1,16 @@
 package agent
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap/zapcore"
+	"go.viam.com/rdk/logging"
 	"go.viam.com/test"
 )
 
@@ -42,3 +49,225 @@ func TestParseLog(t *testing.T) {
 		test.That(t, entry.Message, test.ShouldResemble, "")
 	})
 }
+
+func TestMatchingLoggerSampling(t *testing.T) {
+	logger, observed := logging.NewObservedTestLogger(t)
+	ml := NewMatchingLogger(logger, false, true, WithSampling(time.Hour, 2, 3))
+	_, err := ml.AddMatcher("important", regexp.MustCompile(`important`), false)
+	test.That(t, err, test.ShouldBeNil)
+
+	for i := 0; i < 10; i++ {
+		_, err := ml.Write([]byte(fmt.Sprintf("2024-06-01T00:00:00\tINFO\tsampletest\tfile.go:10\tchatty %d", i)))
+		test.That(t, err, test.ShouldBeNil)
+	}
+	// first 2, then every 3rd of the remaining 8
+	test.That(t, observed.FilterMessageSnippet("chatty").Len(), test.ShouldEqual, 4)
+
+	// warnings and matched lines always get through
+	for i := 0; i < 5; i++ {
+		ml.Write([]byte("2024-06-01T00:00:00\tWARN\tsampletest\tfile.go:10\twarning"))
+		ml.Write([]byte("2024-06-01T00:00:00\tINFO\tsampletest\tfile.go:10\timportant"))
+	}
+	test.That(t, observed.FilterMessageSnippet("warning").Len(), test.ShouldEqual, 5)
+	test.That(t, observed.FilterMessageSnippet("important").Len(), test.ShouldEqual, 5)
+}
+
+func TestMatchingLoggerInject(t *testing.T) {
+	logger, observed := logging.NewObservedTestLogger(t)
+	ml := NewMatchingLogger(logger, false, true)
+	c, err := ml.AddMatcher("checkURL", regexp.MustCompile(`serving\W*{"url":\W*"(https?://[\w\.:-]+)"`), true)
+	test.That(t, err, test.ShouldBeNil)
+	defer ml.DeleteMatcher("checkURL")
+
+	ml.Inject(`2024-06-01T00:00:00	INFO	robot_server	web/web.go:10	serving	{"url": "https://localhost:8080"}`)
+	select {
+	case matches := <-c:
+		test.That(t, matches[1], test.ShouldEqual, "https://localhost:8080")
+	default:
+		t.Fatal("matcher didn't fire")
+	}
+	// masked lines aren't logged
+	test.That(t, observed.Len(), test.ShouldEqual, 0)
+
+	ml.Inject("no match here")
+	test.That(t, len(c), test.ShouldEqual, 0)
+	test.That(t, observed.FilterMessageSnippet("no match here").Len(), test.ShouldEqual, 1)
+}
+
+func TestMatchingLoggerLineBuffering(t *testing.T) {
+	logger, observed := logging.NewObservedTestLogger(t)
+	ml := NewMatchingLogger(logger, false, true, WithLineBuffering(64))
+	c, err := ml.AddMatcher("match", regexp.MustCompile(`^match (\d+)\n$`), false)
+	test.That(t, err, test.ShouldBeNil)
+	defer ml.DeleteMatcher("match")
+
+	var input string
+	for i := 0; i < 10; i++ {
+		input += fmt.Sprintf("match %d\n", i)
+	}
+	// split at varying boundaries, including mid-line and right at newlines
+	for start, size := 0, 1; start < len(input); size = size%7 + 1 {
+		end := min(start+size, len(input))
+		n, err := ml.Write([]byte(input[start:end]))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, n, test.ShouldEqual, end-start)
+		start = end
+	}
+	test.That(t, len(c), test.ShouldEqual, 10)
+	for i := 0; i < 10; i++ {
+		matches := <-c
+		test.That(t, matches[1], test.ShouldEqual, fmt.Sprint(i))
+	}
+
+	// a partial line is only processed once it's complete, or flushed
+	_, err = ml.Write([]byte("match 10"))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, len(c), test.ShouldEqual, 0)
+	_, err = ml.Write([]byte("\n"))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, len(c), test.ShouldEqual, 1)
+	<-c
+
+	_, err = ml.Write([]byte("trailing output"))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, observed.FilterMessageSnippet("trailing output").Len(), test.ShouldEqual, 0)
+	ml.Flush()
+	test.That(t, observed.FilterMessageSnippet("trailing output").Len(), test.ShouldEqual, 1)
+
+	// overlong lines are processed in chunks rather than buffered forever
+	_, err = ml.Write([]byte(strings.Repeat("x", 150)))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, observed.FilterMessageSnippet(strings.Repeat("x", 64)).Len(), test.ShouldEqual, 2)
+}
+
+func TestMatchingLoggerAggregatedMatches(t *testing.T) {
+	ml := NewMatchingLogger(logging.NewTestLogger(t), false, false)
+	errs, err := ml.AddMatcher("error", regexp.MustCompile(`error: (\w+)`), true)
+	test.That(t, err, test.ShouldBeNil)
+	defer ml.DeleteMatcher("error")
+	_, err = ml.AddMatcher("panic", regexp.MustCompile(`panic: (\w+)`), true)
+	test.That(t, err, test.ShouldBeNil)
+	defer ml.DeleteMatcher("panic")
+
+	all := ml.AggregatedMatches()
+	ml.Inject("error: disk")
+	ml.Inject("panic: nil")
+	ml.CloseAggregatedMatches()
+
+	var got []NamedMatch
+	for match := range all {
+		got = append(got, match)
+	}
+	test.That(t, got, test.ShouldResemble, []NamedMatch{
+		{Name: "error", Matches: []string{"error: disk", "disk"}},
+		{Name: "panic", Matches: []string{"panic: nil", "nil"}},
+	})
+
+	// the per-matcher channels still receive their own matches
+	test.That(t, len(errs), test.ShouldEqual, 1)
+}
+
+func TestMatchingLoggerAggregatedMatchesStalled(t *testing.T) {
+	ml := NewMatchingLogger(logging.NewTestLogger(t), false, false)
+	c, err := ml.AddMatcher("match", regexp.MustCompile(`match`), true)
+	test.That(t, err, test.ShouldBeNil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		//nolint:revive
+		for range c {
+		}
+	}()
+
+	// never read, so everything past its buffer is dropped rather than blocking writes
+	ml.AggregatedMatches()
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i < 100; i++ {
+			ml.Inject("match")
+		}
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second * 5):
+		t.Fatal("writes blocked on a stalled aggregated consumer")
+	}
+	test.That(t, ml.AggregatedDropped(), test.ShouldEqual, 100-32)
+
+	ml.CloseAggregatedMatches()
+	ml.DeleteMatcher("match")
+	<-done
+}
+
+func TestMatchingLoggerLineSink(t *testing.T) {
+	type sunk struct {
+		level zapcore.Level
+		line  string
+	}
+	var got []sunk
+	ml := NewMatchingLogger(logging.NewTestLogger(t), true, false, WithLineSink(func(level zapcore.Level, line string) {
+		got = append(got, sunk{level, line})
+	}))
+	_, err := ml.AddMatcher("masked", regexp.MustCompile(`secret`), true)
+	test.That(t, err, test.ShouldBeNil)
+
+	ml.Inject("2024-06-01T00:00:00\tDEBUG\ttest\tfile.go:1\tstructured\n")
+	ml.Inject("unstructured\n")
+	ml.Inject("secret\n")
+	test.That(t, got, test.ShouldResemble, []sunk{
+		{zapcore.DebugLevel, "2024-06-01T00:00:00\tDEBUG\ttest\tfile.go:1\tstructured"},
+		{zapcore.ErrorLevel, "unstructured"},
+	})
+}
+
+func TestMatchingLoggerJSONOutput(t *testing.T) {
+	var buf bytes.Buffer
+	ml := NewMatchingLogger(logging.NewTestLogger(t), false, false, WithJSONOutput(&buf, "viam-server", "stdout"))
+	line := "2024-06-01T00:00:00\tWARN\trdk\tfile.go:1\tsome \"quoted\" message\n"
+	n, err := ml.Write([]byte(line))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, n, test.ShouldEqual, len(line))
+	// unstructured lines go to the agent's logger, as before
+	ml.Inject("unstructured\n")
+
+	var rec map[string]string
+	test.That(t, json.Unmarshal(buf.Bytes(), &rec), test.ShouldBeNil)
+	test.That(t, rec["level"], test.ShouldEqual, "warn")
+	test.That(t, rec["subsystem"], test.ShouldEqual, "viam-server")
+	test.That(t, rec["stream"], test.ShouldEqual, "stdout")
+	test.That(t, rec["msg"], test.ShouldEqual, strings.TrimSpace(line))
+	test.That(t, rec["ts"], test.ShouldNotBeEmpty)
+}
+
+func TestMatchingLoggerAbandonedMatcher(t *testing.T) {
+	ml := NewMatchingLogger(logging.NewTestLogger(t), false, false)
+	// never read, like Start's matcher after it times out
+	_, err := ml.AddMatcher("abandoned", regexp.MustCompile(`match`), true)
+	test.That(t, err, test.ShouldBeNil)
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		// more than the channel buffers, so this blocks until the matcher is deleted
+		for i := 0; i < 100; i++ {
+			ml.Inject("match\n")
+		}
+	}()
+	select {
+	case <-written:
+		t.Fatal("writes didn't block on the full channel")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	ml.DeleteMatcher("abandoned")
+	select {
+	case <-written:
+	case <-time.After(time.Second * 5):
+		t.Fatal("writer still blocked after DeleteMatcher")
+	}
+	// and the name can be reused
+	_, err = ml.AddMatcher("abandoned", regexp.MustCompile(`match`), true)
+	test.That(t, err, test.ShouldBeNil)
+	ml.DeleteMatcher("abandoned")
+}
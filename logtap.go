@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// WithPassthrough makes the MatchingLogger readable, so it can sit as a tap between two streams. Everything
+// written is also buffered (up to size bytes) to be returned by Read. Writes block while the buffer is full.
+func WithPassthrough(size int) MatchingLoggerOption {
+	return func(l *MatchingLogger) {
+		if size <= 0 {
+			return
+		}
+		l.tap = newRingBuffer(size)
+	}
+}
+
+// Read returns bytes previously passed to Write, or io.EOF once Close has been called and everything is read.
+// It is only usable if the logger was created with WithPassthrough.
+func (l *MatchingLogger) Read(p []byte) (int, error) {
+	if l.tap == nil {
+		return 0, io.EOF
+	}
+	return l.tap.Read(p)
+}
+
+// ReadLine returns the next line written, without its newline.
+// It is only usable if the logger was created with WithPassthrough.
+func (l *MatchingLogger) ReadLine() (string, error) {
+	if l.tap == nil {
+		return "", io.EOF
+	}
+	return l.tap.ReadLine()
+}
+
+// Close marks the end of the written stream, so readers get io.EOF after draining the buffer.
+func (l *MatchingLogger) Close() error {
+	if l.tap != nil {
+		l.tap.Close()
+	}
+	return nil
+}
+
+// ringBuffer is a bounded, blocking, in-memory pipe.
+type ringBuffer struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	start  int
+	count  int
+	closed bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	r := &ringBuffer{buf: make([]byte, size)}
+	r.cond = sync.NewCond(&r.mu)
+	return r
+}
+
+// Write copies all of p into the buffer, waiting for readers to make room as needed.
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	written := 0
+	for written < len(p) {
+		for r.count == len(r.buf) && !r.closed {
+			r.cond.Wait()
+		}
+		if r.closed {
+			return written, io.ErrClosedPipe
+		}
+		end := (r.start + r.count) % len(r.buf)
+		space := len(r.buf) - r.count
+		if end+space > len(r.buf) {
+			space = len(r.buf) - end
+		}
+		n := copy(r.buf[end:end+space], p[written:])
+		r.count += n
+		written += n
+		r.cond.Broadcast()
+	}
+	return written, nil
+}
+
+// Read waits for data, then returns as much as fits in p.
+func (r *ringBuffer) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for r.count == 0 && !r.closed {
+		r.cond.Wait()
+	}
+	if r.count == 0 {
+		return 0, io.EOF
+	}
+	n := r.take(p)
+	r.cond.Broadcast()
+	return n, nil
+}
+
+// ReadLine waits for a full line (or the end of the stream), and returns it without the newline.
+func (r *ringBuffer) ReadLine() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		if idx := r.indexNewline(); idx >= 0 {
+			line := make([]byte, idx+1)
+			r.take(line)
+			r.cond.Broadcast()
+			return string(line[:idx]), nil
+		}
+		// a line longer than the buffer can never complete, so it's returned in pieces
+		if r.count == len(r.buf) || (r.closed && r.count > 0) {
+			line := make([]byte, r.count)
+			r.take(line)
+			r.cond.Broadcast()
+			return string(line), nil
+		}
+		if r.closed {
+			return "", io.EOF
+		}
+		r.cond.Wait()
+	}
+}
+
+// Close wakes all waiters. Buffered data can still be read, and further writes fail.
+func (r *ringBuffer) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	r.cond.Broadcast()
+}
+
+// take moves up to len(p) bytes out of the buffer. Must be called with mu held.
+func (r *ringBuffer) take(p []byte) int {
+	n := 0
+	for n < len(p) && r.count > 0 {
+		chunk := r.count
+		if r.start+chunk > len(r.buf) {
+			chunk = len(r.buf) - r.start
+		}
+		c := copy(p[n:], r.buf[r.start:r.start+chunk])
+		r.start = (r.start + c) % len(r.buf)
+		r.count -= c
+		n += c
+	}
+	return n
+}
+
+// indexNewline returns the offset of the first buffered newline, or -1. Must be called with mu held.
+func (r *ringBuffer) indexNewline() int {
+	first := r.count
+	if r.start+first > len(r.buf) {
+		first = len(r.buf) - r.start
+	}
+	if idx := bytes.IndexByte(r.buf[r.start:r.start+first], '\n'); idx >= 0 {
+		return idx
+	}
+	if idx := bytes.IndexByte(r.buf[:r.count-first], '\n'); idx >= 0 {
+		return first + idx
+	}
+	return -1
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestMatchingLoggerPassthrough(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&input, "2024-06-01T00:00:00\tINFO\ttest\tfile.go:1\tline %d\n", i)
+	}
+
+	// a small buffer forces wraparound and writers waiting on the reader
+	ml := NewMatchingLogger(logging.NewTestLogger(t), false, false, WithPassthrough(16), WithLineBuffering(1024))
+	c, err := ml.AddMatcher("line", regexp.MustCompile(`line (\d+)`), true)
+	test.That(t, err, test.ShouldBeNil)
+	matched := make(chan int)
+	go func() {
+		var count int
+		for range c {
+			count++
+		}
+		matched <- count
+	}()
+
+	copyErr := make(chan error)
+	go func() {
+		_, err := io.Copy(ml, strings.NewReader(input.String()))
+		ml.Close()
+		copyErr <- err
+	}()
+
+	var dst bytes.Buffer
+	_, err = io.Copy(&dst, ml)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, <-copyErr, test.ShouldBeNil)
+	test.That(t, dst.String(), test.ShouldEqual, input.String())
+
+	ml.DeleteMatcher("line")
+	test.That(t, <-matched, test.ShouldEqual, 100)
+}
+
+func TestMatchingLoggerReadLine(t *testing.T) {
+	ml := NewMatchingLogger(logging.NewTestLogger(t), false, false, WithPassthrough(64))
+	go func() {
+		for _, chunk := range []string{"first ", "line\nsecond line\n", "no newline"} {
+			//nolint:errcheck
+			ml.Write([]byte(chunk))
+		}
+		ml.Close()
+	}()
+
+	for _, expected := range []string{"first line", "second line", "no newline"} {
+		line, err := ml.ReadLine()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, line, test.ShouldEqual, expected)
+	}
+	_, err := ml.ReadLine()
+	test.That(t, err, test.ShouldEqual, io.EOF)
+
+	// writes after close fail rather than blocking forever
+	_, err = ml.Write([]byte("late"))
+	test.That(t, err, test.ShouldEqual, io.ErrClosedPipe)
+}
@@ -15,11 +15,13 @@ import (
 	"time"
 
 	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent/config"
 	"github.com/viamrobotics/agent/subsystems"
 	"github.com/viamrobotics/agent/subsystems/registry"
 	pb "go.viam.com/api/app/agent/v1"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/utils/rpc"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -46,13 +48,55 @@ type Manager struct {
 
 	subsystemsMu     sync.Mutex
 	loadedSubsystems map[string]subsystems.Subsystem
+	// the config of each subsystem found crash looping, which isn't started again until that changes
+	crashLooping map[string]*pb.DeviceSubsystemConfig
+	// from each subsystem's boot_priority attribute, see bootOrder
+	bootPriority  map[string]int
+	lastBootOrder []string
+
+	watchdogMu  sync.Mutex
+	watchdog    *HardwareWatchdog
+	watchdogCfg agentConfig
+	// how old the latest round of health checks may get before the agent is assumed hung, see setWatchdogMaxCheckAge
+	watchdogMaxCheckAge time.Duration
+	// systemd's service watchdog, and whether READY=1 should be/has been sent
+	sdWatchdog    *HardwareWatchdog
+	sdNotifyReady bool
+	sdReadySent   bool
+
+	reaperMu     sync.Mutex
+	reaper       *OrphanReaper
+	reaperWarned bool
+
+	healthMu          sync.Mutex
+	unhealthyAction   UnhealthyAction
+	healthStatus      map[string]error
+	healthConcurrency int
+	// subsystems with the "required" attribute set, see HealthSummary
+	required map[string]bool
+	// how often health checks run, see nextHealthCheck
+	escalation EscalationPolicy
 }
 
+// UnhealthyAction is what the manager does when a subsystem fails its healthcheck.
+type UnhealthyAction string
+
+const (
+	// UnhealthyActionRestart restarts the subsystem (the default).
+	UnhealthyActionRestart UnhealthyAction = "restart"
+	// UnhealthyActionAlert logs an error, but leaves the subsystem running for manual intervention.
+	UnhealthyActionAlert UnhealthyAction = "alert"
+	// UnhealthyActionNone only records the failure, see HealthStatus.
+	UnhealthyActionNone UnhealthyAction = "none"
+)
+
 // NewManager returns a new Manager.
 func NewManager(ctx context.Context, logger logging.Logger) (*Manager, error) {
 	manager := &Manager{
 		logger:           logger,
 		loadedSubsystems: make(map[string]subsystems.Subsystem),
+		unhealthyAction:  UnhealthyActionRestart,
+		healthStatus:     make(map[string]error),
 	}
 
 	return manager, manager.LoadSubsystems(ctx)
@@ -69,6 +113,13 @@ func (m *Manager) LoadConfig(cfgPath string) error {
 		return errw.Wrap(err, "reading config file")
 	}
 
+	b, err = config.ExpandEnvInJSON(b)
+	if config.IsUnresolvedOnly(err) {
+		m.logger.Warn(errw.Wrap(err, "expanding config file"))
+	} else if err != nil {
+		return errw.Wrap(err, "parsing config file")
+	}
+
 	cfg := make(map[string]map[string]string)
 	err = json.Unmarshal(b, &cfg)
 	if err != nil {
@@ -141,6 +192,7 @@ func (m *Manager) SelfUpdate(ctx context.Context) (bool, error) {
 	if !ok {
 		return false, errw.Errorf("no %s section found in config", SubsystemName)
 	}
+	m.applyAgentConfig(cfg)
 	return subsys.Update(ctx, cfg)
 }
 
@@ -152,30 +204,237 @@ func (m *Manager) SubsystemUpdates(ctx context.Context, cfg map[string]*pb.Devic
 	m.subsystemsMu.Lock()
 	defer m.subsystemsMu.Unlock()
 
+	// agent-wide settings must be applied before any downloads start
+	m.applyAgentConfig(cfg[SubsystemName])
+
 	// check updates and (re)start
-	for name, sub := range m.loadedSubsystems {
+	for _, name := range m.setBootPriorities(cfg) {
 		if ctx.Err() != nil {
 			return
 		}
-		cancelCtx, cancel := context.WithTimeout(ctx, time.Minute*5)
-		defer cancel()
-		restart, err := sub.Update(cancelCtx, cfg[name])
-		if err != nil {
+		m.updateSubsystem(ctx, name, m.loadedSubsystems[name], cfg[name])
+	}
+}
+
+// updateSubsystem updates a single subsystem, restarting it if needed. Must be called with subsystemsMu held.
+func (m *Manager) updateSubsystem(ctx context.Context, name string, sub subsystems.Subsystem, cfg *pb.DeviceSubsystemConfig) {
+	m.setRequired(name, cfg)
+	cancelCtx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+	restart, err := sub.Update(cancelCtx, cfg)
+	if err != nil {
+		m.logger.Error(err)
+		return
+	}
+	if restart {
+		if err := sub.Stop(ctx); err != nil {
 			m.logger.Error(err)
-			continue
+			return
 		}
-		if restart {
-			if err := sub.Stop(ctx); err != nil {
-				m.logger.Error(err)
-				continue
+	}
+	if held, ok := m.crashLooping[name]; ok {
+		if proto.Equal(held, cfg) {
+			m.logger.Debugf("%s is crash looping, not starting it until its config changes", name)
+			return
+		}
+		m.logger.Infof("config for %s changed, clearing its crash loop", name)
+		delete(m.crashLooping, name)
+		if clearer, ok := sub.(subsystems.FailureStateClearer); ok {
+			clearer.ClearFailureState()
+		}
+	}
+	if err := sub.Start(ctx); err != nil && !errors.Is(err, ErrSubsystemDisabled) {
+		m.logger.Error(err)
+		if errors.Is(err, subsystems.ErrCrashLooping) {
+			m.holdCrashLooping(name, cfg)
+		}
+	}
+}
+
+// holdCrashLooping stops the manager starting a crash looping subsystem until its config changes from cfg. Must be
+// called with subsystemsMu held.
+func (m *Manager) holdCrashLooping(name string, cfg *pb.DeviceSubsystemConfig) {
+	if m.crashLooping == nil {
+		m.crashLooping = make(map[string]*pb.DeviceSubsystemConfig)
+	}
+	var held *pb.DeviceSubsystemConfig
+	if cfg != nil {
+		held, _ = proto.Clone(cfg).(*pb.DeviceSubsystemConfig)
+	}
+	m.crashLooping[name] = held
+}
+
+// applyAgentConfig applies settings from the viam-agent subsystem's attributes that affect the agent as a whole.
+func (m *Manager) applyAgentConfig(cfg *pb.DeviceSubsystemConfig) {
+	agentCfg := agentConfigFromProto(m.logger, cfg)
+
+	if agentCfg.downloadBandwidthBPS != GlobalBandwidthLimiter.LimitBPS() {
+		m.logger.Infof("setting global download bandwidth limit to %d bytes/sec", agentCfg.downloadBandwidthBPS)
+		GlobalBandwidthLimiter.SetLimitBPS(agentCfg.downloadBandwidthBPS)
+	}
+
+	m.configureWatchdog(agentCfg)
+	m.configureSystemdWatchdog(agentCfg)
+	m.configureReaper(agentCfg)
+
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	if agentCfg.unhealthyAction != m.unhealthyAction {
+		m.logger.Infof("setting unhealthy subsystem action to %s", agentCfg.unhealthyAction)
+		m.unhealthyAction = agentCfg.unhealthyAction
+	}
+	m.healthConcurrency = agentCfg.healthCheckConcurrency
+	m.escalation.FailedInterval = agentCfg.healthCheckFailedInterval
+	m.escalation.EscalateAfterFailures = agentCfg.healthCheckEscalateAfter
+	m.escalation.DeescalateAfterSuccesses = agentCfg.healthCheckDeescalateAfter
+}
+
+// HealthStatus returns the action taken on failed healthchecks, and the latest healthcheck result of each subsystem.
+func (m *Manager) HealthStatus() (UnhealthyAction, map[string]error) {
+	m.healthMu.Lock()
+	defer m.healthMu.Unlock()
+	status := make(map[string]error, len(m.healthStatus))
+	for name, err := range m.healthStatus {
+		status[name] = err
+	}
+	return m.unhealthyAction, status
+}
+
+// configureWatchdog starts, stops, or reconfigures the hardware watchdog as needed.
+func (m *Manager) configureWatchdog(cfg agentConfig) {
+	m.watchdogMu.Lock()
+	defer m.watchdogMu.Unlock()
+
+	if m.watchdog != nil {
+		if cfg.hardwareWatchdog && cfg.watchdogDevice == m.watchdogCfg.watchdogDevice &&
+			cfg.watchdogPetInterval == m.watchdogCfg.watchdogPetInterval {
+			return
+		}
+		m.logger.Infof("stopping hardware watchdog on %s", m.watchdogCfg.watchdogDevice)
+		if err := m.watchdog.Close(); err != nil {
+			m.logger.Error(errw.Wrap(err, "closing hardware watchdog"))
+		}
+		m.watchdog = nil
+	}
+
+	if !cfg.hardwareWatchdog {
+		return
+	}
+
+	m.logger.Infof("starting hardware watchdog on %s, petting every %s", cfg.watchdogDevice, cfg.watchdogPetInterval)
+	watchdog := NewHardwareWatchdog(m.logger, &LinuxWatchdogPetter{Device: cfg.watchdogDevice}, cfg.watchdogPetInterval)
+	watchdog.SetMaxCheckAge(m.watchdogMaxCheckAge)
+	if err := watchdog.Start(context.Background()); err != nil {
+		m.logger.Error(errw.Wrap(err, "starting hardware watchdog"))
+		return
+	}
+	m.watchdog = watchdog
+	m.watchdogCfg = cfg
+}
+
+// setWatchdogMaxCheckAge stops the watchdog being petted if no round of health checks completes within twice
+// checkInterval, the longest the background checks wait between rounds, as the manager loop must have hung.
+func (m *Manager) setWatchdogMaxCheckAge(checkInterval time.Duration) {
+	m.watchdogMu.Lock()
+	defer m.watchdogMu.Unlock()
+	m.watchdogMaxCheckAge = checkInterval * 2
+	if m.watchdog != nil {
+		m.watchdog.SetMaxCheckAge(m.watchdogMaxCheckAge)
+	}
+	if m.sdWatchdog != nil {
+		m.sdWatchdog.SetMaxCheckAge(m.watchdogMaxCheckAge)
+	}
+}
+
+// configureSystemdWatchdog starts or stops pinging systemd's watchdog as needed. It's a no-op unless the agent's
+// unit sets WatchdogSec.
+func (m *Manager) configureSystemdWatchdog(cfg agentConfig) {
+	m.watchdogMu.Lock()
+	defer m.watchdogMu.Unlock()
+	m.sdNotifyReady = cfg.systemdNotifyReady
+
+	if !cfg.systemdWatchdog {
+		if m.sdWatchdog != nil {
+			m.logger.Info("stopping systemd watchdog notifications")
+			if err := m.sdWatchdog.Close(); err != nil {
+				m.logger.Error(errw.Wrap(err, "closing systemd watchdog"))
 			}
+			m.sdWatchdog = nil
 		}
-		if err := sub.Start(ctx); err != nil && !errors.Is(err, ErrSubsystemDisabled) {
-			m.logger.Error(err)
+		return
+	}
+	if m.sdWatchdog != nil {
+		return
+	}
+	interval, ok := SystemdWatchdogInterval()
+	if !ok {
+		return
+	}
+	m.logger.Infof("starting systemd watchdog notifications every %s", interval)
+	watchdog := NewHardwareWatchdog(m.logger, SystemdWatchdogPetter{}, interval)
+	watchdog.SetMaxCheckAge(m.watchdogMaxCheckAge)
+	if err := watchdog.Start(context.Background()); err != nil {
+		m.logger.Error(errw.Wrap(err, "starting systemd watchdog"))
+		return
+	}
+	m.sdWatchdog = watchdog
+}
+
+// updateSystemdHealth gates systemd's watchdog on viam-server's latest healthcheck, and sends READY=1 the first time
+// it passes (if configured.) A subsystem that isn't loaded doesn't block either.
+func (m *Manager) updateSystemdHealth() {
+	m.healthMu.Lock()
+	err, checked := m.healthStatus[systemdWatchdogSubsystem]
+	m.healthMu.Unlock()
+	healthy := err == nil
+
+	m.watchdogMu.Lock()
+	defer m.watchdogMu.Unlock()
+	if m.sdWatchdog != nil {
+		m.sdWatchdog.SetHealthy(healthy)
+	}
+	if m.sdNotifyReady && !m.sdReadySent && healthy && checked {
+		sent, err := SdNotify("READY=1")
+		if err != nil {
+			m.logger.Error(errw.Wrap(err, "notifying systemd of readiness"))
+			return
+		}
+		m.sdReadySent = true
+		if sent {
+			m.logger.Infof("%s is healthy, notified systemd of readiness", systemdWatchdogSubsystem)
 		}
 	}
 }
 
+// configureReaper starts or stops reaping orphaned processes as needed.
+func (m *Manager) configureReaper(cfg agentConfig) {
+	m.reaperMu.Lock()
+	defer m.reaperMu.Unlock()
+
+	if !cfg.reapOrphans {
+		if m.reaper != nil {
+			m.logger.Info("stopping orphaned process reaper")
+			m.reaper.Close()
+			m.reaper = nil
+		}
+		return
+	}
+	if m.reaper != nil {
+		return
+	}
+	if !CanReapOrphans() {
+		if m.reaperWarned {
+			return
+		}
+		m.reaperWarned = true
+		m.logger.Warn("reap_orphans is set, but orphans aren't reparented to the agent (it's not PID 1 or a subreaper), ignoring")
+		return
+	}
+	m.logger.Info("starting orphaned process reaper")
+	m.reaper = NewOrphanReaper(m.logger)
+	m.reaper.Start(context.Background())
+}
+
 // CheckUpdates retrieves an updated config from the cloud, and then passes it to SubsystemUpdates().
 func (m *Manager) CheckUpdates(ctx context.Context) time.Duration {
 	m.logger.Debug("Checking cloud for update")
@@ -195,24 +454,51 @@ func (m *Manager) CheckUpdates(ctx context.Context) time.Duration {
 	return interval
 }
 
-// SubsystemHealthChecks makes sure all subsystems are responding, and restarts them if not.
+// SubsystemHealthChecks makes sure all subsystems are responding, and restarts them if not. The checks run
+// health_check_concurrency at a time, via AggregateHealth.
 func (m *Manager) SubsystemHealthChecks(ctx context.Context) {
 	if ctx.Err() != nil {
 		return
 	}
 	m.logger.Debug("Starting health checks for all subsystems")
+	report := m.AggregateHealth(ctx)
+	if ctx.Err() != nil {
+		return
+	}
 	m.subsystemsMu.Lock()
 	defer m.subsystemsMu.Unlock()
-	for subsystemName, sub := range m.loadedSubsystems {
-		if ctx.Err() != nil {
-			return
+	m.healthMu.Lock()
+	action := m.unhealthyAction
+	m.healthMu.Unlock()
+
+	// failures that are only alerted on, or held crash looping, don't count, as rebooting would defeat the point
+	allHealthy := true
+	for _, health := range report {
+		subsystemName, err := health.Name, health.Err
+		sub, ok := m.loadedSubsystems[subsystemName]
+		if !ok {
+			// unloaded while being checked
+			continue
 		}
-		ctxTimeout, cancelFunc := context.WithTimeout(ctx, time.Second*15)
-		defer cancelFunc()
-		if err := sub.HealthCheck(ctxTimeout); err != nil {
-			if ctx.Err() != nil {
-				return
+		m.healthMu.Lock()
+		m.healthStatus[subsystemName] = err
+		m.healthMu.Unlock()
+		if err != nil {
+			switch action {
+			case UnhealthyActionNone:
+				m.logger.Debug(errw.Wrapf(err, "subsystem healthcheck failed for %s", subsystemName))
+				continue
+			case UnhealthyActionAlert:
+				m.logger.Error(errw.Wrapf(err, "subsystem healthcheck failed for %s, not restarting (unhealthy_action: alert)",
+					subsystemName))
+				continue
+			case UnhealthyActionRestart:
+			}
+			if _, ok := m.crashLooping[subsystemName]; ok {
+				m.logger.Debug(errw.Wrapf(err, "subsystem healthcheck failed for %s, crash looping, not restarting", subsystemName))
+				continue
 			}
+			allHealthy = false
 			m.logger.Error(errw.Wrapf(err, "subsystem healthcheck failed for %s", subsystemName))
 			if err := sub.Stop(ctx); err != nil {
 				m.logger.Error(errw.Wrapf(err, "stopping subsystem %s", subsystemName))
@@ -222,9 +508,26 @@ func (m *Manager) SubsystemHealthChecks(ctx context.Context) {
 			}
 			if err := sub.Start(ctx); err != nil && !errors.Is(err, ErrSubsystemDisabled) {
 				m.logger.Error(errw.Wrapf(err, "restarting subsystem %s", subsystemName))
+				if errors.Is(err, subsystems.ErrCrashLooping) {
+					// held until the config changes from the one it was last updated with
+					cachedConfig, err := m.getCachedConfig()
+					if err != nil {
+						m.logger.Error(errw.Wrap(err, "getting cached config"))
+					}
+					m.holdCrashLooping(subsystemName, cachedConfig[subsystemName])
+				}
 			}
 		}
 	}
+
+	m.updateSystemdHealth()
+
+	// the hardware watchdog is only petted while no subsystem needed restarting at its last check
+	m.watchdogMu.Lock()
+	defer m.watchdogMu.Unlock()
+	if m.watchdog != nil {
+		m.watchdog.SetHealthy(allHealthy)
+	}
 }
 
 // CloseAll stops all subsystems and closes the cloud connection.
@@ -234,14 +537,37 @@ func (m *Manager) CloseAll() {
 
 	m.subsystemsMu.Lock()
 	defer m.subsystemsMu.Unlock()
-	// close all subsystems
-	for _, sub := range m.loadedSubsystems {
-		if err := sub.Stop(ctx); err != nil {
+	// close all subsystems, in the reverse of their start order
+	order := m.bootOrder()
+	for i := len(order) - 1; i >= 0; i-- {
+		if err := m.loadedSubsystems[order[i]].Stop(ctx); err != nil {
 			m.logger.Error(err)
 		}
 	}
 	m.activeBackgroundWorkers.Wait()
 
+	m.watchdogMu.Lock()
+	if m.watchdog != nil {
+		if err := m.watchdog.Close(); err != nil {
+			m.logger.Error(errw.Wrap(err, "closing hardware watchdog"))
+		}
+		m.watchdog = nil
+	}
+	if m.sdWatchdog != nil {
+		if err := m.sdWatchdog.Close(); err != nil {
+			m.logger.Error(errw.Wrap(err, "closing systemd watchdog"))
+		}
+		m.sdWatchdog = nil
+	}
+	m.watchdogMu.Unlock()
+
+	m.reaperMu.Lock()
+	if m.reaper != nil {
+		m.reaper.Close()
+		m.reaper = nil
+	}
+	m.reaperMu.Unlock()
+
 	m.connMu.Lock()
 	defer m.connMu.Unlock()
 
@@ -270,6 +596,8 @@ func (m *Manager) StartBackgroundChecks(ctx context.Context) {
 	m.activeBackgroundWorkers.Add(1)
 	go func() {
 		checkInterval := m.CheckUpdates(ctx)
+		m.setWatchdogMaxCheckAge(checkInterval)
+		lastUpdateCheck := time.Now()
 		timer := time.NewTimer(checkInterval)
 		defer timer.Stop()
 		defer m.activeBackgroundWorkers.Done()
@@ -281,9 +609,14 @@ func (m *Manager) StartBackgroundChecks(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case <-timer.C:
-				checkInterval = m.CheckUpdates(ctx)
+				// while health checks are escalated, they run more often than updates are checked for
+				if time.Since(lastUpdateCheck) >= checkInterval {
+					checkInterval = m.CheckUpdates(ctx)
+					m.setWatchdogMaxCheckAge(checkInterval)
+					lastUpdateCheck = time.Now()
+				}
 				m.SubsystemHealthChecks(ctx)
-				timer.Reset(checkInterval)
+				timer.Reset(min(m.nextHealthCheck(checkInterval), checkInterval-time.Since(lastUpdateCheck)))
 			}
 		}
 	}()
@@ -325,6 +658,7 @@ func (m *Manager) loadSubsystem(ctx context.Context, name string, subCfg *pb.Dev
 			return err
 		}
 		m.loadedSubsystems[name] = sub
+		m.setRequired(name, subCfg)
 		return nil
 	}
 	return errw.Errorf("unknown subsystem name %s", name)
@@ -440,7 +774,7 @@ func (m *Manager) GetConfig(ctx context.Context) (map[string]*pb.DeviceSubsystem
 		return conf, minimalCheckInterval, err
 	}
 
-	m.logger.Debugf("Cloud-provided config: %+v", resp)
+	m.logger.Debugf("Cloud-provided config: %+v", config.SanitizeMessage(resp))
 
 	err = m.saveCachedConfig(resp.GetSubsystemConfigs())
 	if err != nil {
@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent/subsystems"
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type fakeSubsystem struct {
+	healthErr     error
+	readyErr      error
+	startErr      error
+	starts, stops int
+	cleared       int
+	banner        string
+	exitSignal    syscall.Signal
+	timeline      []subsystems.StateEvent
+	startKind     subsystems.StartKind
+	checkURLs     []string
+	restart       subsystems.RestartWrapper
+	updates       []*pb.DeviceSubsystemConfig
+	// optional, shared between subsystems to record the order of starts and stops
+	name string
+	log  *[]string
+}
+
+func (f *fakeSubsystem) Start(ctx context.Context) error {
+	f.starts++
+	if f.log != nil {
+		*f.log = append(*f.log, "start "+f.name)
+	}
+	return f.startErr
+}
+
+func (f *fakeSubsystem) Stop(ctx context.Context) error {
+	f.stops++
+	if f.log != nil {
+		*f.log = append(*f.log, "stop "+f.name)
+	}
+	return nil
+}
+
+func (f *fakeSubsystem) Update(ctx context.Context, cfg *pb.DeviceSubsystemConfig) (bool, error) {
+	f.updates = append(f.updates, cfg)
+	return false, nil
+}
+
+func (f *fakeSubsystem) HealthCheck(ctx context.Context) error { return f.healthErr }
+
+func (f *fakeSubsystem) Readiness(ctx context.Context) error { return f.readyErr }
+
+func (f *fakeSubsystem) Version() string { return "" }
+
+func (f *fakeSubsystem) StartupBanner() string { return f.banner }
+
+func (f *fakeSubsystem) Timeline() []subsystems.StateEvent { return f.timeline }
+
+func (f *fakeSubsystem) LastStartKind() subsystems.StartKind { return f.startKind }
+
+func (f *fakeSubsystem) CheckURLs() []string { return f.checkURLs }
+
+func (f *fakeSubsystem) LastExitSignal() (syscall.Signal, bool) {
+	return f.exitSignal, f.exitSignal != 0
+}
+
+func (f *fakeSubsystem) SetRestartWrapper(wrap subsystems.RestartWrapper) { f.restart = wrap }
+
+func (f *fakeSubsystem) ClearFailureState() {
+	f.cleared++
+	f.startErr = nil
+}
+
+func TestCrashLoopHold(t *testing.T) {
+	ctx := context.Background()
+	sub := &fakeSubsystem{startErr: errors.Join(errors.New("viam-server"), subsystems.ErrCrashLooping)}
+	m := &Manager{
+		logger:           logging.NewTestLogger(t),
+		loadedSubsystems: map[string]subsystems.Subsystem{"fake": sub},
+		healthStatus:     map[string]error{},
+		watchdog:         NewHardwareWatchdog(logging.NewTestLogger(t), NoopWatchdogPetter{}, time.Hour),
+	}
+	attrs, err := structpb.NewStruct(map[string]any{"a": 1})
+	test.That(t, err, test.ShouldBeNil)
+	cfg := &pb.DeviceSubsystemConfig{Attributes: attrs}
+
+	m.updateSubsystem(ctx, "fake", sub, cfg)
+	test.That(t, sub.starts, test.ShouldEqual, 1)
+
+	// not started again, by updates or health checks, while the config is the same
+	m.updateSubsystem(ctx, "fake", sub, &pb.DeviceSubsystemConfig{Attributes: attrs})
+	sub.healthErr = errors.New("not running")
+	m.SubsystemHealthChecks(ctx)
+	test.That(t, sub.starts, test.ShouldEqual, 1)
+	test.That(t, sub.stops, test.ShouldEqual, 0)
+	// and the watchdog isn't starved over it, as a reboot won't help either
+	test.That(t, m.watchdog.healthy.Load(), test.ShouldBeTrue)
+
+	// a changed config clears it and tries again
+	attrs.Fields["a"] = structpb.NewNumberValue(2)
+	m.updateSubsystem(ctx, "fake", sub, cfg)
+	test.That(t, sub.cleared, test.ShouldEqual, 1)
+	test.That(t, sub.starts, test.ShouldEqual, 2)
+	test.That(t, m.crashLooping, test.ShouldNotContainKey, "fake")
+}
+
+func TestUnhealthyAction(t *testing.T) {
+	ctx := context.Background()
+	errUnhealthy := errors.New("unhealthy")
+
+	for _, tc := range []struct {
+		action   UnhealthyAction
+		restarts int
+		// alerting shouldn't reboot the host via the watchdog either
+		watchdogHealthy bool
+	}{
+		{UnhealthyActionRestart, 1, false},
+		{UnhealthyActionAlert, 0, true},
+		{UnhealthyActionNone, 0, true},
+	} {
+		t.Run(string(tc.action), func(t *testing.T) {
+			sub := &fakeSubsystem{healthErr: errUnhealthy}
+			m := &Manager{
+				logger:           logging.NewTestLogger(t),
+				loadedSubsystems: map[string]subsystems.Subsystem{"fake": sub},
+				unhealthyAction:  UnhealthyActionRestart,
+				healthStatus:     make(map[string]error),
+			}
+			attrs, err := structpb.NewStruct(map[string]any{"unhealthy_action": string(tc.action)})
+			test.That(t, err, test.ShouldBeNil)
+			m.applyAgentConfig(&pb.DeviceSubsystemConfig{Attributes: attrs})
+			// not started, so it's only recording health
+			m.watchdog = NewHardwareWatchdog(m.logger, NoopWatchdogPetter{}, time.Hour)
+
+			m.SubsystemHealthChecks(ctx)
+			test.That(t, sub.stops, test.ShouldEqual, tc.restarts)
+			test.That(t, sub.starts, test.ShouldEqual, tc.restarts)
+			test.That(t, m.watchdog.healthy.Load(), test.ShouldEqual, tc.watchdogHealthy)
+
+			action, status := m.HealthStatus()
+			test.That(t, action, test.ShouldEqual, tc.action)
+			test.That(t, status["fake"], test.ShouldEqual, errUnhealthy)
+
+			sub.healthErr = nil
+			m.SubsystemHealthChecks(ctx)
+			_, status = m.HealthStatus()
+			test.That(t, status["fake"], test.ShouldBeNil)
+		})
+	}
+}
+
+func TestBootPriority(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	m := &Manager{
+		logger:           logging.NewTestLogger(t),
+		loadedSubsystems: make(map[string]subsystems.Subsystem),
+		unhealthyAction:  UnhealthyActionRestart,
+		healthStatus:     make(map[string]error),
+	}
+	for _, name := range []string{"exporter", "viam-server", "zeta", "alpha"} {
+		m.loadedSubsystems[name] = &fakeSubsystem{name: name, log: &log}
+	}
+	priority := func(p float64) *pb.DeviceSubsystemConfig {
+		attrs, err := structpb.NewStruct(map[string]any{"boot_priority": p})
+		test.That(t, err, test.ShouldBeNil)
+		return &pb.DeviceSubsystemConfig{Attributes: attrs}
+	}
+	bad, err := structpb.NewStruct(map[string]any{"boot_priority": "first"})
+	test.That(t, err, test.ShouldBeNil)
+
+	m.SubsystemUpdates(ctx, map[string]*pb.DeviceSubsystemConfig{
+		"viam-server": priority(10),
+		"exporter":    priority(200),
+		// invalid is the default
+		"zeta": {Attributes: bad},
+		// as is unset, so equal priorities go by name
+		"alpha": {},
+	})
+	test.That(t, log, test.ShouldResemble, []string{"start viam-server", "start alpha", "start zeta", "start exporter"})
+
+	log = nil
+	m.CloseAll()
+	test.That(t, log, test.ShouldResemble, []string{"stop exporter", "stop zeta", "stop alpha", "stop viam-server"})
+}
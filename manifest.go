@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	errw "github.com/pkg/errors"
+)
+
+// ErrManifestMismatch is returned by VerifyManifest when an installed file isn't what the manifest recorded.
+var ErrManifestMismatch = errw.New("installed file doesn't match manifest")
+
+// ManifestEntry is what an installed file is expected to be.
+type ManifestEntry struct {
+	// the installed file, which may be reached through a symlink
+	Path   string
+	SHA256 []byte
+	// zero if not recorded
+	Size int64
+}
+
+// Manifest looks up the expected state of installed files, so they can be verified before being used.
+type Manifest interface {
+	// Lookup returns the entry for path (the installed file, or a symlink to it), and false if path isn't tracked.
+	Lookup(path string) (ManifestEntry, bool)
+}
+
+// Lookup implements Manifest from the install cache, for the current version. It doesn't lock, so like the start and
+// stop hooks it must only be called while the subsystem is locked, such as from the wrapped subsystem's Start.
+func (s *AgentSubsystem) Lookup(path string) (ManifestEntry, bool) {
+	if s.CacheData == nil {
+		return ManifestEntry{}, false
+	}
+	ver, ok := s.CacheData.Versions[s.CacheData.CurrentVersion]
+	if !ok || ver.UnpackedPath == "" || (path != ver.SymlinkPath && path != ver.UnpackedPath) {
+		return ManifestEntry{}, false
+	}
+	return ManifestEntry{Path: ver.UnpackedPath, SHA256: ver.UnpackedSHA}, true
+}
+
+// VerifyManifest checks that path resolves to the file its manifest entry records, with the recorded size and hash.
+// Untracked files are reported as not verified (false), rather than as an error.
+func VerifyManifest(manifest Manifest, path string) (bool, error) {
+	entry, ok := manifest.Lookup(path)
+	if !ok {
+		return false, nil
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false, errw.Wrapf(err, "resolving %s", path)
+	}
+	expected, err := filepath.EvalSymlinks(entry.Path)
+	if err != nil {
+		return false, errw.Wrapf(ErrManifestMismatch, "installed file %s for %s is missing: %v", entry.Path, path, err)
+	}
+	if resolved != expected {
+		return false, errw.Wrapf(ErrManifestMismatch, "%s points to %s instead of %s", path, resolved, expected)
+	}
+	if entry.Size > 0 {
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return false, err
+		}
+		if info.Size() != entry.Size {
+			return false, errw.Wrapf(ErrManifestMismatch, "%s is %d bytes instead of %d", resolved, info.Size(), entry.Size)
+		}
+	}
+	sum, err := GetFileSum(resolved)
+	if err != nil {
+		return false, errw.Wrapf(err, "hashing %s", resolved)
+	}
+	if !bytes.Equal(sum, entry.SHA256) {
+		return false, errw.Wrapf(ErrManifestMismatch, "sha256 of %s is %s instead of %s", resolved,
+			hex.EncodeToString(sum), hex.EncodeToString(entry.SHA256))
+	}
+	return true, nil
+}
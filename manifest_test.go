@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestVerifyManifest(t *testing.T) {
+	dir := t.TempDir()
+	installed := filepath.Join(dir, "viam-server-v1")
+	test.That(t, os.WriteFile(installed, []byte("binary"), 0o600), test.ShouldBeNil)
+	link := filepath.Join(dir, "viam-server")
+	test.That(t, os.Symlink(installed, link), test.ShouldBeNil)
+	sum, err := GetFileSum(installed)
+	test.That(t, err, test.ShouldBeNil)
+
+	sub := &AgentSubsystem{CacheData: &CacheData{
+		CurrentVersion: "v1",
+		Versions: map[string]*VersionInfo{
+			"v1": {Version: "v1", UnpackedPath: installed, UnpackedSHA: sum, SymlinkPath: link},
+		},
+	}}
+	verified, err := VerifyManifest(sub, link)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, verified, test.ShouldBeTrue)
+
+	// untracked isn't an error
+	verified, err = VerifyManifest(sub, filepath.Join(dir, "other"))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, verified, test.ShouldBeFalse)
+
+	// modified
+	test.That(t, os.WriteFile(installed, []byte("tampered"), 0o600), test.ShouldBeNil)
+	_, err = VerifyManifest(sub, link)
+	test.That(t, errors.Is(err, ErrManifestMismatch), test.ShouldBeTrue)
+
+	// pointed elsewhere, even at an identical copy
+	test.That(t, os.WriteFile(installed, []byte("binary"), 0o600), test.ShouldBeNil)
+	other := filepath.Join(dir, "copy")
+	test.That(t, os.WriteFile(other, []byte("binary"), 0o600), test.ShouldBeNil)
+	test.That(t, os.Remove(link), test.ShouldBeNil)
+	test.That(t, os.Symlink(other, link), test.ShouldBeNil)
+	_, err = VerifyManifest(sub, link)
+	test.That(t, errors.Is(err, ErrManifestMismatch), test.ShouldBeTrue)
+
+	// wrong size
+	_, err = VerifyManifest(fakeManifest{ManifestEntry{Path: other, SHA256: sum, Size: 100}}, other)
+	test.That(t, errors.Is(err, ErrManifestMismatch), test.ShouldBeTrue)
+}
+
+type fakeManifest struct {
+	entry ManifestEntry
+}
+
+func (f fakeManifest) Lookup(path string) (ManifestEntry, bool) {
+	return f.entry, path == f.entry.Path
+}
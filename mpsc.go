@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// WithHighThroughputMode makes Write hand data off to a lock-free ring buffer of (at least) slots entries, which a
+// single background goroutine drains to do the matching and logging. Writers never contend on a mutex, and only spin
+// (yielding with runtime.Gosched) while the ring is full. Zero or less leaves it disabled.
+func WithHighThroughputMode(slots int) MatchingLoggerOption {
+	return func(l *MatchingLogger) {
+		if slots <= 0 {
+			return
+		}
+		l.ring = newMPSCRing(slots)
+	}
+}
+
+// mpscRing is a bounded multi-producer single-consumer queue. Producers claim a position by CompareAndSwap on head,
+// then publish it through the slot's sequence number, so the consumer only sees complete writes, in order.
+type mpscRing struct {
+	slots []mpscSlot
+	mask  uint64
+	head  atomic.Uint64
+	// only touched by the active consumer, but atomic as that role moves between goroutines
+	tail atomic.Uint64
+	// entries fully processed by the consumer, for Flush
+	consumed atomic.Uint64
+	// whether a consumer goroutine is running
+	active atomic.Bool
+}
+
+type mpscSlot struct {
+	// equal to the position when free to write, position+1 once written
+	seq  atomic.Uint64
+	data []byte
+}
+
+func newMPSCRing(size int) *mpscRing {
+	// a power of two, so positions wrap with a mask
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	r := &mpscRing{slots: make([]mpscSlot, n), mask: uint64(n - 1)}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+// push adds data, spinning while the ring is full.
+func (r *mpscRing) push(data []byte) {
+	for {
+		pos := r.head.Load()
+		slot := &r.slots[pos&r.mask]
+		seq := slot.seq.Load()
+		switch {
+		case seq == pos:
+			if r.head.CompareAndSwap(pos, pos+1) {
+				slot.data = data
+				slot.seq.Store(pos + 1)
+				return
+			}
+		case seq < pos:
+			// full, the consumer hasn't freed this slot since the last lap
+			runtime.Gosched()
+		default:
+			// another producer claimed pos first, retry with the new head
+		}
+	}
+}
+
+// pop removes the oldest entry, if one has been published. Only the active consumer may call it.
+func (r *mpscRing) pop() ([]byte, bool) {
+	pos := r.tail.Load()
+	slot := &r.slots[pos&r.mask]
+	if slot.seq.Load() != pos+1 {
+		return nil, false
+	}
+	data := slot.data
+	slot.data = nil
+	slot.seq.Store(pos + uint64(len(r.slots)))
+	r.tail.Store(pos + 1)
+	return data, true
+}
+
+// pending returns true if there's a published entry waiting for the consumer.
+func (r *mpscRing) pending() bool {
+	pos := r.tail.Load()
+	return r.slots[pos&r.mask].seq.Load() == pos+1
+}
+
+// enqueue copies p into the ring, and makes sure a consumer is running to process it.
+func (l *MatchingLogger) enqueue(p []byte) {
+	l.ring.push(append([]byte(nil), p...))
+	if l.ring.active.CompareAndSwap(false, true) {
+		go l.consume()
+	}
+}
+
+// consume processes entries until the ring is empty. It exits rather than idling, so no goroutine outlives the
+// logger, and the next enqueue starts another.
+func (l *MatchingLogger) consume() {
+	for {
+		for data, ok := l.ring.pop(); ok; data, ok = l.ring.pop() {
+			l.process(data)
+			l.ring.consumed.Add(1)
+		}
+		l.ring.active.Store(false)
+		// a producer may have published after the last pop, but seen this consumer as still active
+		if !l.ring.pending() || !l.ring.active.CompareAndSwap(false, true) {
+			return
+		}
+	}
+}
+
+// drain waits until everything enqueued before the call has been processed.
+func (l *MatchingLogger) drain() {
+	target := l.ring.head.Load()
+	for l.ring.consumed.Load() < target {
+		runtime.Gosched()
+	}
+}
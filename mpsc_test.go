@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestHighThroughputMode(t *testing.T) {
+	const producers, lines = 8, 500
+	var mu sync.Mutex
+	var received []string
+	// a tiny ring, so producers have to wait for the consumer
+	ml := NewMatchingLogger(logging.NewBlankLogger("test"), false, false,
+		WithHighThroughputMode(4),
+		WithLineBuffering(1024),
+		WithLineSink(func(level zapcore.Level, line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, line)
+		}),
+	)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				_, err := fmt.Fprintf(ml, "producer %d line %d\n", p, i)
+				test.That(t, err, test.ShouldBeNil)
+			}
+		}(p)
+	}
+	wg.Wait()
+	// everything written before Flush has been processed once it returns
+	ml.Write([]byte("partial"))
+	ml.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	test.That(t, received, test.ShouldHaveLength, producers*lines+1)
+	test.That(t, received[len(received)-1], test.ShouldEqual, "partial")
+	// each producer's lines arrive in the order written
+	next := make([]int, producers)
+	lineRegex := regexp.MustCompile(`^producer (\d+) line (\d+)$`)
+	for _, line := range received[:len(received)-1] {
+		matches := lineRegex.FindStringSubmatch(line)
+		test.That(t, matches, test.ShouldNotBeNil)
+		p, _ := strconv.Atoi(matches[1]) //nolint:errcheck
+		i, _ := strconv.Atoi(matches[2]) //nolint:errcheck
+		test.That(t, i, test.ShouldEqual, next[p])
+		next[p]++
+	}
+}
+
+func TestHighThroughputModeMatchers(t *testing.T) {
+	ml := NewMatchingLogger(logging.NewTestLogger(t), false, false, WithHighThroughputMode(16))
+	c, err := ml.AddMatcher("match", regexp.MustCompile(`match (\d+)`), true)
+	test.That(t, err, test.ShouldBeNil)
+	defer ml.DeleteMatcher("match")
+	ml.Inject("match 1")
+	ml.Inject("match 2")
+	test.That(t, (<-c)[1], test.ShouldEqual, "1")
+	test.That(t, (<-c)[1], test.ShouldEqual, "2")
+}
+
+// run with -cpu 8 to compare under GOMAXPROCS=8.
+func BenchmarkMatchingLogger(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		opts []MatchingLoggerOption
+	}{
+		{"mutex", nil},
+		{"mpsc", []MatchingLoggerOption{WithHighThroughputMode(4096)}},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			opts := append([]MatchingLoggerOption{WithLineBuffering(1024)}, tc.opts...)
+			ml := NewMatchingLogger(logging.NewBlankLogger("bench"), false, false, opts...)
+			// masks every line, so this measures the handoff rather than log output
+			c, err := ml.AddMatcher("all", regexp.MustCompile(`line`), true)
+			if err != nil {
+				b.Fatal(err)
+			}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				//nolint:revive
+				for range c {
+				}
+			}()
+			line := []byte("2024-01-01T00:00:00.000Z\tINFO\tbench\tbench.go:1\tsome log line\n")
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					//nolint:errcheck
+					ml.Write(line)
+				}
+			})
+			ml.Flush()
+			b.StopTimer()
+			ml.DeleteMatcher("all")
+			<-done
+		})
+	}
+}
@@ -0,0 +1,292 @@
+// Package otellogs forwards log records to an OpenTelemetry collector, using OTLP/HTTP with JSON encoding.
+// It is kept separate (and free of OTel SDK dependencies) so the core agent doesn't pull them in.
+package otellogs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultBatchSize     = 256
+	defaultFlushInterval = time.Second * 5
+	// records held while the collector is unavailable, beyond which the oldest are dropped.
+	defaultMaxBuffered = 10000
+	exportTimeout      = time.Second * 10
+)
+
+// Record is a single log record.
+type Record struct {
+	Time  time.Time
+	Level zapcore.Level
+	Body  string
+}
+
+// Exporter batches records and sends them to a collector in the background.
+type Exporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+	batchSize   int
+	interval    time.Duration
+	maxBuffered int
+
+	mu          sync.Mutex
+	pending     []Record
+	dropped     int
+	rateLimited int
+	closed      bool
+	limiter     RateLimiter
+
+	flush   chan struct{}
+	done    chan struct{}
+	workers sync.WaitGroup
+	onError func(error)
+}
+
+// Option configures optional Exporter behavior.
+type Option func(*Exporter)
+
+// WithBatching sets how many records are sent per request, and how often pending records are sent regardless.
+func WithBatching(size int, interval time.Duration) Option {
+	return func(e *Exporter) {
+		if size > 0 {
+			e.batchSize = size
+		}
+		if interval > 0 {
+			e.interval = interval
+		}
+	}
+}
+
+// WithMaxBuffered bounds how many records are held while the collector is unavailable.
+func WithMaxBuffered(n int) Option {
+	return func(e *Exporter) {
+		if n > 0 {
+			e.maxBuffered = n
+		}
+	}
+}
+
+// RateLimiter decides whether a record of the given size may be sent.
+type RateLimiter interface {
+	Allow(bytes int64) bool
+}
+
+// WithRateLimit drops records below error level that limiter doesn't allow, such as to cap log volume on metered links.
+// Error level records are always kept.
+func WithRateLimit(limiter RateLimiter) Option {
+	return func(e *Exporter) { e.limiter = limiter }
+}
+
+// WithErrorHandler is called with export failures. Records are kept and retried.
+func WithErrorHandler(fn func(error)) Option {
+	return func(e *Exporter) { e.onError = fn }
+}
+
+// New starts an Exporter sending to endpoint (e.g. http://collector:4318), tagging records with serviceName.
+func New(endpoint, serviceName string, opts ...Option) *Exporter {
+	e := &Exporter{
+		url:         strings.TrimSuffix(endpoint, "/") + "/v1/logs",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		batchSize:   defaultBatchSize,
+		interval:    defaultFlushInterval,
+		maxBuffered: defaultMaxBuffered,
+		flush:       make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		onError:     func(error) {},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.workers.Add(1)
+	go e.run()
+	return e
+}
+
+// Emit queues a record without blocking. If too many records are pending, the oldest are dropped.
+func (e *Exporter) Emit(r Record) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	if e.limiter != nil && r.Level < zapcore.ErrorLevel && !e.limiter.Allow(int64(len(r.Body))) {
+		e.rateLimited++
+		return
+	}
+	e.pending = append(e.pending, r)
+	if over := len(e.pending) - e.maxBuffered; over > 0 {
+		e.pending = e.pending[over:]
+		e.dropped += over
+	}
+	if len(e.pending) >= e.batchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Dropped returns how many records have been dropped due to buffering limits.
+func (e *Exporter) Dropped() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dropped
+}
+
+// RateLimited returns how many records have been dropped by the WithRateLimit limiter.
+func (e *Exporter) RateLimited() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rateLimited
+}
+
+// Close stops the exporter after a final attempt to send pending records.
+func (e *Exporter) Close() {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.closed = true
+	e.mu.Unlock()
+	close(e.done)
+	e.workers.Wait()
+}
+
+func (e *Exporter) run() {
+	defer e.workers.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			e.sendPending()
+			return
+		case <-ticker.C:
+		case <-e.flush:
+		}
+		e.sendPending()
+	}
+}
+
+// sendPending sends batches until nothing is pending, or a send fails (leaving the rest for the next try).
+func (e *Exporter) sendPending() {
+	for {
+		e.mu.Lock()
+		n := min(len(e.pending), e.batchSize)
+		batch := append([]Record(nil), e.pending[:n]...)
+		droppedBefore := e.dropped
+		e.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			e.onError(err)
+			return
+		}
+		e.mu.Lock()
+		// records may have been dropped from the front while sending, and those were part of this batch
+		if remaining := n - (e.dropped - droppedBefore); remaining > 0 {
+			e.pending = e.pending[remaining:]
+		}
+		e.mu.Unlock()
+	}
+}
+
+func (e *Exporter) send(batch []Record) error {
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		return errw.Wrap(err, "encoding log records")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errw.Wrap(err, "exporting logs")
+	}
+	//nolint:errcheck
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errw.Errorf("exporting logs, got code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// the subset of the OTLP JSON log schema that is used.
+type (
+	anyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	logRecord struct {
+		TimeUnixNano   string   `json:"timeUnixNano"`
+		SeverityNumber int      `json:"severityNumber"`
+		SeverityText   string   `json:"severityText"`
+		Body           anyValue `json:"body"`
+	}
+	scopeLogs struct {
+		Scope      map[string]string `json:"scope"`
+		LogRecords []logRecord       `json:"logRecords"`
+	}
+	resourceLogs struct {
+		Resource  map[string][]keyValue `json:"resource"`
+		ScopeLogs []scopeLogs           `json:"scopeLogs"`
+	}
+	exportRequest struct {
+		ResourceLogs []resourceLogs `json:"resourceLogs"`
+	}
+)
+
+func (e *Exporter) encode(batch []Record) exportRequest {
+	records := make([]logRecord, 0, len(batch))
+	for _, r := range batch {
+		records = append(records, logRecord{
+			TimeUnixNano:   strconv.FormatInt(r.Time.UnixNano(), 10),
+			SeverityNumber: severityNumber(r.Level),
+			SeverityText:   r.Level.CapitalString(),
+			Body:           anyValue{StringValue: r.Body},
+		})
+	}
+	return exportRequest{ResourceLogs: []resourceLogs{{
+		Resource: map[string][]keyValue{
+			"attributes": {{Key: "service.name", Value: anyValue{StringValue: e.serviceName}}},
+		},
+		ScopeLogs: []scopeLogs{{Scope: map[string]string{"name": "viam-agent"}, LogRecords: records}},
+	}}}
+}
+
+// severityNumber maps zap levels onto the OTel log data model's severity ranges.
+func severityNumber(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return 5
+	case level == zapcore.InfoLevel:
+		return 9
+	case level == zapcore.WarnLevel:
+		return 13
+	case level == zapcore.ErrorLevel:
+		return 17
+	default:
+		return 21
+	}
+}
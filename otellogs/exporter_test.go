@@ -0,0 +1,103 @@
+package otellogs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"go.viam.com/test"
+)
+
+func TestExporter(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	var available atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		test.That(t, r.URL.Path, test.ShouldEqual, "/v1/logs")
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req exportRequest
+		test.That(t, json.NewDecoder(r.Body).Decode(&req), test.ShouldBeNil)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rec := range req.ResourceLogs[0].ScopeLogs[0].LogRecords {
+			bodies = append(bodies, rec.Body.StringValue)
+		}
+	}))
+	defer srv.Close()
+
+	var failures atomic.Int32
+	exp := New(srv.URL, "viam-server",
+		WithBatching(2, time.Millisecond*20),
+		WithMaxBuffered(3),
+		WithErrorHandler(func(error) { failures.Add(1) }),
+	)
+
+	// while the collector is down, records are kept up to the buffer limit
+	for _, body := range []string{"one", "two", "three", "four"} {
+		exp.Emit(Record{Time: time.Now(), Level: zapcore.InfoLevel, Body: body})
+	}
+	time.Sleep(time.Millisecond * 100)
+	test.That(t, failures.Load(), test.ShouldBeGreaterThan, 0)
+	test.That(t, exp.Dropped(), test.ShouldEqual, 1)
+
+	available.Store(true)
+	exp.Emit(Record{Time: time.Now(), Level: zapcore.ErrorLevel, Body: "five"})
+	exp.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// depending on timing, "two" may have been sent just before it would have been dropped
+	if len(bodies) == 4 {
+		test.That(t, bodies[0], test.ShouldEqual, "two")
+		bodies = bodies[1:]
+	}
+	test.That(t, bodies, test.ShouldResemble, []string{"three", "four", "five"})
+}
+
+type fixedLimiter struct {
+	remaining int64
+}
+
+func (l *fixedLimiter) Allow(bytes int64) bool {
+	if bytes > l.remaining {
+		return false
+	}
+	l.remaining -= bytes
+	return true
+}
+
+func TestExporterRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req exportRequest
+		test.That(t, json.NewDecoder(r.Body).Decode(&req), test.ShouldBeNil)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rec := range req.ResourceLogs[0].ScopeLogs[0].LogRecords {
+			bodies = append(bodies, rec.Body.StringValue)
+		}
+	}))
+	defer srv.Close()
+
+	exp := New(srv.URL, "viam-server", WithRateLimit(&fixedLimiter{remaining: 8}))
+	exp.Emit(Record{Time: time.Now(), Level: zapcore.InfoLevel, Body: "12345"})
+	exp.Emit(Record{Time: time.Now(), Level: zapcore.InfoLevel, Body: "12345"})
+	exp.Emit(Record{Time: time.Now(), Level: zapcore.DebugLevel, Body: "123"})
+	// errors are kept regardless of the budget
+	exp.Emit(Record{Time: time.Now(), Level: zapcore.ErrorLevel, Body: "error"})
+	exp.Close()
+
+	test.That(t, exp.RateLimited(), test.ShouldEqual, 1)
+	mu.Lock()
+	defer mu.Unlock()
+	test.That(t, bodies, test.ShouldResemble, []string{"12345", "123", "error"})
+}
@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errw "github.com/pkg/errors"
+)
+
+// clock ticks per second, as used by /proc/<pid>/stat. This is fixed at 100 on all Linux platforms we support.
+const clockTicks = 100
+
+// ProcessStats holds kernel level statistics for a process, beyond plain CPU and memory usage.
+type ProcessStats struct {
+	VoluntaryContextSwitches   uint64
+	InvoluntaryContextSwitches uint64
+	MajorPageFaults            uint64
+	MinorPageFaults            uint64
+	BlockedIOWaitSeconds       float64
+	OpenFileDescriptors        int
+	Threads                    int
+	RSSBytes                   uint64
+}
+
+// ReadProcStatus parses /proc/<pid>/status into a map of its fields, with values trimmed.
+func ReadProcStatus(pid int) (map[string]string, error) {
+	//nolint:gosec
+	raw, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+	if err != nil {
+		return nil, err
+	}
+	status := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		key, val, ok := strings.Cut(scanner.Text(), ":")
+		if ok {
+			status[key] = strings.TrimSpace(val)
+		}
+	}
+	return status, scanner.Err()
+}
+
+// statusKB parses a status value like "1234 kB" into bytes.
+func statusKB(val string) uint64 {
+	num, _ := strconv.ParseUint(strings.TrimSuffix(val, " kB"), 10, 64) //nolint:errcheck
+	return num * 1024
+}
+
+// ReadRSSBytes returns the resident set size (VmRSS) of pid.
+func ReadRSSBytes(pid int) (uint64, error) {
+	status, err := ReadProcStatus(pid)
+	if err != nil {
+		return 0, errw.Wrapf(err, "reading status of pid %d", pid)
+	}
+	return statusKB(status["VmRSS"]), nil
+}
+
+// ReadProcessStats collects ProcessStats from /proc/<pid>/status, /proc/<pid>/stat, and /proc/<pid>/fdinfo.
+func ReadProcessStats(pid int) (ProcessStats, error) {
+	var stats ProcessStats
+	status, err := ReadProcStatus(pid)
+	if err != nil {
+		return stats, errw.Wrapf(err, "reading status of pid %d", pid)
+	}
+	stats.VoluntaryContextSwitches, _ = strconv.ParseUint(status["voluntary_ctxt_switches"], 10, 64)      //nolint:errcheck
+	stats.InvoluntaryContextSwitches, _ = strconv.ParseUint(status["nonvoluntary_ctxt_switches"], 10, 64) //nolint:errcheck
+	stats.Threads, _ = strconv.Atoi(status["Threads"])                                                    //nolint:errcheck
+	stats.RSSBytes = statusKB(status["VmRSS"])
+
+	procDir := filepath.Join("/proc", strconv.Itoa(pid))
+	//nolint:gosec
+	rawStat, err := os.ReadFile(filepath.Join(procDir, "stat"))
+	if err != nil {
+		return stats, errw.Wrapf(err, "reading stat of pid %d", pid)
+	}
+	// the command name may contain spaces, so fields are counted from after its closing paren
+	idx := bytes.LastIndexByte(rawStat, ')')
+	if idx < 0 {
+		return stats, errw.Errorf("malformed stat for pid %d", pid)
+	}
+	// fields[0] is field 3 (state) in proc(5)
+	fields := strings.Fields(string(rawStat[idx+1:]))
+	if len(fields) < 40 {
+		return stats, errw.Errorf("malformed stat for pid %d", pid)
+	}
+	stats.MinorPageFaults, _ = strconv.ParseUint(fields[7], 10, 64) //nolint:errcheck
+	stats.MajorPageFaults, _ = strconv.ParseUint(fields[9], 10, 64) //nolint:errcheck
+	blkioTicks, _ := strconv.ParseUint(fields[39], 10, 64)          //nolint:errcheck
+	stats.BlockedIOWaitSeconds = float64(blkioTicks) / clockTicks
+
+	fds, err := os.ReadDir(filepath.Join(procDir, "fdinfo"))
+	if err != nil {
+		return stats, errw.Wrapf(err, "listing fds of pid %d", pid)
+	}
+	stats.OpenFileDescriptors = len(fds)
+	return stats, nil
+}
+
+// StatsDelta is the change in cumulative ProcessStats over an interval. Gauges (fds, threads, RSS) are
+// reported as their latest values.
+type StatsDelta struct {
+	Interval time.Duration
+	ProcessStats
+}
+
+// DeltaSampler turns successive cumulative ProcessStats samples into per-interval deltas.
+type DeltaSampler struct {
+	mu       sync.Mutex
+	prev     ProcessStats
+	prevTime time.Time
+}
+
+// Sample records stats, and returns the change since the previous sample. The first sample reports false.
+func (d *DeltaSampler) Sample(stats ProcessStats) (StatsDelta, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	prev, prevTime := d.prev, d.prevTime
+	d.prev, d.prevTime = stats, now
+	if prevTime.IsZero() {
+		return StatsDelta{}, false
+	}
+
+	// counters reset if the process was restarted between samples
+	sub := func(cur, old uint64) uint64 {
+		if cur < old {
+			return cur
+		}
+		return cur - old
+	}
+	delta := StatsDelta{Interval: now.Sub(prevTime), ProcessStats: stats}
+	delta.VoluntaryContextSwitches = sub(stats.VoluntaryContextSwitches, prev.VoluntaryContextSwitches)
+	delta.InvoluntaryContextSwitches = sub(stats.InvoluntaryContextSwitches, prev.InvoluntaryContextSwitches)
+	delta.MajorPageFaults = sub(stats.MajorPageFaults, prev.MajorPageFaults)
+	delta.MinorPageFaults = sub(stats.MinorPageFaults, prev.MinorPageFaults)
+	delta.BlockedIOWaitSeconds = stats.BlockedIOWaitSeconds - prev.BlockedIOWaitSeconds
+	if delta.BlockedIOWaitSeconds < 0 {
+		delta.BlockedIOWaitSeconds = stats.BlockedIOWaitSeconds
+	}
+	return delta, true
+}
+
+// ProcessEntry is a process's identity and relationships, from /proc/<pid>/stat.
+type ProcessEntry struct {
+	Pid   int
+	PPid  int
+	Pgid  int
+	State string
+}
+
+// ListProcesses returns every process currently in /proc. Processes that exit while listing are skipped.
+func ListProcesses() ([]ProcessEntry, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var procs []ProcessEntry
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		//nolint:gosec
+		raw, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// the command name is in parens and may contain anything, so fields are counted from the last paren
+		idx := bytes.LastIndexByte(raw, ')')
+		if idx < 0 {
+			continue
+		}
+		fields := strings.Fields(string(raw[idx+1:]))
+		if len(fields) < 3 {
+			continue
+		}
+		ppid, err1 := strconv.Atoi(fields[1])
+		pgid, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		procs = append(procs, ProcessEntry{Pid: pid, PPid: ppid, Pgid: pgid, State: fields[0]})
+	}
+	return procs, nil
+}
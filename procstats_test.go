@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"os"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestReadProcessStats(t *testing.T) {
+	stats, err := ReadProcessStats(os.Getpid())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, stats.Threads, test.ShouldBeGreaterThan, 0)
+	test.That(t, stats.OpenFileDescriptors, test.ShouldBeGreaterThan, 0)
+	test.That(t, stats.RSSBytes, test.ShouldBeGreaterThan, 0)
+	test.That(t, stats.MinorPageFaults, test.ShouldBeGreaterThan, 0)
+
+	_, err = ReadProcessStats(-1)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	rss, err := ReadRSSBytes(os.Getpid())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, rss, test.ShouldBeGreaterThan, 0)
+}
+
+func TestDeltaSampler(t *testing.T) {
+	var sampler DeltaSampler
+	_, ok := sampler.Sample(ProcessStats{VoluntaryContextSwitches: 100, MinorPageFaults: 50, Threads: 4})
+	test.That(t, ok, test.ShouldBeFalse)
+
+	delta, ok := sampler.Sample(ProcessStats{VoluntaryContextSwitches: 130, MinorPageFaults: 55, Threads: 6})
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, delta.VoluntaryContextSwitches, test.ShouldEqual, 30)
+	test.That(t, delta.MinorPageFaults, test.ShouldEqual, 5)
+	test.That(t, delta.Threads, test.ShouldEqual, 6)
+	test.That(t, delta.Interval, test.ShouldBeGreaterThan, 0)
+
+	// a restarted process starts its counters over
+	delta, ok = sampler.Sample(ProcessStats{VoluntaryContextSwitches: 10})
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, delta.VoluntaryContextSwitches, test.ShouldEqual, 10)
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent/subsystems"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	// ProtocolVersions are the subsystem protocol versions offered during negotiation, most preferred first.
+	ProtocolVersions = []string{"v1"}
+	// ProtocolVersionRange is the range of negotiated protocol versions this agent can work with.
+	ProtocolVersionRange = CompatibleVersionRange{Min: "v1", Max: "v1"}
+
+	// ErrIncompatibleProtocol is returned by Start when a subsystem negotiates a protocol version outside ProtocolVersionRange.
+	ErrIncompatibleProtocol = errors.New("incompatible protocol version")
+)
+
+// CompatibleVersionRange is an inclusive range of semantic versions ("v1", "v1.2", etc.) Empty bounds are open.
+type CompatibleVersionRange struct {
+	Min string
+	Max string
+}
+
+// Contains returns true if version is valid and within the range.
+func (r CompatibleVersionRange) Contains(version string) bool {
+	if !semver.IsValid(version) {
+		return false
+	}
+	if r.Min != "" && semver.Compare(version, r.Min) < 0 {
+		return false
+	}
+	if r.Max != "" && semver.Compare(version, r.Max) > 0 {
+		return false
+	}
+	return true
+}
+
+// negotiateProtocol negotiates a protocol version with the inner subsystem, if it supports that, after it's started.
+// A failed negotiation is only logged, as older versions may not support it, but an incompatible result is an error.
+// Must be called with mu held.
+func (s *AgentSubsystem) negotiateProtocol(ctx context.Context, info *VersionInfo) error {
+	inner, ok := s.inner.(subsystems.Upgradeable)
+	if !ok {
+		return nil
+	}
+	version, err := inner.NegotiateVersion(ctx, ProtocolVersions)
+	if errors.Is(err, subsystems.ErrVersionNegotiationUnsupported) {
+		return nil
+	}
+	if err != nil {
+		s.logger.Warn(errw.Wrapf(err, "negotiating protocol version with %s", s.name))
+		return nil
+	}
+	info.ProtocolVersion = version
+	if !ProtocolVersionRange.Contains(version) {
+		return errw.Wrapf(ErrIncompatibleProtocol, "%s negotiated %s, need between %s and %s",
+			s.name, version, ProtocolVersionRange.Min, ProtocolVersionRange.Max)
+	}
+	s.logger.Infof("negotiated protocol version %s with %s", version, s.name)
+	return nil
+}
+
+// ProtocolVersion returns the protocol version negotiated with the running version, if any.
+func (s *AgentSubsystem) ProtocolVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.CacheData == nil {
+		return ""
+	}
+	if info, ok := s.CacheData.Versions[s.CacheData.CurrentVersion]; ok {
+		return info.ProtocolVersion
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viamrobotics/agent/subsystems"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+type upgradeableSubsystem struct {
+	fakeSubsystem
+	version string
+	err     error
+	offered []string
+}
+
+func (u *upgradeableSubsystem) NegotiateVersion(ctx context.Context, offered []string) (string, error) {
+	u.offered = offered
+	return u.version, u.err
+}
+
+func TestCompatibleVersionRange(t *testing.T) {
+	r := CompatibleVersionRange{Min: "v1.2", Max: "v2"}
+	test.That(t, r.Contains("v1.2"), test.ShouldBeTrue)
+	test.That(t, r.Contains("v1.10.3"), test.ShouldBeTrue)
+	test.That(t, r.Contains("v2"), test.ShouldBeTrue)
+	test.That(t, r.Contains("v1.1"), test.ShouldBeFalse)
+	test.That(t, r.Contains("v2.1"), test.ShouldBeFalse)
+	test.That(t, r.Contains("garbage"), test.ShouldBeFalse)
+	test.That(t, CompatibleVersionRange{}.Contains("v99"), test.ShouldBeTrue)
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	useTempViamDirs(t)
+	ctx := context.Background()
+	inner := &upgradeableSubsystem{version: "v1"}
+	sub, err := NewAgentSubsystem(ctx, "fake", logging.NewTestLogger(t), inner)
+	test.That(t, err, test.ShouldBeNil)
+
+	test.That(t, sub.Start(ctx), test.ShouldBeNil)
+	test.That(t, inner.offered, test.ShouldResemble, ProtocolVersions)
+	test.That(t, sub.ProtocolVersion(), test.ShouldEqual, "v1")
+
+	// failing to negotiate isn't fatal
+	inner.err = subsystems.ErrVersionNegotiationUnsupported
+	test.That(t, sub.Start(ctx), test.ShouldBeNil)
+	inner.err = errors.New("unknown flag")
+	test.That(t, sub.Start(ctx), test.ShouldBeNil)
+	test.That(t, inner.stops, test.ShouldEqual, 0)
+
+	// but an incompatible version stops the subsystem
+	inner.err = nil
+	inner.version = "v7"
+	err = sub.Start(ctx)
+	test.That(t, errors.Is(err, ErrIncompatibleProtocol), test.ShouldBeTrue)
+	test.That(t, inner.stops, test.ShouldEqual, 1)
+	test.That(t, sub.ProtocolVersion(), test.ShouldEqual, "v7")
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	errw "github.com/pkg/errors"
+	"go.viam.com/rdk/logging"
+	"golang.org/x/sys/unix"
+)
+
+// how often to sweep for orphans even without a SIGCHLD, as signals can be coalesced or missed.
+const orphanSweepInterval = time.Minute
+
+// OrphanReaper reaps zombie processes that were reparented to the agent, as happens when it's PID 1 in a container.
+//
+// Only zombies that are neither process group leaders nor in the agent's own process group are reaped. Every process the
+// agent starts itself is one or the other (subsystems and hooks get their own group, anything else shares the agent's),
+// so their exits are always left to be collected by their own cmd.Wait(). Orphans that don't match, such as daemons that
+// called setsid, are left alone.
+type OrphanReaper struct {
+	logger logging.Logger
+
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// NewOrphanReaper returns an OrphanReaper.
+func NewOrphanReaper(logger logging.Logger) *OrphanReaper {
+	return &OrphanReaper{logger: logger}
+}
+
+// CanReapOrphans returns true if orphans are reparented to this process, because it's PID 1 or a subreaper.
+func CanReapOrphans() bool {
+	if os.Getpid() == 1 {
+		return true
+	}
+	var isSubreaper int32
+	//nolint:gosec
+	err := unix.Prctl(unix.PR_GET_CHILD_SUBREAPER, uintptr(unsafe.Pointer(&isSubreaper)), 0, 0, 0)
+	return err == nil && isSubreaper != 0
+}
+
+// Start begins reaping in the background, on every SIGCHLD and periodically.
+func (r *OrphanReaper) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGCHLD)
+	r.workers.Add(1)
+	go func() {
+		defer r.workers.Done()
+		defer signal.Stop(sigChan)
+		ticker := time.NewTicker(orphanSweepInterval)
+		defer ticker.Stop()
+		for {
+			r.Reap()
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigChan:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Reap reaps all current orphaned zombies, returning how many were reaped.
+func (r *OrphanReaper) Reap() int {
+	pids, err := orphanZombies(os.Getpid(), syscall.Getpgrp())
+	if err != nil {
+		r.logger.Error(errw.Wrap(err, "finding orphaned processes"))
+		return 0
+	}
+	reaped := 0
+	for _, pid := range pids {
+		var status unix.WaitStatus
+		// by pid, so nothing else can be collected by mistake
+		wpid, err := unix.Wait4(pid, &status, unix.WNOHANG, nil)
+		if err != nil || wpid != pid {
+			continue
+		}
+		reaped++
+		r.logger.Debugw("reaped orphaned process", "pid", pid, "exit code", status.ExitStatus())
+	}
+	return reaped
+}
+
+// Close stops reaping.
+func (r *OrphanReaper) Close() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.workers.Wait()
+}
+
+// orphanZombies returns the zombie children of self that aren't group leaders or in selfPgrp.
+func orphanZombies(self, selfPgrp int) ([]int, error) {
+	procs, err := ListProcesses()
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, proc := range procs {
+		if proc.State != "Z" || proc.PPid != self || proc.Pgid == proc.Pid || proc.Pgid == selfPgrp {
+			continue
+		}
+		pids = append(pids, proc.Pid)
+	}
+	return pids, nil
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"golang.org/x/sys/unix"
+)
+
+func isZombie(pid int) bool {
+	//nolint:gosec
+	raw, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(raw[strings.LastIndexByte(string(raw), ')')+1:]))
+	return fields[0] == "Z"
+}
+
+func TestOrphanReaper(t *testing.T) {
+	// orphans are only reparented to a subreaper (or PID 1)
+	test.That(t, unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0), test.ShouldBeNil)
+	t.Cleanup(func() {
+		//nolint:errcheck
+		unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 0, 0, 0, 0)
+	})
+	test.That(t, CanReapOrphans(), test.ShouldBeTrue)
+	reaper := NewOrphanReaper(logging.NewTestLogger(t))
+
+	// a direct child is never reaped, even once it's a zombie
+	child := exec.Command("true")
+	child.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	test.That(t, child.Start(), test.ShouldBeNil)
+	for !isZombie(child.Process.Pid) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	test.That(t, reaper.Reap(), test.ShouldEqual, 0)
+	test.That(t, child.Wait(), test.ShouldBeNil)
+
+	// the shell exits straight away, orphaning its background sleep
+	out, err := func() ([]byte, error) {
+		cmd := exec.Command("sh", "-c", "sleep 0.2 & echo $!")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		return cmd.Output()
+	}()
+	test.That(t, err, test.ShouldBeNil)
+	orphan, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	test.That(t, err, test.ShouldBeNil)
+	for !isZombie(orphan) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	test.That(t, reaper.Reap(), test.ShouldEqual, 1)
+	_, err = os.Stat(filepath.Join("/proc", strconv.Itoa(orphan)))
+	test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+}
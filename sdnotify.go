@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	errw "github.com/pkg/errors"
+)
+
+// systemd's watchdog is gated on this subsystem's health (the viamserver package can't be imported here.)
+const systemdWatchdogSubsystem = "viam-server"
+
+// SdNotify sends state (such as "READY=1") to systemd's notification socket. It returns false, without error, when
+// the agent isn't running under a unit that listens for notifications (NOTIFY_SOCKET is unset.)
+func SdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	// a leading @ is an abstract socket
+	if socket[0] == '@' {
+		addr.Name = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix(addr.Net, nil, addr)
+	if err != nil {
+		return false, errw.Wrapf(err, "connecting to systemd notify socket %s", socket)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, errw.Wrapf(err, "sending %q to systemd", state)
+	}
+	return true, nil
+}
+
+// SystemdWatchdogInterval returns how often to ping systemd's watchdog, which is half of the unit's WatchdogSec as
+// systemd recommends. It returns false if the watchdog isn't enabled for this process.
+func SystemdWatchdogInterval() (time.Duration, bool) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return 0, false
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	// when set, WATCHDOG_PID says which process the watchdog is meant for
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil || pid != os.Getpid() {
+			return 0, false
+		}
+	}
+	return time.Duration(usec) * time.Microsecond / 2, true
+}
+
+// SystemdWatchdogPetter pets systemd's service watchdog (WatchdogSec) via sd_notify, so systemd restarts the unit if
+// the pings stop.
+type SystemdWatchdogPetter struct{}
+
+// Open checks that there's a notify socket to send to.
+func (SystemdWatchdogPetter) Open() error {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return errw.New("NOTIFY_SOCKET is not set")
+	}
+	return nil
+}
+
+// Pet sends WATCHDOG=1.
+func (SystemdWatchdogPetter) Pet() error {
+	_, err := SdNotify("WATCHDOG=1")
+	return err
+}
+
+// Close does nothing, systemd's watchdog can't be disarmed from the service.
+func (SystemdWatchdogPetter) Close() error { return nil }
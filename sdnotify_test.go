@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent/subsystems"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+// listenNotify stands in for systemd's notify socket, returning each datagram received.
+func listenNotify(t *testing.T) <-chan string {
+	t.Helper()
+	// unix socket paths are length limited, so avoid the (long) t.TempDir()
+	dir, err := os.MkdirTemp("", "sd")
+	test.That(t, err, test.ShouldBeNil)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	test.That(t, err, test.ShouldBeNil)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	received := make(chan string, 100)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			received <- string(buf[:n])
+		}
+	}()
+	return received
+}
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := SdNotify("READY=1")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sent, test.ShouldBeFalse)
+	_, ok := SystemdWatchdogInterval()
+	test.That(t, ok, test.ShouldBeFalse)
+
+	received := listenNotify(t)
+	sent, err = SdNotify("READY=1")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, sent, test.ShouldBeTrue)
+	test.That(t, <-received, test.ShouldEqual, "READY=1")
+
+	t.Setenv("WATCHDOG_USEC", "4000000")
+	interval, ok := SystemdWatchdogInterval()
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, interval, test.ShouldEqual, time.Second*2)
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	_, ok = SystemdWatchdogInterval()
+	test.That(t, ok, test.ShouldBeFalse)
+}
+
+func TestSystemdWatchdog(t *testing.T) {
+	received := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	errUnhealthy := errors.New("unhealthy")
+	viamServer := &fakeSubsystem{healthErr: errUnhealthy}
+	m := &Manager{
+		logger:           logging.NewTestLogger(t),
+		loadedSubsystems: map[string]subsystems.Subsystem{systemdWatchdogSubsystem: viamServer},
+		healthStatus:     map[string]error{},
+		unhealthyAction:  UnhealthyActionNone,
+	}
+	defer m.CloseAll()
+	m.configureSystemdWatchdog(agentConfig{systemdWatchdog: true, systemdNotifyReady: true})
+	test.That(t, m.sdWatchdog, test.ShouldNotBeNil)
+
+	// collects what's received over a short window
+	drain := func() []string {
+		var msgs []string
+		window := time.After(time.Millisecond * 50)
+		for {
+			select {
+			case msg := <-received:
+				msgs = append(msgs, msg)
+			case <-window:
+				return msgs
+			}
+		}
+	}
+
+	// no pings (and not ready) while viam-server is unhealthy
+	m.SubsystemHealthChecks(context.Background())
+	drain()
+	test.That(t, drain(), test.ShouldBeEmpty)
+
+	viamServer.healthErr = nil
+	m.SubsystemHealthChecks(context.Background())
+	msgs := drain()
+	test.That(t, msgs, test.ShouldContain, "READY=1")
+	test.That(t, msgs, test.ShouldContain, "WATCHDOG=1")
+
+	// ready is only sent once
+	m.SubsystemHealthChecks(context.Background())
+	test.That(t, drain(), test.ShouldNotContain, "READY=1")
+
+	// no pings once health checks stop, as if the manager loop hung
+	m.setWatchdogMaxCheckAge(time.Millisecond * 50)
+	m.SubsystemHealthChecks(context.Background())
+	time.Sleep(time.Millisecond * 150)
+	drain()
+	test.That(t, drain(), test.ShouldBeEmpty)
+}
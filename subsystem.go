@@ -18,6 +18,8 @@ import (
 	"time"
 
 	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent/config"
+	"github.com/viamrobotics/agent/subsystems"
 	pb "go.viam.com/api/app/agent/v1"
 	"go.viam.com/rdk/logging"
 )
@@ -43,6 +45,17 @@ type BasicSubsystem interface {
 	HealthCheck(ctx context.Context) error
 }
 
+// readinessChecker is if a wrapped subsystem has a readiness check distinct from its HealthCheck.
+type readinessChecker interface {
+	Readiness(ctx context.Context) error
+}
+
+// failureStateClearer is if a wrapped subsystem can refuse to start after repeated failures, see
+// subsystems.FailureStateClearer.
+type failureStateClearer interface {
+	ClearFailureState()
+}
+
 // updatable is if a wrapped subsystem has it's own (additional) update code to run.
 type updatable interface {
 	Update(ctx context.Context, cfg *pb.DeviceSubsystemConfig, newVersion bool) (bool, error)
@@ -58,6 +71,34 @@ type AgentSubsystem struct {
 	name   string
 	logger logging.Logger
 	inner  BasicSubsystem
+
+	preStartHook  func(ctx context.Context) error
+	postStartHook func(ctx context.Context) error
+	preStopHook   func(ctx context.Context) error
+	postStopHook  func(ctx context.Context) error
+}
+
+// AgentSubsystemOption configures optional AgentSubsystem behavior.
+type AgentSubsystemOption func(*AgentSubsystem)
+
+// WithPreStartHook runs fn before each start. If it returns an error, the subsystem isn't started.
+func WithPreStartHook(fn func(ctx context.Context) error) AgentSubsystemOption {
+	return func(s *AgentSubsystem) { s.preStartHook = fn }
+}
+
+// WithPostStartHook runs fn after each successful start. Errors are only logged.
+func WithPostStartHook(fn func(ctx context.Context) error) AgentSubsystemOption {
+	return func(s *AgentSubsystem) { s.postStartHook = fn }
+}
+
+// WithPreStopHook runs fn before each stop. If it returns an error, the subsystem isn't stopped.
+func WithPreStopHook(fn func(ctx context.Context) error) AgentSubsystemOption {
+	return func(s *AgentSubsystem) { s.preStopHook = fn }
+}
+
+// WithPostStopHook runs fn after each successful stop. Errors are only logged.
+func WithPostStopHook(fn func(ctx context.Context) error) AgentSubsystemOption {
+	return func(s *AgentSubsystem) { s.postStopHook = fn }
 }
 
 // CacheData stores VersionInfo and the current/previous versions for (TODO) rollback.
@@ -80,6 +121,8 @@ type VersionInfo struct {
 	StartCount     uint
 	LongFailCount  uint
 	ShortFailCount uint
+	// protocol version negotiated with this version, if it supports that
+	ProtocolVersion string
 }
 
 // Version returns the running version.
@@ -96,10 +139,19 @@ func (s *AgentSubsystem) Version() string {
 func (s *AgentSubsystem) Start(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.start(ctx, s.inner.Start)
+}
 
+// start runs startInner, which starts the inner subsystem, along with the hooks and bookkeeping. mu must be held.
+func (s *AgentSubsystem) start(ctx context.Context, startInner func(ctx context.Context) error) error {
 	if s.disable {
 		return ErrSubsystemDisabled
 	}
+	if s.preStartHook != nil {
+		if err := s.preStartHook(ctx); err != nil {
+			return errw.Wrapf(err, "pre-start hook for %s", s.name)
+		}
+	}
 
 	info, ok := s.CacheData.Versions[s.CacheData.CurrentVersion]
 	if !ok {
@@ -115,15 +167,56 @@ func (s *AgentSubsystem) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	return s.inner.Start(ctx)
+	if err := startInner(ctx); err != nil {
+		return err
+	}
+	if err := s.negotiateProtocol(ctx, info); err != nil {
+		// running something the agent can't work with is worse than not running it
+		return errors.Join(err, s.inner.Stop(ctx), s.saveCache())
+	}
+	if s.postStartHook != nil {
+		if err := s.postStartHook(ctx); err != nil {
+			s.logger.Error(errw.Wrapf(err, "post-start hook for %s", s.name))
+		}
+	}
+	return nil
 }
 
 // Stop stops the subsystem.
 func (s *AgentSubsystem) Stop(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.stop(ctx, s.inner.Stop)
+}
+
+// stop runs stopInner, which stops the inner subsystem, along with the hooks. mu must be held.
+func (s *AgentSubsystem) stop(ctx context.Context, stopInner func(ctx context.Context) error) error {
+	if s.preStopHook != nil {
+		if err := s.preStopHook(ctx); err != nil {
+			return errw.Wrapf(err, "pre-stop hook for %s", s.name)
+		}
+	}
 	s.startTime = nil
-	return s.inner.Stop(ctx)
+	if err := stopInner(ctx); err != nil {
+		return err
+	}
+	if s.postStopHook != nil {
+		if err := s.postStopHook(ctx); err != nil {
+			s.logger.Error(errw.Wrapf(err, "post-stop hook for %s", s.name))
+		}
+	}
+	return nil
+}
+
+// restart is the subsystems.RestartWrapper given to inner subsystems that restart themselves, so those restarts run
+// the same hooks and bookkeeping as a Stop followed by a Start.
+func (s *AgentSubsystem) restart(ctx context.Context, stopInner, startInner func(ctx context.Context) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.stop(ctx, stopInner); err != nil {
+		return err
+	}
+	return s.start(ctx, startInner)
 }
 
 // HealthCheck calls the inner subsystem's HealthCheck() to verify, and logs failures/successes.
@@ -160,14 +253,95 @@ func (s *AgentSubsystem) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// StartupBanner returns the inner subsystem's StartupBanner(), if it has one.
+func (s *AgentSubsystem) StartupBanner() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inner, ok := s.inner.(subsystems.StartupBannerReporter); ok {
+		return inner.StartupBanner()
+	}
+	return ""
+}
+
+// Timeline returns the inner subsystem's Timeline(), if it has one.
+func (s *AgentSubsystem) Timeline() []subsystems.StateEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inner, ok := s.inner.(subsystems.TimelineReporter); ok {
+		return inner.Timeline()
+	}
+	return nil
+}
+
+// LastStartKind returns the inner subsystem's LastStartKind(), if it has one.
+func (s *AgentSubsystem) LastStartKind() subsystems.StartKind {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inner, ok := s.inner.(subsystems.StartKindReporter); ok {
+		return inner.LastStartKind()
+	}
+	return ""
+}
+
+// CheckURLs returns the inner subsystem's CheckURLs(), if it has one.
+func (s *AgentSubsystem) CheckURLs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inner, ok := s.inner.(subsystems.CheckURLReporter); ok {
+		return inner.CheckURLs()
+	}
+	return nil
+}
+
+// LastExitSignal returns the inner subsystem's LastExitSignal(), if it has one.
+func (s *AgentSubsystem) LastExitSignal() (syscall.Signal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inner, ok := s.inner.(subsystems.ExitSignalReporter); ok {
+		return inner.LastExitSignal()
+	}
+	return 0, false
+}
+
+// ClearFailureState calls the inner subsystem's ClearFailureState(), if it has one.
+func (s *AgentSubsystem) ClearFailureState() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inner, ok := s.inner.(failureStateClearer); ok {
+		inner.ClearFailureState()
+	}
+}
+
+// Readiness calls the inner subsystem's Readiness(). Subsystems without one are ready whenever they're healthy, so
+// it returns nil for those and callers should check health first.
+func (s *AgentSubsystem) Readiness(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disable {
+		return nil
+	}
+	inner, ok := s.inner.(readinessChecker)
+	if ok {
+		return inner.Readiness(ctx)
+	}
+	return nil
+}
+
 // NewAgentSubsystem returns a new wrapped subsystem.
 func NewAgentSubsystem(
 	ctx context.Context,
 	name string,
 	logger logging.Logger,
 	subsys BasicSubsystem,
+	opts ...AgentSubsystemOption,
 ) (*AgentSubsystem, error) {
 	sub := &AgentSubsystem{name: name, logger: logger, inner: subsys}
+	for _, opt := range opts {
+		opt(sub)
+	}
+	if restarter, ok := subsys.(subsystems.SelfRestarter); ok {
+		restarter.SetRestartWrapper(sub.restart)
+	}
 	err := sub.LoadCache()
 	if err != nil {
 		return nil, err
@@ -522,7 +696,12 @@ func (is *InternalSubsystem) Stop(ctx context.Context) error {
 	}
 
 	is.logger.Warnf("%s refused to exit, killing", is.name)
-	err = syscall.Kill(-is.cmd.Process.Pid, syscall.SIGKILL)
+	err = KillProcessGroup(is.cmd.Process.Pid, syscall.SIGKILL)
+	if errors.Is(err, ErrSameProcessGroup) {
+		// never signal our own group, so only the main process can be killed
+		is.logger.Error(err)
+		err = is.cmd.Process.Kill()
+	}
 	if err != nil {
 		is.logger.Error(err)
 	}
@@ -599,6 +778,13 @@ func (is *InternalSubsystem) Update(ctx context.Context, cfg *pb.DeviceSubsystem
 		return true, err
 	}
 
+	jsonBytes, err = config.ExpandEnvInJSON(jsonBytes)
+	if config.IsUnresolvedOnly(err) {
+		is.logger.Warn(errw.Wrapf(err, "expanding config for %s", is.name))
+	} else if err != nil {
+		return true, err
+	}
+
 	fileBytes, err := os.ReadFile(is.cfgPath)
 	// If no changes, only restart if there was a new version.
 	if err == nil && bytes.Equal(fileBytes, jsonBytes) {
@@ -611,6 +797,7 @@ func (is *InternalSubsystem) Update(ctx context.Context, cfg *pb.DeviceSubsystem
 	}
 
 	// If attribute changes, restart after writing the new config file.
-	//nolint:gosec
-	return true, errors.Join(os.WriteFile(is.cfgPath, jsonBytes, 0o644), SyncFS(is.cfgPath))
+	// It may hold secrets expanded from the environment, so it's only readable by its owner, including files written
+	// before this was the case.
+	return true, errors.Join(os.WriteFile(is.cfgPath, jsonBytes, 0o600), os.Chmod(is.cfgPath, 0o600), SyncFS(is.cfgPath))
 }
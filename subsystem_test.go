@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/viamrobotics/agent/subsystems"
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestAgentSubsystemHooks(t *testing.T) {
+	useTempViamDirs(t)
+	ctx := context.Background()
+	errHook := errors.New("hook failed")
+
+	var calls []string
+	var preStartErr, postStopErr error
+	inner := &fakeSubsystem{}
+	sub, err := NewAgentSubsystem(ctx, "fake", logging.NewTestLogger(t), inner,
+		WithPreStartHook(func(ctx context.Context) error {
+			calls = append(calls, "pre-start")
+			return preStartErr
+		}),
+		WithPostStartHook(func(ctx context.Context) error {
+			calls = append(calls, "post-start")
+			return nil
+		}),
+		WithPreStopHook(func(ctx context.Context) error {
+			calls = append(calls, "pre-stop")
+			return nil
+		}),
+		WithPostStopHook(func(ctx context.Context) error {
+			calls = append(calls, "post-stop")
+			return postStopErr
+		}),
+	)
+	test.That(t, err, test.ShouldBeNil)
+
+	test.That(t, sub.Start(ctx), test.ShouldBeNil)
+	test.That(t, sub.Stop(ctx), test.ShouldBeNil)
+	test.That(t, calls, test.ShouldResemble, []string{"pre-start", "post-start", "pre-stop", "post-stop"})
+	test.That(t, inner.starts, test.ShouldEqual, 1)
+	test.That(t, inner.stops, test.ShouldEqual, 1)
+
+	// a failed pre-hook aborts the operation
+	calls = nil
+	preStartErr = errHook
+	test.That(t, errors.Is(sub.Start(ctx), errHook), test.ShouldBeTrue)
+	test.That(t, calls, test.ShouldResemble, []string{"pre-start"})
+	test.That(t, inner.starts, test.ShouldEqual, 1)
+
+	// a failed post-hook doesn't change the result
+	postStopErr = errHook
+	test.That(t, sub.Stop(ctx), test.ShouldBeNil)
+	test.That(t, inner.stops, test.ShouldEqual, 2)
+
+	// the inner subsystem's own restarts run the hooks too
+	calls = nil
+	preStartErr, postStopErr = nil, nil
+	test.That(t, sub.Start(ctx), test.ShouldBeNil)
+	calls = nil
+	test.That(t, inner.restart(ctx, inner.Stop, inner.Start), test.ShouldBeNil)
+	test.That(t, calls, test.ShouldResemble, []string{"pre-stop", "post-stop", "pre-start", "post-start"})
+	test.That(t, inner.starts, test.ShouldEqual, 3)
+	test.That(t, inner.stops, test.ShouldEqual, 3)
+	test.That(t, sub.CacheData.Versions[sub.CacheData.CurrentVersion].StartCount, test.ShouldEqual, 3)
+
+	// a failed stop, such as when it was already stopped, skips the start
+	calls = nil
+	errStop := errors.New("already stopped")
+	err = inner.restart(ctx, func(ctx context.Context) error { return errStop }, inner.Start)
+	test.That(t, errors.Is(err, errStop), test.ShouldBeTrue)
+	test.That(t, calls, test.ShouldResemble, []string{"pre-stop"})
+	test.That(t, inner.starts, test.ShouldEqual, 3)
+}
+
+func TestAgentSubsystemReporters(t *testing.T) {
+	useTempViamDirs(t)
+	ctx := context.Background()
+	inner := &fakeSubsystem{exitSignal: syscall.SIGSEGV, timeline: []subsystems.StateEvent{{State: "crashed"}}}
+	sub, err := NewAgentSubsystem(ctx, "fake", logging.NewTestLogger(t), inner)
+	test.That(t, err, test.ShouldBeNil)
+
+	sig, ok := sub.LastExitSignal()
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, sig, test.ShouldEqual, syscall.SIGSEGV)
+	test.That(t, sub.Timeline(), test.ShouldResemble, inner.timeline)
+	test.That(t, sub.LastStartKind(), test.ShouldEqual, subsystems.StartKind(""))
+	inner.startKind = "restart_after_crash"
+	test.That(t, sub.LastStartKind(), test.ShouldEqual, inner.startKind)
+	test.That(t, sub.CheckURLs(), test.ShouldBeEmpty)
+	inner.checkURLs = []string{"https://robot.local:8080"}
+	test.That(t, sub.CheckURLs(), test.ShouldResemble, inner.checkURLs)
+}
+
+func TestInternalSubsystemUpdateFileMode(t *testing.T) {
+	useTempViamDirs(t)
+	is, err := NewInternalSubsystem("fake", nil, logging.NewTestLogger(t), false)
+	test.That(t, err, test.ShouldBeNil)
+	// written by an older version, before it was restricted
+	test.That(t, os.WriteFile(is.cfgPath, []byte("{}"), 0o644), test.ShouldBeNil)
+
+	t.Setenv("VIAM_SECRET", "hunter2")
+	attrs, err := structpb.NewStruct(map[string]any{"key": "$VIAM_SECRET"})
+	test.That(t, err, test.ShouldBeNil)
+	needRestart, err := is.Update(context.Background(), &pb.DeviceSubsystemConfig{Attributes: attrs}, false)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, needRestart, test.ShouldBeTrue)
+
+	info, err := os.Stat(is.cfgPath)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, info.Mode().Perm(), test.ShouldEqual, os.FileMode(0o600))
+	data, err := os.ReadFile(is.cfgPath)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(data), test.ShouldEqual, `{"key":"hunter2"}`)
+}
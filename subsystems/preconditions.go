@@ -0,0 +1,111 @@
+package subsystems
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Precondition is a check that must pass before a subsystem is started.
+type Precondition interface {
+	// Check returns an error if the subsystem shouldn't be started
+	Check(ctx context.Context) error
+
+	// Name identifies the precondition in errors and logs
+	Name() string
+}
+
+// BlockingPrecondition can be implemented by a Precondition that must not run concurrently with others
+// (e.g. because it changes system state). Blocking preconditions run one at a time, after all the others.
+type BlockingPrecondition interface {
+	Precondition
+	Blocking() bool
+}
+
+// PreconditionFailure is the error from a single failed precondition.
+type PreconditionFailure struct {
+	Name string
+	Err  error
+}
+
+// PreconditionError collects every failed precondition from a run.
+type PreconditionError struct {
+	Failures []PreconditionFailure
+}
+
+func (e *PreconditionError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", f.Name, f.Err))
+	}
+	return "start preconditions failed: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap allows errors.Is/As to match against the individual failures.
+func (e *PreconditionError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f.Err)
+	}
+	return errs
+}
+
+func isBlocking(p Precondition) bool {
+	b, ok := p.(BlockingPrecondition)
+	return ok && b.Blocking()
+}
+
+// RunPreconditions runs non-blocking preconditions concurrently, then blocking ones in order, and returns
+// a *PreconditionError listing all failures. A timeout above zero caps the total time for all of them.
+func RunPreconditions(ctx context.Context, preconditions []Precondition, timeout time.Duration) error {
+	if len(preconditions) == 0 {
+		return nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var mu sync.Mutex
+	var failures []PreconditionFailure
+	record := func(p Precondition, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, PreconditionFailure{Name: p.Name(), Err: err})
+	}
+
+	var group errgroup.Group
+	var blocking []Precondition
+	for _, p := range preconditions {
+		if isBlocking(p) {
+			blocking = append(blocking, p)
+			continue
+		}
+		p := p
+		group.Go(func() error {
+			if err := p.Check(ctx); err != nil {
+				record(p, err)
+			}
+			// failures are collected rather than returned, so one doesn't hide the others
+			return nil
+		})
+	}
+	//nolint:errcheck
+	group.Wait()
+
+	for _, p := range blocking {
+		if err := p.Check(ctx); err != nil {
+			record(p, err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return &PreconditionError{Failures: failures}
+	}
+	return nil
+}
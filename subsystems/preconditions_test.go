@@ -0,0 +1,85 @@
+package subsystems
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+)
+
+type fakePrecondition struct {
+	name     string
+	err      error
+	blocking bool
+	delay    time.Duration
+	running  *atomic.Int32
+	maxSeen  *atomic.Int32
+}
+
+func (p *fakePrecondition) Name() string { return p.name }
+
+func (p *fakePrecondition) Blocking() bool { return p.blocking }
+
+func (p *fakePrecondition) Check(ctx context.Context) error {
+	n := p.running.Add(1)
+	defer p.running.Add(-1)
+	for {
+		seen := p.maxSeen.Load()
+		if n <= seen || p.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.delay):
+	}
+	return p.err
+}
+
+func TestRunPreconditions(t *testing.T) {
+	ctx := context.Background()
+	errPort := errors.New("port in use")
+	errDisk := errors.New("disk full")
+
+	t.Run("concurrent", func(t *testing.T) {
+		var running, maxSeen atomic.Int32
+		preconds := []Precondition{
+			&fakePrecondition{name: "a", delay: time.Millisecond * 50, running: &running, maxSeen: &maxSeen},
+			&fakePrecondition{name: "b", delay: time.Millisecond * 50, running: &running, maxSeen: &maxSeen, err: errPort},
+			&fakePrecondition{name: "c", delay: time.Millisecond * 50, running: &running, maxSeen: &maxSeen, err: errDisk},
+		}
+		err := RunPreconditions(ctx, preconds, 0)
+		test.That(t, maxSeen.Load(), test.ShouldEqual, 3)
+
+		var precondErr *PreconditionError
+		test.That(t, errors.As(err, &precondErr), test.ShouldBeTrue)
+		test.That(t, len(precondErr.Failures), test.ShouldEqual, 2)
+		test.That(t, errors.Is(err, errPort), test.ShouldBeTrue)
+		test.That(t, errors.Is(err, errDisk), test.ShouldBeTrue)
+	})
+
+	t.Run("blocking", func(t *testing.T) {
+		var running, maxSeen atomic.Int32
+		preconds := []Precondition{
+			&fakePrecondition{name: "a", blocking: true, delay: time.Millisecond * 20, running: &running, maxSeen: &maxSeen},
+			&fakePrecondition{name: "b", blocking: true, delay: time.Millisecond * 20, running: &running, maxSeen: &maxSeen},
+		}
+		test.That(t, RunPreconditions(ctx, preconds, 0), test.ShouldBeNil)
+		test.That(t, maxSeen.Load(), test.ShouldEqual, 1)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		var running, maxSeen atomic.Int32
+		preconds := []Precondition{
+			&fakePrecondition{name: "slow", delay: time.Second * 10, running: &running, maxSeen: &maxSeen},
+		}
+		start := time.Now()
+		err := RunPreconditions(ctx, preconds, time.Millisecond*50)
+		test.That(t, time.Since(start), test.ShouldBeLessThan, time.Second)
+		test.That(t, errors.Is(err, context.DeadlineExceeded), test.ShouldBeTrue)
+	})
+}
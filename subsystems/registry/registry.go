@@ -11,9 +11,10 @@ import (
 )
 
 var (
-	mu       sync.Mutex
-	creators = map[string]CreatorFunc{}
-	configs  = map[string]*pb.DeviceSubsystemConfig{}
+	mu            sync.Mutex
+	creators      = map[string]CreatorFunc{}
+	configs       = map[string]*pb.DeviceSubsystemConfig{}
+	preconditions = map[string][]subsystems.Precondition{}
 )
 
 type CreatorFunc func(ctx context.Context, logger logging.Logger, updateConf *pb.DeviceSubsystemConfig) (subsystems.Subsystem, error)
@@ -25,10 +26,29 @@ func Register(name string, creator CreatorFunc, defaultCfg *pb.DeviceSubsystemCo
 	configs[name] = defaultCfg
 }
 
+// RegisterWithPreconditions registers a subsystem along with checks that must pass before each start.
+func RegisterWithPreconditions(
+	name string, creator CreatorFunc, defaultCfg *pb.DeviceSubsystemConfig, preconds ...subsystems.Precondition,
+) {
+	mu.Lock()
+	defer mu.Unlock()
+	creators[name] = creator
+	configs[name] = defaultCfg
+	preconditions[name] = append(preconditions[name], preconds...)
+}
+
 func Deregister(name string) {
 	mu.Lock()
 	defer mu.Unlock()
 	delete(creators, name)
+	delete(preconditions, name)
+}
+
+// GetPreconditions returns the start preconditions registered for a subsystem.
+func GetPreconditions(name string) []subsystems.Precondition {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]subsystems.Precondition(nil), preconditions[name]...)
 }
 
 func GetCreator(name string) CreatorFunc {
@@ -3,6 +3,9 @@ package subsystems
 
 import (
 	"context"
+	"errors"
+	"syscall"
+	"time"
 
 	pb "go.viam.com/api/app/agent/v1"
 )
@@ -23,3 +26,80 @@ type Subsystem interface {
 	// Version returns the current version of the subsystem
 	Version() string
 }
+
+// Upgradeable is implemented by subsystems that can negotiate a protocol version with the agent, which may allow
+// compatibility to be confirmed (or an incompatibility found) without a binary swap.
+type Upgradeable interface {
+	// NegotiateVersion returns the protocol version chosen from those offered by the agent.
+	NegotiateVersion(ctx context.Context, offered []string) (string, error)
+}
+
+// ErrVersionNegotiationUnsupported is returned by NegotiateVersion when the running version can't negotiate.
+var ErrVersionNegotiationUnsupported = errors.New("version negotiation unsupported")
+
+// ErrCrashLooping is returned (possibly wrapped) by Start while a subsystem won't be started, as it has exited
+// unexpectedly too often. It stays that way until its failure state is cleared, see FailureStateClearer.
+var ErrCrashLooping = errors.New("crash looping")
+
+// RestartWrapper runs a subsystem's own restart: stop, then start if stop succeeded.
+type RestartWrapper func(ctx context.Context, stop, start func(ctx context.Context) error) error
+
+// SelfRestarter is implemented by subsystems that restart themselves, such as on a schedule, rather than only when
+// the agent asks. The agent sets a RestartWrapper before the first Start, which those restarts must go through, so the
+// agent's hooks and bookkeeping run just as they would for its own restarts.
+type SelfRestarter interface {
+	SetRestartWrapper(wrap RestartWrapper)
+}
+
+// StartupBannerReporter is implemented by subsystems that keep what they logged during startup (build info, loaded
+// config summary, etc.), for diagnostics.
+type StartupBannerReporter interface {
+	// StartupBanner returns the output of the last startup, empty if there is none.
+	StartupBanner() string
+}
+
+// State is a state a subsystem has transitioned to, as recorded in its Timeline.
+type State string
+
+// StateEvent is a single transition in a subsystem's Timeline.
+type StateEvent struct {
+	Time  time.Time `json:"time"`
+	State State     `json:"state"`
+	// optional, such as the crash reason or healthcheck error
+	Detail string `json:"detail,omitempty"`
+}
+
+// TimelineReporter is implemented by subsystems that keep their recent state transitions, for diagnostics.
+type TimelineReporter interface {
+	// Timeline returns the most recent state transitions, oldest first.
+	Timeline() []StateEvent
+}
+
+// StartKind is why a subsystem was last started, such as a first start or a restart after a crash.
+type StartKind string
+
+// StartKindReporter is implemented by subsystems that track why they were started, for diagnostics.
+type StartKindReporter interface {
+	// LastStartKind returns why the subsystem was last started, "" if it hasn't been.
+	LastStartKind() StartKind
+}
+
+// CheckURLReporter is implemented by subsystems that serve on URLs they've logged, for diagnostics.
+type CheckURLReporter interface {
+	// CheckURLs returns the URLs being served on during the current run.
+	CheckURLs() []string
+}
+
+// ExitSignalReporter is implemented by subsystems that run a process, reporting whether a signal ended it, for
+// diagnostics.
+type ExitSignalReporter interface {
+	// LastExitSignal returns the signal that terminated the process the last time it exited, if one did.
+	LastExitSignal() (syscall.Signal, bool)
+}
+
+// FailureStateClearer is implemented by subsystems that refuse to start after repeated failures, such as with
+// ErrCrashLooping, allowing them to be started again.
+type FailureStateClearer interface {
+	// ClearFailureState forgets the failures, so the next Start will try again.
+	ClearFailureState()
+}
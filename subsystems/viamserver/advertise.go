@@ -0,0 +1,46 @@
+package viamserver
+
+import (
+	"net/url"
+	"strconv"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+)
+
+// advertise registers viam-server for local mDNS discovery, if enabled.
+func (s *viamServer) advertise(version string) error {
+	if !globalConfig.Load().advertiseCapabilities {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mdnsServer != nil {
+		return nil
+	}
+	parsed, err := url.Parse(s.checkURL)
+	if err != nil {
+		return errw.Wrapf(err, "parsing %s URL %s", SubsysName, s.checkURL)
+	}
+	port, err := strconv.Atoi(parsed.Port())
+	if err != nil {
+		return errw.Errorf("no port in %s URL %s", SubsysName, s.checkURL)
+	}
+	server, err := agent.AdvertiseCapabilities(port, version, []string{SubsysName}, s.checkURL)
+	if err != nil {
+		return err
+	}
+	s.logger.Infof("advertising %s on port %d via mDNS", agent.MDNSServiceType, port)
+	s.mdnsServer = server
+	return nil
+}
+
+// stopAdvertising deregisters from mDNS, if advertising.
+func (s *viamServer) stopAdvertising() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mdnsServer != nil {
+		s.mdnsServer.Shutdown()
+		s.mdnsServer = nil
+	}
+}
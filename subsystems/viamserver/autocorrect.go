@@ -0,0 +1,42 @@
+package viamserver
+
+import (
+	"fmt"
+	"time"
+)
+
+// below this, viam-server can time out while still loading modules on slower hardware.
+const minStartTimeout = time.Second * 10
+
+// correctConfig fixes settings that are valid on their own, but known to break viam-server (or the agent's handling
+// of it), returning a description of each change. It only runs when auto_correct is set, as some of these may be
+// intentional while testing.
+func correctConfig(cfg *viamServerConfig) []string {
+	var changes []string
+	if cfg.startTimeout < minStartTimeout {
+		changes = append(changes, fmt.Sprintf("start_timeout raised from %s to %s", cfg.startTimeout, minStartTimeout))
+		cfg.startTimeout = minStartTimeout
+	}
+	if cfg.healthCheckAttempts < 1 {
+		changes = append(changes, fmt.Sprintf("healthcheck_attempts raised from %d to 1", cfg.healthCheckAttempts))
+		cfg.healthCheckAttempts = 1
+	}
+	if cfg.crashLoopThreshold > 0 && cfg.crashLoopWindow <= 0 {
+		changes = append(changes, fmt.Sprintf("crash_loop_window set from %s to %s",
+			cfg.crashLoopWindow, defaultCrashLoopWindow))
+		cfg.crashLoopWindow = defaultCrashLoopWindow
+	}
+	if cfg.memoryLimitSoftBytes > 0 {
+		if cfg.memorySampleInterval <= 0 {
+			changes = append(changes, fmt.Sprintf("memory_sample_interval set from %s to %s",
+				cfg.memorySampleInterval, defaultMemorySampleInterval))
+			cfg.memorySampleInterval = defaultMemorySampleInterval
+		}
+		if cfg.softEvictionRecoveryRatio <= 0 || cfg.softEvictionRecoveryRatio > 1 {
+			changes = append(changes, fmt.Sprintf("soft_eviction_recovery_ratio set from %v to %v",
+				cfg.softEvictionRecoveryRatio, defaultSoftEvictionRecoveryRatio))
+			cfg.softEvictionRecoveryRatio = defaultSoftEvictionRecoveryRatio
+		}
+	}
+	return changes
+}
@@ -0,0 +1,56 @@
+package viamserver
+
+import (
+	"testing"
+	"time"
+
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCorrectConfig(t *testing.T) {
+	t.Run("good config unchanged", func(t *testing.T) {
+		cfg := configFromProto(logging.NewTestLogger(t), &pb.DeviceSubsystemConfig{})
+		before := *cfg
+		test.That(t, correctConfig(cfg), test.ShouldBeEmpty)
+		test.That(t, *cfg, test.ShouldResemble, before)
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		cfg := &viamServerConfig{
+			startTimeout:              time.Second,
+			healthCheckAttempts:       0,
+			crashLoopThreshold:        3,
+			memoryLimitSoftBytes:      1 << 30,
+			softEvictionRecoveryRatio: 1.5,
+		}
+		changes := correctConfig(cfg)
+		test.That(t, changes, test.ShouldHaveLength, 5)
+		test.That(t, cfg.startTimeout, test.ShouldEqual, minStartTimeout)
+		test.That(t, cfg.healthCheckAttempts, test.ShouldEqual, 1)
+		test.That(t, cfg.crashLoopWindow, test.ShouldEqual, defaultCrashLoopWindow)
+		test.That(t, cfg.memorySampleInterval, test.ShouldEqual, defaultMemorySampleInterval)
+		test.That(t, cfg.softEvictionRecoveryRatio, test.ShouldEqual, defaultSoftEvictionRecoveryRatio)
+	})
+
+	t.Run("memory rules only with a limit", func(t *testing.T) {
+		cfg := &viamServerConfig{startTimeout: minStartTimeout, healthCheckAttempts: 1}
+		test.That(t, correctConfig(cfg), test.ShouldBeEmpty)
+	})
+
+	t.Run("only when enabled", func(t *testing.T) {
+		logger := logging.NewTestLogger(t)
+		for _, enabled := range []bool{false, true} {
+			attrs, err := structpb.NewStruct(map[string]any{"start_timeout": "1s", "auto_correct": enabled})
+			test.That(t, err, test.ShouldBeNil)
+			cfg := configFromProto(logger, &pb.DeviceSubsystemConfig{Attributes: attrs})
+			if enabled {
+				test.That(t, cfg.startTimeout, test.ShouldEqual, minStartTimeout)
+			} else {
+				test.That(t, cfg.startTimeout, test.ShouldEqual, time.Second)
+			}
+		}
+	})
+}
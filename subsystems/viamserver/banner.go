@@ -0,0 +1,57 @@
+package viamserver
+
+import (
+	"strings"
+	"sync"
+)
+
+// startupBanner keeps the first lines viam-server writes (build info, loaded config summary, etc.) until startup
+// completes, bounded by bannerMaxLines and bannerMaxBytes. It's fed by agent.WithRawLineSink rather than a matcher,
+// so the lines still count as unmatched for sampling.
+type startupBanner struct {
+	mu     sync.Mutex
+	lines  []string
+	size   int
+	closed bool
+}
+
+// add keeps line (which may hold several), if there's room and startup hasn't completed.
+func (b *startupBanner) add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || line == "" {
+		return
+	}
+	for _, line := range strings.Split(line, "\n") {
+		if len(b.lines) >= bannerMaxLines || b.size+len(line) > bannerMaxBytes {
+			b.closed = true
+			return
+		}
+		b.lines = append(b.lines, line)
+		b.size += len(line) + 1
+	}
+}
+
+// close stops keeping lines, as startup has completed.
+func (b *startupBanner) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+func (b *startupBanner) String() string {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Join(b.lines, "\n")
+}
+
+// StartupBanner returns the output (build info, loaded config summary, etc.) logged by viam-server during its last startup.
+func (s *viamServer) StartupBanner() string {
+	s.mu.Lock()
+	banner := s.banner
+	s.mu.Unlock()
+	return banner.String()
+}
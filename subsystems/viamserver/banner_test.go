@@ -0,0 +1,54 @@
+package viamserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestStartupBanner(t *testing.T) {
+	binPath := fakeViamServer(t)
+	script := "#!/bin/sh\n" +
+		"echo 'viam-server v1.2.3'\n" +
+		"echo 'config: 4 components'\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"sleep 0.1\necho 'after startup'\n" +
+		"exec sleep 30\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.StartupBanner(), test.ShouldBeEmpty)
+
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	banner := s.StartupBanner()
+	test.That(t, banner, test.ShouldStartWith, "viam-server v1.2.3\nconfig: 4 components\nserving")
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, s.StartupBanner(), test.ShouldEqual, banner)
+	test.That(t, banner, test.ShouldNotContainSubstring, "after startup")
+}
+
+func TestStartupBannerBounds(t *testing.T) {
+	var b startupBanner
+	for i := 0; i < bannerMaxLines*2; i++ {
+		b.add(fmt.Sprintf("line %d", i))
+	}
+	test.That(t, strings.Split(b.String(), "\n"), test.ShouldHaveLength, bannerMaxLines)
+
+	b = startupBanner{}
+	b.add(strings.Repeat("x", bannerMaxBytes-10))
+	b.add(strings.Repeat("y", 20))
+	b.add("short")
+	test.That(t, b.String(), test.ShouldEqual, strings.Repeat("x", bannerMaxBytes-10))
+
+	b = startupBanner{}
+	b.add("kept")
+	b.close()
+	b.add("dropped")
+	test.That(t, b.String(), test.ShouldEqual, "kept")
+}
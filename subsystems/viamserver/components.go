@@ -0,0 +1,127 @@
+package viamserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	errw "github.com/pkg/errors"
+)
+
+const (
+	defaultReadyComponentsPath    = "/components/status"
+	defaultReadyComponentsTimeout = time.Minute * 2
+	readyComponentsInterval       = time.Second
+	// matches every component in the status response
+	allComponents = "*"
+)
+
+// componentsStatus is the response expected from ready_components_path, e.g.
+// {"components": [{"name": "camera1", "ready": true}, {"name": "sensor1", "ready": false}]}.
+type componentsStatus struct {
+	Components []struct {
+		Name  string `json:"name"`
+		Ready bool   `json:"ready"`
+	} `json:"components"`
+}
+
+// fetchComponentsStatus requests the component status from the server at checkURL, authenticated like healthchecks.
+func fetchComponentsStatus(ctx context.Context, cfg *viamServerConfig, checkURL string) (map[string]bool, error) {
+	client, base := healthCheckClient(checkURL)
+	target, err := url.JoinPath(base, cfg.readyComponentsPath)
+	if err != nil {
+		return nil, errw.Wrapf(err, "building component status URL from %s", base)
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckAttemptTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	authHeader, authValue, err := healthCheckAuth(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if authHeader != "" {
+		req.Header.Set(authHeader, authValue)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errw.Wrap(err, "requesting component status")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errw.Errorf("component status returned %s", resp.Status)
+	}
+	var status componentsStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, errw.Wrap(err, "decoding component status")
+	}
+	ret := make(map[string]bool, len(status.Components))
+	for _, c := range status.Components {
+		ret[c.Name] = c.Ready
+	}
+	return ret, nil
+}
+
+// notReadyComponents returns the expected components that aren't (yet) reported as ready, sorted.
+func notReadyComponents(status map[string]bool, expected []string) []string {
+	var ret []string
+	for _, name := range expected {
+		if name == allComponents {
+			for reported, ready := range status {
+				if !ready {
+					ret = append(ret, reported)
+				}
+			}
+			continue
+		}
+		if !status[name] {
+			ret = append(ret, name)
+		}
+	}
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
+
+// checkComponents returns an error naming any of cfg.readyComponents that aren't ready.
+func checkComponents(ctx context.Context, cfg *viamServerConfig, checkURL string) error {
+	status, err := fetchComponentsStatus(ctx, cfg, checkURL)
+	if err != nil {
+		return err
+	}
+	if missing := notReadyComponents(status, cfg.readyComponents); len(missing) > 0 {
+		return errw.Errorf("components not ready: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// waitForComponents polls until cfg.readyComponents are all ready, since viam-server starts serving before its
+// components and modules finish loading. If they aren't within readyComponentsTimeout, it only warns, as a single
+// broken component shouldn't fail the whole start (Readiness keeps reporting it instead.) It returns an error if ctx
+// is cancelled or the process exits first.
+func (s *viamServer) waitForComponents(ctx context.Context, cfg *viamServerConfig, checkURL string, done <-chan struct{}) error {
+	timeout := time.After(cfg.readyComponentsTimeout)
+	for {
+		err := checkComponents(ctx, cfg, checkURL)
+		if err == nil {
+			s.logger.Infof("%s components ready", SubsysName)
+			return nil
+		}
+		s.logger.Debug(err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+			return errw.Errorf("%s exited while waiting for components", SubsysName)
+		case <-timeout:
+			s.logger.Warnf("%s started, but not ready within %s: %v", SubsysName, cfg.readyComponentsTimeout, err)
+			return nil
+		case <-time.After(readyComponentsInterval):
+		}
+	}
+}
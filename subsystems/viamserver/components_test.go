@@ -0,0 +1,64 @@
+package viamserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestNotReadyComponents(t *testing.T) {
+	status := map[string]bool{"camera1": false, "sensor1": true, "arm1": false}
+	test.That(t, notReadyComponents(status, []string{"sensor1"}), test.ShouldBeEmpty)
+	test.That(t, notReadyComponents(status, []string{"camera1", "sensor1", "missing"}), test.ShouldResemble,
+		[]string{"camera1", "missing"})
+	test.That(t, notReadyComponents(status, []string{"*", "camera1"}), test.ShouldResemble, []string{"arm1", "camera1"})
+}
+
+func TestWaitForComponents(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultReadyComponentsPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// the camera takes a few polls to come up
+		cameraReady := requests.Add(1) >= 3
+		//nolint:errcheck
+		json.NewEncoder(w).Encode(map[string]any{"components": []map[string]any{
+			{"name": "camera1", "ready": cameraReady},
+			{"name": "sensor1", "ready": true},
+		}})
+	}))
+	defer srv.Close()
+
+	cfg := &viamServerConfig{
+		readyComponents:        []string{"camera1", "sensor1"},
+		readyComponentsPath:    defaultReadyComponentsPath,
+		readyComponentsTimeout: time.Second * 10,
+	}
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+
+	test.That(t, checkComponents(ctx, cfg, srv.URL), test.ShouldNotBeNil)
+	test.That(t, s.waitForComponents(ctx, cfg, srv.URL, nil), test.ShouldBeNil)
+	test.That(t, requests.Load(), test.ShouldEqual, 3)
+	test.That(t, checkComponents(ctx, cfg, srv.URL), test.ShouldBeNil)
+
+	// never ready only warns
+	cfg.readyComponents = []string{"missing"}
+	cfg.readyComponentsTimeout = time.Millisecond * 100
+	test.That(t, s.waitForComponents(ctx, cfg, srv.URL, nil), test.ShouldBeNil)
+
+	// but exiting fails
+	done := make(chan struct{})
+	close(done)
+	cfg.readyComponentsTimeout = time.Second * 10
+	test.That(t, s.waitForComponents(ctx, cfg, srv.URL, done), test.ShouldNotBeNil)
+}
@@ -0,0 +1,60 @@
+package viamserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+)
+
+// ErrConfigTampered is returned by Start after ConfigFilePath was modified while viam-server was running, and
+// stop_on_config_tamper is set. Start fails until ClearFailureState is called, once the file has been checked.
+var ErrConfigTampered = errw.New("config file modified while running")
+
+// watchConfigIntegrity re-hashes ConfigFilePath every interval until done is closed, and raises an alert if it no
+// longer matches configHash (taken when viam-server was started.) The agent only writes the file from a ConfigStore,
+// which updates configHash along with it, so any other change is unexpected.
+func (s *viamServer) watchConfigIntegrity(cfg *viamServerConfig, done <-chan struct{}) {
+	ticker := time.NewTicker(cfg.configIntegrityInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		// hashed under the lock, so a write from the config store is never half seen
+		s.mu.Lock()
+		hash, err := agent.GetFileSum(ConfigFilePath)
+		expected := s.configHash
+		s.mu.Unlock()
+		if err != nil {
+			s.logger.Warn(errw.Wrapf(err, "checking integrity of %s", ConfigFilePath))
+			continue
+		}
+		if bytes.Equal(hash, expected) {
+			continue
+		}
+		s.logger.Errorw("SECURITY ALERT: config file was modified while "+SubsysName+" was running",
+			"path", ConfigFilePath, "expected_sha256", hex.EncodeToString(expected), "sha256", hex.EncodeToString(hash))
+		s.mu.Lock()
+		if cfg.stopOnConfigTamper {
+			s.configTampered = true
+		} else {
+			// only alert once per change
+			s.configHash = hash
+		}
+		s.mu.Unlock()
+		if !cfg.stopOnConfigTamper {
+			continue
+		}
+		s.logger.Errorf("stopping %s, it will not be restarted until its failure state is cleared", SubsysName)
+		if err := s.Stop(context.Background()); err != nil {
+			s.logger.Error(errw.Wrapf(err, "stopping %s after config modification", SubsysName))
+		}
+		return
+	}
+}
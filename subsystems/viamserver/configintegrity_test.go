@@ -0,0 +1,50 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestConfigIntegrity(t *testing.T) {
+	fakeViamServer(t)
+	prevPath := ConfigFilePath
+	t.Cleanup(func() { ConfigFilePath = prevPath })
+	ConfigFilePath = filepath.Join(t.TempDir(), "viam.json")
+	test.That(t, os.WriteFile(ConfigFilePath, []byte(`{"cloud": {}}`), 0o600), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := *prevCfg
+	cfg.configIntegrityInterval = time.Millisecond * 20
+	cfg.stopOnConfigTamper = true
+	globalConfig.Store(&cfg)
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	// an unchanged file is left alone
+	time.Sleep(time.Millisecond * 100)
+	s.mu.Lock()
+	test.That(t, s.running, test.ShouldBeTrue)
+	s.mu.Unlock()
+
+	test.That(t, os.WriteFile(ConfigFilePath, []byte(`{"cloud": {"id": "other"}}`), 0o600), test.ShouldBeNil)
+	select {
+	case <-s.exitChan:
+	case <-time.After(time.Second * 10):
+		t.Fatal("not stopped after the config changed")
+	}
+	err := s.Start(ctx)
+	test.That(t, errors.Is(err, ErrConfigTampered), test.ShouldBeTrue)
+
+	s.ClearFailureState()
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
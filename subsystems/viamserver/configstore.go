@@ -0,0 +1,233 @@
+package viamserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+	"github.com/viamrobotics/agent/subsystems"
+	"github.com/viamrobotics/agent/subsystems/registry"
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+)
+
+// how often FileConfigStore.Watch checks the file, when Interval isn't set.
+const defaultConfigStorePollInterval = time.Second * 10
+
+// ConfigStore is where viam-server's config comes from, when that isn't just ConfigFilePath. viam-server itself only
+// reads a file, so the store's contents are written to ConfigFilePath before each start, and again whenever Watch
+// delivers new contents (viam-server reloads its config file when it changes.)
+type ConfigStore interface {
+	Read(ctx context.Context) ([]byte, error)
+	Write(ctx context.Context, data []byte) error
+	// Watch returns a channel that receives the full contents after each change, until ctx is done.
+	Watch(ctx context.Context) (<-chan []byte, error)
+}
+
+// Option configures a viam-server subsystem created by NewSubsystemWithOptions.
+type Option func(*viamServer)
+
+// WithConfigStore makes viam-server's config come from store.
+func WithConfigStore(store ConfigStore) Option {
+	return func(s *viamServer) {
+		s.configStore = store
+	}
+}
+
+// WithManifest sets where verify_manifest checks the binary against, instead of the subsystem's own install cache.
+func WithManifest(manifest agent.Manifest) Option {
+	return func(s *viamServer) {
+		s.manifest = manifest
+	}
+}
+
+// NewSubsystemWithOptions returns a creator like NewSubsystem, applying opts to each subsystem it creates. Register it
+// in place of the default to use them, e.g.
+// registry.Register(SubsysName, NewSubsystemWithOptions(WithConfigStore(store)), DefaultConfig).
+func NewSubsystemWithOptions(opts ...Option) registry.CreatorFunc {
+	return func(ctx context.Context, logger logging.Logger, updateConf *pb.DeviceSubsystemConfig) (subsystems.Subsystem, error) {
+		return newSubsystem(ctx, logger, updateConf, opts...)
+	}
+}
+
+// FileConfigStore keeps the config in a file, polled for changes every Interval.
+type FileConfigStore struct {
+	Path     string
+	Interval time.Duration
+}
+
+func (f *FileConfigStore) Read(ctx context.Context) ([]byte, error) {
+	//nolint:gosec
+	return os.ReadFile(f.Path)
+}
+
+func (f *FileConfigStore) Write(ctx context.Context, data []byte) error {
+	return writeFileAtomic(f.Path, data)
+}
+
+func (f *FileConfigStore) Watch(ctx context.Context) (<-chan []byte, error) {
+	last, err := f.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	interval := f.Interval
+	if interval <= 0 {
+		interval = defaultConfigStorePollInterval
+	}
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			data, err := f.Read(ctx)
+			if err != nil || bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+			select {
+			case ch <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// InMemoryConfigStore keeps the config in memory, mostly for tests.
+type InMemoryConfigStore struct {
+	mu       sync.Mutex
+	data     []byte
+	watchers map[chan []byte]struct{}
+}
+
+// NewInMemoryConfigStore returns an InMemoryConfigStore holding data.
+func NewInMemoryConfigStore(data []byte) *InMemoryConfigStore {
+	return &InMemoryConfigStore{data: data, watchers: make(map[chan []byte]struct{})}
+}
+
+func (m *InMemoryConfigStore) Read(ctx context.Context) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil, os.ErrNotExist
+	}
+	return bytes.Clone(m.data), nil
+}
+
+func (m *InMemoryConfigStore) Write(ctx context.Context, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data = bytes.Clone(data)
+	for ch := range m.watchers {
+		// only the latest contents matter to a slow watcher
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- bytes.Clone(data)
+	}
+	return nil
+}
+
+func (m *InMemoryConfigStore) Watch(ctx context.Context) (<-chan []byte, error) {
+	ch := make(chan []byte, 1)
+	m.mu.Lock()
+	m.watchers[ch] = struct{}{}
+	m.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.watchers, ch)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// writeFileAtomic replaces path with data, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) (errRet error) {
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errRet != nil {
+			errRet = errors.Join(errRet, os.Remove(out.Name()))
+		}
+	}()
+	if _, err := out.Write(data); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	if err := out.Chmod(0o600); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), path)
+}
+
+// syncConfigFromStore writes data to ConfigFilePath if it differs, keeping configHash in step so the write isn't
+// reported by watchConfigIntegrity.
+func (s *viamServer) syncConfigFromStore(data []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	//nolint:gosec
+	current, err := os.ReadFile(ConfigFilePath)
+	if err == nil && bytes.Equal(current, data) {
+		return false, nil
+	}
+	if err := writeFileAtomic(ConfigFilePath, data); err != nil {
+		return false, errw.Wrapf(err, "writing %s", ConfigFilePath)
+	}
+	if s.configHash != nil {
+		sum := sha256.Sum256(data)
+		s.configHash = sum[:]
+	}
+	return true, nil
+}
+
+// watchConfigStore copies changes from the config store to ConfigFilePath until done is closed.
+func (s *viamServer) watchConfigStore(done <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+	updates, err := s.configStore.Watch(ctx)
+	if err != nil {
+		s.logger.Error(errw.Wrap(err, "watching config store, changes won't be applied until restart"))
+		return
+	}
+	// catch a change between Start's read and the watch beginning
+	if data, err := s.configStore.Read(ctx); err == nil {
+		if _, err := s.syncConfigFromStore(data); err != nil {
+			s.logger.Error(err)
+		}
+	}
+	for data := range updates {
+		changed, err := s.syncConfigFromStore(data)
+		if err != nil {
+			s.logger.Error(err)
+			continue
+		}
+		if changed {
+			s.logger.Infof("updated %s from the config store", ConfigFilePath)
+		}
+	}
+}
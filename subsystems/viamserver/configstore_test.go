@@ -0,0 +1,96 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestFileConfigStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &FileConfigStore{Path: filepath.Join(t.TempDir(), "viam.json"), Interval: time.Millisecond * 10}
+	test.That(t, store.Write(ctx, []byte("one")), test.ShouldBeNil)
+	data, err := store.Read(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(data), test.ShouldEqual, "one")
+
+	updates, err := store.Watch(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, store.Write(ctx, []byte("two")), test.ShouldBeNil)
+	select {
+	case data := <-updates:
+		test.That(t, string(data), test.ShouldEqual, "two")
+	case <-time.After(time.Second * 5):
+		t.Fatal("no update after writing")
+	}
+	cancel()
+	//nolint:revive
+	for range updates {
+	}
+}
+
+func TestInMemoryConfigStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	store := NewInMemoryConfigStore([]byte("one"))
+	updates, err := store.Watch(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	// a watcher that falls behind only gets the latest
+	test.That(t, store.Write(ctx, []byte("two")), test.ShouldBeNil)
+	test.That(t, store.Write(ctx, []byte("three")), test.ShouldBeNil)
+	test.That(t, string(<-updates), test.ShouldEqual, "three")
+	data, err := store.Read(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(data), test.ShouldEqual, "three")
+
+	cancel()
+	_, ok := <-updates
+	test.That(t, ok, test.ShouldBeFalse)
+}
+
+func TestStartConfigStore(t *testing.T) {
+	fakeViamServer(t)
+	prevPath := ConfigFilePath
+	t.Cleanup(func() { ConfigFilePath = prevPath })
+	ConfigFilePath = filepath.Join(t.TempDir(), "viam.json")
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := *prevCfg
+	// changes from the store aren't tampering
+	cfg.configIntegrityInterval = time.Millisecond * 10
+	cfg.stopOnConfigTamper = true
+	globalConfig.Store(&cfg)
+
+	ctx := context.Background()
+	store := NewInMemoryConfigStore([]byte(`{"cloud": {}}`))
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	WithConfigStore(store)(s)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	defer func() { test.That(t, s.Stop(ctx), test.ShouldBeNil) }()
+	raw, err := os.ReadFile(ConfigFilePath)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(raw), test.ShouldEqual, `{"cloud": {}}`)
+
+	test.That(t, store.Write(ctx, []byte(`{"cloud": {"id": "new"}}`)), test.ShouldBeNil)
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		raw, err = os.ReadFile(ConfigFilePath)
+		test.That(t, err, test.ShouldBeNil)
+		if string(raw) == `{"cloud": {"id": "new"}}` {
+			break
+		}
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond * 10)
+	}
+	time.Sleep(time.Millisecond * 100)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	test.That(t, s.running, test.ShouldBeTrue)
+	test.That(t, s.configTampered, test.ShouldBeFalse)
+}
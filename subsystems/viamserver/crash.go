@@ -0,0 +1,68 @@
+package viamserver
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// signals that mean the process crashed, rather than being asked to exit.
+var crashSignals = []syscall.Signal{syscall.SIGSEGV, syscall.SIGABRT, syscall.SIGBUS}
+
+// the first line the go runtime writes to stderr on an unrecovered panic or fatal error.
+var goPanicRegex = regexp.MustCompile(`^(panic: |fatal error: )`)
+
+// panicWatcher remembers the first go panic line seen on the process's stderr.
+type panicWatcher struct {
+	mu   sync.Mutex
+	line string
+}
+
+func (w *panicWatcher) observe(line string) {
+	if !goPanicRegex.MatchString(line) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.line == "" {
+		w.line = strings.TrimSpace(line)
+	}
+}
+
+func (w *panicWatcher) panicLine() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.line
+}
+
+// exitSignal returns the signal that terminated the process, if it was terminated by one.
+func exitSignal(state *os.ProcessState) (syscall.Signal, bool) {
+	if state == nil {
+		return 0, false
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return 0, false
+	}
+	return status.Signal(), true
+}
+
+// crashReason describes why an exit was a crash (a fatal signal, or a go panic), or returns "" if it wasn't one.
+func crashReason(state *os.ProcessState, panicLine string) string {
+	if state == nil {
+		return ""
+	}
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() && slices.Contains(crashSignals, status.Signal()) {
+		return fmt.Sprintf("killed by %s", unix.SignalName(status.Signal()))
+	}
+	if state.ExitCode() != 0 && panicLine != "" {
+		return panicLine
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func exitState(t *testing.T, script string) *os.ProcessState {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	//nolint:errcheck
+	cmd.Run()
+	test.That(t, cmd.ProcessState, test.ShouldNotBeNil)
+	return cmd.ProcessState
+}
+
+func TestCrashReason(t *testing.T) {
+	test.That(t, crashReason(exitState(t, "kill -SEGV $$"), ""), test.ShouldEqual, "killed by SIGSEGV")
+	test.That(t, crashReason(exitState(t, "kill -ABRT $$"), ""), test.ShouldEqual, "killed by SIGABRT")
+	// asked to exit, not a crash
+	test.That(t, crashReason(exitState(t, "kill -TERM $$"), ""), test.ShouldEqual, "")
+	test.That(t, crashReason(exitState(t, "exit 2"), ""), test.ShouldEqual, "")
+	test.That(t, crashReason(exitState(t, "exit 2"), "panic: boom"), test.ShouldEqual, "panic: boom")
+	test.That(t, crashReason(exitState(t, "exit 0"), "panic: boom"), test.ShouldEqual, "")
+	test.That(t, crashReason(nil, "panic: boom"), test.ShouldEqual, "")
+
+	w := &panicWatcher{}
+	w.observe("not a panic: really")
+	w.observe("panic: runtime error: invalid memory address or nil pointer dereference\n")
+	w.observe("panic: second")
+	test.That(t, w.panicLine(), test.ShouldEqual, "panic: runtime error: invalid memory address or nil pointer dereference")
+}
+
+func TestCrashHealthCheck(t *testing.T) {
+	binPath := fakeViamServer(t)
+	script := "#!/bin/sh\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"sleep 0.2\necho 'panic: boom' >&2\nexit 2\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	select {
+	case <-s.exitChan:
+	case <-time.After(time.Second * 10):
+		t.Fatal("process didn't exit")
+	}
+	err := s.HealthCheck(ctx)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "crashed: panic: boom")
+}
+
+func TestExitSignal(t *testing.T) {
+	sig, ok := exitSignal(exitState(t, "kill -KILL $$"))
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, sig, test.ShouldEqual, syscall.SIGKILL)
+	_, ok = exitSignal(exitState(t, "exit 137"))
+	test.That(t, ok, test.ShouldBeFalse)
+	_, ok = exitSignal(nil)
+	test.That(t, ok, test.ShouldBeFalse)
+
+	fakeViamServer(t)
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	_, ok = s.LastExitSignal()
+	test.That(t, ok, test.ShouldBeFalse)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.cmd.Process.Signal(syscall.SIGKILL), test.ShouldBeNil)
+	select {
+	case <-s.exitChan:
+	case <-time.After(time.Second * 10):
+		t.Fatal("process didn't exit")
+	}
+	sig, ok = s.LastExitSignal()
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, sig, test.ShouldEqual, syscall.SIGKILL)
+	err := s.HealthCheck(ctx)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "killed by SIGKILL")
+}
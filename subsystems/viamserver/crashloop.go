@@ -0,0 +1,79 @@
+package viamserver
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/viamrobotics/agent/subsystems"
+)
+
+const defaultCrashLoopWindow = time.Minute * 5
+
+// ErrCrashLooping is returned (as a *CrashLoopError) by Start once viam-server has exited unexpectedly
+// crash_loop_threshold times within crash_loop_window. Start fails immediately, without trying to launch, until
+// ClearFailureState is called, so supervisors should treat it as terminal rather than retrying Start. The manager
+// clears it once the config changes.
+var ErrCrashLooping = subsystems.ErrCrashLooping
+
+// ExitRecord is a single unexpected exit of viam-server.
+type ExitRecord struct {
+	Time  time.Time
+	Code  int
+	Crash string
+	// last known /proc/<pid>/status fields, if status_capture_interval is set
+	Status map[string]string
+}
+
+// CrashLoopError carries the recent exits that caused viam-server to be considered crash looping.
+type CrashLoopError struct {
+	Exits []ExitRecord
+}
+
+func (e *CrashLoopError) Error() string {
+	exits := make([]string, 0, len(e.Exits))
+	for _, exit := range e.Exits {
+		desc := fmt.Sprintf("code %d at %s", exit.Code, exit.Time.Format(time.RFC3339))
+		if exit.Crash != "" {
+			desc += fmt.Sprintf(" (%s)", exit.Crash)
+		}
+		exits = append(exits, desc)
+	}
+	return fmt.Sprintf("%s %s, not starting until cleared, recent exits: %s", SubsysName, ErrCrashLooping, strings.Join(exits, ", "))
+}
+
+// Unwrap allows errors.Is(err, ErrCrashLooping).
+func (e *CrashLoopError) Unwrap() error {
+	return ErrCrashLooping
+}
+
+// recordUnexpectedExit adds an exit to the recent history, and marks viam-server as crash looping if there have been
+// too many within the window. Must be called with mu held.
+func (s *viamServer) recordUnexpectedExit(cfg *viamServerConfig, exit ExitRecord) {
+	if cfg.crashLoopThreshold <= 0 {
+		return
+	}
+	s.recentExits = slices.DeleteFunc(s.recentExits, func(prev ExitRecord) bool {
+		return exit.Time.Sub(prev.Time) > cfg.crashLoopWindow
+	})
+	s.recentExits = append(s.recentExits, exit)
+	if len(s.recentExits) >= cfg.crashLoopThreshold && !s.crashLooping {
+		s.crashLooping = true
+		s.logger.Errorf("%s exited unexpectedly %d times within %s, it will not be restarted until its failure state is cleared",
+			SubsysName, len(s.recentExits), cfg.crashLoopWindow)
+		if cfg.configQuarantine {
+			s.quarantineConfig(cfg)
+		}
+	}
+}
+
+// ClearFailureState forgets the recent exit history and any config modification, allowing Start to launch viam-server
+// again after crash looping or ErrConfigTampered.
+func (s *viamServer) ClearFailureState() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.crashLooping = false
+	s.recentExits = nil
+	s.configTampered = false
+}
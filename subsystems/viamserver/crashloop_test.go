@@ -0,0 +1,57 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestCrashLooping(t *testing.T) {
+	binPath := fakeViamServer(t)
+	script := "#!/bin/sh\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"sleep 0.5\nexit 3\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:       time.Second * 10,
+		launchTimeout:      defaultLaunchTimeout,
+		crashLoopThreshold: 2,
+		crashLoopWindow:    time.Minute,
+	})
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	for i := 0; i < 2; i++ {
+		test.That(t, s.Start(ctx), test.ShouldBeNil)
+		<-s.exitChan
+	}
+
+	// fails immediately with the exit history, without launching
+	prevCmd := s.cmd
+	err := s.Start(ctx)
+	test.That(t, errors.Is(err, ErrCrashLooping), test.ShouldBeTrue)
+	var loopErr *CrashLoopError
+	test.That(t, errors.As(err, &loopErr), test.ShouldBeTrue)
+	test.That(t, loopErr.Exits, test.ShouldHaveLength, 2)
+	test.That(t, loopErr.Exits[1].Code, test.ShouldEqual, 3)
+	test.That(t, s.cmd, test.ShouldEqual, prevCmd)
+
+	s.ClearFailureState()
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+
+	// stops aren't counted
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
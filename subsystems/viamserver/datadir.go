@@ -0,0 +1,65 @@
+package viamserver
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// permissions for a data_dir created by the agent, when data_dir_mode isn't set.
+const defaultDataDirMode fs.FileMode = 0o700
+
+// helper to parse an octal permission string like "0750", otherwise return the default.
+func fileModeFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string, defaultValue fs.FileMode) fs.FileMode {
+	str := stringFromProtoStruct(logger, protoStruct, key, "")
+	if str == "" {
+		return defaultValue
+	}
+	mode, err := strconv.ParseUint(str, 8, 32)
+	if err != nil || mode > uint64(fs.ModePerm) {
+		logger.Warnf("invalid permissions at %s: %s", key, str)
+		return defaultValue
+	}
+	return fs.FileMode(mode)
+}
+
+// resolveDataDir makes a relative data_dir relative to ViamDirs["viam"].
+func resolveDataDir(dir string) string {
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(agent.ViamDirs["viam"], dir)
+}
+
+// ensureDataDir creates dir with mode if it doesn't exist, and verifies it's a directory (or a symlink to one.) The
+// permissions of an existing directory are left alone, with a warning if they differ.
+func ensureDataDir(logger logging.Logger, dir string, mode fs.FileMode) error {
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(dir, mode); err != nil {
+			return errw.Wrapf(err, "creating %s data dir", SubsysName)
+		}
+		// MkdirAll's permissions are subject to the umask
+		if err := os.Chmod(dir, mode); err != nil {
+			return errw.Wrapf(err, "setting permissions of %s data dir", SubsysName)
+		}
+		return nil
+	}
+	if err != nil {
+		return errw.Wrapf(err, "checking %s data dir", SubsysName)
+	}
+	if !info.IsDir() {
+		return errw.Errorf("%s data dir %s is not a directory", SubsysName, dir)
+	}
+	if info.Mode().Perm() != mode {
+		logger.Warnf("%s data dir %s has permissions %#o, not the configured %#o", SubsysName, dir, info.Mode().Perm(), mode)
+	}
+	return nil
+}
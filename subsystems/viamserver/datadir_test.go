@@ -0,0 +1,68 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestEnsureDataDir(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	root := t.TempDir()
+
+	dir := filepath.Join(root, "instance", "data")
+	test.That(t, ensureDataDir(logger, dir, 0o750), test.ShouldBeNil)
+	info, err := os.Stat(dir)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, info.IsDir(), test.ShouldBeTrue)
+	test.That(t, info.Mode().Perm(), test.ShouldEqual, os.FileMode(0o750))
+	// existing is fine
+	test.That(t, ensureDataDir(logger, dir, 0o700), test.ShouldBeNil)
+
+	file := filepath.Join(root, "file")
+	test.That(t, os.WriteFile(file, nil, 0o600), test.ShouldBeNil)
+	test.That(t, ensureDataDir(logger, file, 0o700), test.ShouldNotBeNil)
+
+	attrs, err := structpb.NewStruct(map[string]any{"good": "0750", "bad": "0999"})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, fileModeFromProtoStruct(logger, attrs, "good", 0o700), test.ShouldEqual, os.FileMode(0o750))
+	test.That(t, fileModeFromProtoStruct(logger, attrs, "bad", 0o700), test.ShouldEqual, os.FileMode(0o700))
+	test.That(t, fileModeFromProtoStruct(logger, attrs, "missing", 0o700), test.ShouldEqual, os.FileMode(0o700))
+}
+
+func TestStartDataDir(t *testing.T) {
+	binPath := fakeViamServer(t)
+	out := filepath.Join(t.TempDir(), "env")
+	script := "#!/bin/sh\n" +
+		`echo "$PWD $HOME" > ` + out + "\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"exec sleep 30\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := *prevCfg
+	cfg.startTimeout = time.Second * 10
+	cfg.dataDir = "instance1"
+	cfg.dataDirMode = defaultDataDirMode
+	globalConfig.Store(&cfg)
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	defer func() { test.That(t, s.Stop(ctx), test.ShouldBeNil) }()
+
+	dataDir := filepath.Join(agent.ViamDirs["viam"], "instance1")
+	raw, err := os.ReadFile(out)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, strings.TrimSpace(string(raw)), test.ShouldEqual, dataDir+" "+dataDir)
+}
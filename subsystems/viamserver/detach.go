@@ -0,0 +1,41 @@
+package viamserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	errw "github.com/pkg/errors"
+)
+
+// how long to look for something still serving the healthcheck endpoint after viam-server exits cleanly.
+const detachCheckTimeout = time.Second
+
+// ErrProcessDetached is returned when viam-server's process exited, but left something running in its place (such
+// as a wrapper script that double-forks), which the agent can't track.
+var ErrProcessDetached = errw.New("process detached")
+
+// detectDetached describes why it looks like the exited process carries on through another, or returns "" if it
+// doesn't. That's only when it exited cleanly but checkURL is still being served, as a crash or kill can leave module
+// processes behind in its group without anything having detached.
+func detectDetached(ctx context.Context, state *os.ProcessState, checkURL string) string {
+	if state == nil || !state.Success() {
+		return ""
+	}
+	if network, addr, ok := endpointAddr(checkURL); ok && stillListening(ctx, network, addr, detachCheckTimeout) {
+		return fmt.Sprintf("something is still listening on %s", addr)
+	}
+	return ""
+}
+
+// checkDetached is called after an unexpected exit, and logs loudly if the process detached. It doesn't kill anything,
+// that's left to the stop path (see killGroupMembers.)
+func (s *viamServer) checkDetached(state *os.ProcessState, checkURL string) string {
+	reason := detectDetached(context.Background(), state, checkURL)
+	if reason != "" {
+		s.logger.Errorw(fmt.Sprintf("%s exited, but appears to have detached (for example by double-forking), so it can't be tracked",
+			SubsysName), "reason", reason)
+	}
+	return reason
+}
@@ -0,0 +1,84 @@
+package viamserver
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestDetectDetached(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.That(t, err, test.ShouldBeNil)
+	target := "http://" + listener.Addr().String()
+
+	clean := exec.Command("true")
+	test.That(t, clean.Run(), test.ShouldBeNil)
+	failed := exec.Command("false")
+	test.That(t, failed.Run(), test.ShouldNotBeNil)
+
+	test.That(t, detectDetached(ctx, clean.ProcessState, ""), test.ShouldEqual, "")
+	test.That(t, detectDetached(ctx, clean.ProcessState, target), test.ShouldContainSubstring, "still listening")
+	// a failed exit never counts, even if the endpoint is still served
+	test.That(t, detectDetached(ctx, failed.ProcessState, target), test.ShouldEqual, "")
+	test.That(t, detectDetached(ctx, nil, target), test.ShouldEqual, "")
+	test.That(t, listener.Close(), test.ShouldBeNil)
+	test.That(t, detectDetached(ctx, clean.ProcessState, target), test.ShouldEqual, "")
+}
+
+func TestDetachedExit(t *testing.T) {
+	// stands in for the detached server, still serving after the wrapper exits
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.That(t, err, test.ShouldBeNil)
+	defer listener.Close()
+	url := "http://" + listener.Addr().String()
+
+	binPath := fakeViamServer(t)
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		exitCode string
+		detached bool
+	}{
+		{"clean exit", "0", true},
+		// e.g. a crash that leaves module processes in the group
+		{"crash", "1", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			script := "#!/bin/sh\n(sleep 5 >/dev/null 2>&1 &)\n" +
+				`echo 'serving {"url": "` + url + `", "alt_url": "` + url + `"}'` + "\n" +
+				"sleep 0.2\nexit " + tc.exitCode + "\n"
+			//nolint:gosec
+			test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+			s := &viamServer{logger: logging.NewTestLogger(t)}
+			test.That(t, s.Start(ctx), test.ShouldBeNil)
+			s.mu.Lock()
+			exitChan := s.exitChan
+			s.mu.Unlock()
+			select {
+			case <-exitChan:
+			case <-time.After(time.Second * 10):
+				t.Fatal("timed out waiting for exit")
+			}
+			s.mu.Lock()
+			detached := s.detached
+			s.mu.Unlock()
+			if tc.detached {
+				test.That(t, detached, test.ShouldContainSubstring, "still listening")
+			} else {
+				test.That(t, detached, test.ShouldEqual, "")
+			}
+			test.That(t, s.Stop(ctx), test.ShouldBeNil)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package viamserver
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+)
+
+const defaultExecHealthCheckTimeout = time.Second * 10
+
+// NewExecHealthCheck returns a HealthChecker that runs an operator's own check command (argv), such as a script for
+// custom hardware. The target is passed as VIAM_CHECK_URL and pid as VIAM_PID. It's healthy if the command exits zero
+// within timeout, otherwise the first line of its stdout is included in the error.
+func NewExecHealthCheck(argv []string, timeout time.Duration, pid int) HealthChecker {
+	return HealthCheckerFunc(func(ctx context.Context, target string) error {
+		if len(argv) == 0 {
+			return errw.New("no exec healthcheck command")
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		//nolint:gosec
+		cmd := exec.CommandContext(timeoutCtx, argv[0], argv[1:]...)
+		cmd.Env = append(os.Environ(), "VIAM_CHECK_URL="+target, "VIAM_PID="+strconv.Itoa(pid))
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error {
+			return agent.KillProcessGroup(cmd.Process.Pid, syscall.SIGKILL)
+		}
+		cmd.WaitDelay = probeWaitDelay
+
+		out, err := cmd.Output()
+		if err == nil {
+			return nil
+		}
+		firstLine, _, _ := bytes.Cut(out, []byte("\n"))
+		if msg := strings.TrimSpace(string(firstLine)); msg != "" {
+			return errw.Wrapf(err, "exec healthcheck failed: %s", msg)
+		}
+		return errw.Wrap(err, "exec healthcheck failed")
+	})
+}
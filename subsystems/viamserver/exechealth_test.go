@@ -0,0 +1,95 @@
+package viamserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func writeCheckScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "check.sh")
+	test.That(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o600), test.ShouldBeNil)
+	//nolint:gosec
+	test.That(t, os.Chmod(path, 0o755), test.ShouldBeNil)
+	return path
+}
+
+func TestExecHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	envFile := filepath.Join(t.TempDir(), "env")
+	healthy := writeCheckScript(t, `echo "$VIAM_CHECK_URL $VIAM_PID" > `+envFile+"\n")
+	test.That(t, NewExecHealthCheck([]string{healthy}, time.Second, 1234).Check(ctx, "http://localhost:8080"),
+		test.ShouldBeNil)
+	//nolint:gosec
+	env, err := os.ReadFile(envFile)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(env), test.ShouldEqual, "http://localhost:8080 1234\n")
+
+	unhealthy := writeCheckScript(t, "echo 'motor controller not responding'\necho details\nexit 2\n")
+	err = NewExecHealthCheck([]string{unhealthy}, time.Second, 1234).Check(ctx, "http://localhost:8080")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "motor controller not responding")
+	test.That(t, err.Error(), test.ShouldNotContainSubstring, "details")
+
+	hangs := writeCheckScript(t, "exec sleep 30\n")
+	start := time.Now()
+	test.That(t, NewExecHealthCheck([]string{hangs}, time.Millisecond*100, 1234).Check(ctx, "http://localhost:8080"),
+		test.ShouldNotBeNil)
+	test.That(t, time.Since(start), test.ShouldBeLessThan, time.Second*5)
+}
+
+func TestHealthCheckExec(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+
+	globalConfig.Store(&viamServerConfig{execHealthCheck: []string{writeCheckScript(t, "exit 0\n")}, execHealthCheckTimeout: time.Second})
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+
+	globalConfig.Store(&viamServerConfig{execHealthCheck: []string{writeCheckScript(t, "echo bad sensor\nexit 1\n")}, execHealthCheckTimeout: time.Second})
+	err := s.HealthCheck(ctx)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "bad sensor")
+}
+
+func TestHealthCheckExecUnlocked(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	started := filepath.Join(t.TempDir(), "started")
+	globalConfig.Store(&viamServerConfig{
+		execHealthCheck:        []string{writeCheckScript(t, "touch "+started+"\nexec sleep 30\n")},
+		execHealthCheckTimeout: time.Second * 2,
+	})
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+
+	done := make(chan error, 1)
+	go func() { done <- s.HealthCheck(context.Background()) }()
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// the command is still running, but doesn't hold up anything that needs the locks
+	start := time.Now()
+	s.LastStartKind()
+	test.That(t, s.Stop(context.Background()), test.ShouldBeNil)
+	test.That(t, time.Since(start), test.ShouldBeLessThan, time.Second)
+	test.That(t, <-done, test.ShouldNotBeNil)
+}
@@ -0,0 +1,34 @@
+package viamserver
+
+import (
+	"os"
+	"strings"
+
+	"go.viam.com/rdk/logging"
+)
+
+// ExtraArgsEnv holds space-separated arguments appended to viam-server's command line. It's intended for development,
+// such as adding flags from configuration management without editing the agent config, not for production use.
+const ExtraArgsEnv = "VIAMSERVER_ARGS_EXTRA"
+
+// extra arguments may not point viam-server at a different config or plugins.
+var disallowedExtraArgPrefixes = []string{"--config", "-config", "--plugin-dir", "-plugin-dir"}
+
+// extraArgsFromEnv returns the arguments from ExtraArgsEnv. If any is disallowed, they're all ignored, as dropping
+// only the flag would leave its value behind as a stray argument.
+func extraArgsFromEnv(logger logging.Logger) []string {
+	args := strings.Fields(os.Getenv(ExtraArgsEnv))
+	if len(args) == 0 {
+		return nil
+	}
+	for _, arg := range args {
+		for _, prefix := range disallowedExtraArgPrefixes {
+			if strings.HasPrefix(arg, prefix) {
+				logger.Warnf("ignoring %s, as it contains the disallowed argument %q", ExtraArgsEnv, arg)
+				return nil
+			}
+		}
+	}
+	logger.Debugf("appending extra %s arguments from %s: %v", SubsysName, ExtraArgsEnv, args)
+	return args
+}
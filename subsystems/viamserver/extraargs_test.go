@@ -0,0 +1,28 @@
+package viamserver
+
+import (
+	"context"
+	"testing"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestExtraArgsFromEnv(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	t.Setenv(ExtraArgsEnv, "")
+	test.That(t, extraArgsFromEnv(logger), test.ShouldBeNil)
+
+	for _, disallowed := range []string{"--config=/tmp/evil.json", "-config /tmp/evil.json", "--plugin-dir /tmp", "-plugin-dir=/tmp"} {
+		t.Setenv(ExtraArgsEnv, "-debug "+disallowed)
+		test.That(t, extraArgsFromEnv(logger), test.ShouldBeNil)
+	}
+
+	binPath := fakeViamServer(t)
+	t.Setenv(ExtraArgsEnv, "  -debug   -tunnel ")
+	ctx := context.Background()
+	s := &viamServer{logger: logger}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	defer func() { test.That(t, s.Stop(ctx), test.ShouldBeNil) }()
+	test.That(t, s.cmd.Args, test.ShouldResemble, []string{binPath, "-config", ConfigFilePath, "-debug", "-tunnel"})
+}
@@ -0,0 +1,101 @@
+package viamserver
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ErrStartupFatal is returned when viam-server logs one of the startup_fatal_patterns before it's serving.
+var ErrStartupFatal = errw.New("fatal startup error")
+
+// StartupFatalError names the fatal condition matched during startup, and the line that matched it.
+type StartupFatalError struct {
+	Reason string
+	Line   string
+}
+
+func (e *StartupFatalError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", ErrStartupFatal, e.Reason, e.Line)
+}
+
+func (e *StartupFatalError) Unwrap() error {
+	return ErrStartupFatal
+}
+
+// fatalPattern is a log line that means viam-server can't start, so there's no point waiting for startTimeout.
+type fatalPattern struct {
+	regex  *regexp.Regexp
+	reason string
+}
+
+// fatalPatternsFromProtoStruct parses a map of regex to reason, like {"license (invalid|expired)": "license_invalid"},
+// skipping (and warning about) invalid entries.
+func fatalPatternsFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string) []fatalPattern {
+	if protoStruct == nil {
+		return nil
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return nil
+	}
+	patterns, ok := raw.(map[string]any)
+	if !ok {
+		logger.Warnf("invalid %s: %v", key, raw)
+		return nil
+	}
+	ret := make([]fatalPattern, 0, len(patterns))
+	for expr, rawReason := range patterns {
+		reason, ok := rawReason.(string)
+		regex, err := regexp.Compile(expr)
+		if !ok || reason == "" || err != nil {
+			logger.Warnf("invalid %s entry %q: %v", key, expr, rawReason)
+			continue
+		}
+		ret = append(ret, fatalPattern{regex: regex, reason: reason})
+	}
+	// map order is random, but the first pattern to match should be consistent
+	sort.Slice(ret, func(i, j int) bool { return ret[i].regex.String() < ret[j].regex.String() })
+	return ret
+}
+
+// watchFatalPatterns adds a matcher for each pattern to each logger. The returned channel receives the first match.
+// The returned func removes the matchers, and must be called on every path out of Start.
+func watchFatalPatterns(patterns []fatalPattern, loggers ...*agent.MatchingLogger) (<-chan *StartupFatalError, func(), error) {
+	fatalChan := make(chan *StartupFatalError, 1)
+	var added []func()
+	cleanup := func() {
+		for _, del := range added {
+			del()
+		}
+	}
+	for i, pattern := range patterns {
+		name := "fatal" + strconv.Itoa(i)
+		for _, logger := range loggers {
+			logger := logger
+			c, err := logger.AddMatcher(name, pattern.regex, false)
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+			added = append(added, func() { logger.DeleteMatcher(name) })
+			// drained until the matcher is deleted, so logging never blocks on it
+			go func(reason string) {
+				for matches := range c {
+					select {
+					case fatalChan <- &StartupFatalError{Reason: reason, Line: strings.TrimSpace(matches[0])}:
+					default:
+					}
+				}
+			}(pattern.reason)
+		}
+	}
+	return fatalChan, cleanup, nil
+}
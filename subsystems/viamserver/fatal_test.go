@@ -0,0 +1,69 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFatalPatternsFromProtoStruct(t *testing.T) {
+	attrs, err := structpb.NewStruct(map[string]any{
+		"startup_fatal_patterns": map[string]any{
+			"license (invalid|expired)": "license_invalid",
+			"auth failed":               "auth_failed",
+			"(unclosed":                 "bad_regex",
+			"no reason":                 "",
+		},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	patterns := fatalPatternsFromProtoStruct(logging.NewTestLogger(t), attrs, "startup_fatal_patterns")
+	test.That(t, patterns, test.ShouldHaveLength, 2)
+	test.That(t, patterns[0].reason, test.ShouldEqual, "auth_failed")
+	test.That(t, patterns[1].reason, test.ShouldEqual, "license_invalid")
+}
+
+func TestStartFatalPattern(t *testing.T) {
+	binPath := fakeViamServer(t)
+	script := "#!/bin/sh\necho 'starting up'\necho 'error: license expired' >&2\nexec sleep 30\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := *prevCfg
+	cfg.startTimeout = time.Minute
+	cfg.startupFatalPatterns = []fatalPattern{
+		{regex: regexp.MustCompile(`license (invalid|expired)`), reason: "license_invalid"},
+		{regex: regexp.MustCompile(`unsupported architecture`), reason: "unsupported_arch"},
+	}
+	globalConfig.Store(&cfg)
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	start := time.Now()
+	err := s.Start(ctx)
+	test.That(t, time.Since(start), test.ShouldBeLessThan, time.Second*10)
+	test.That(t, errors.Is(err, ErrStartupFatal), test.ShouldBeTrue)
+	var fatal *StartupFatalError
+	test.That(t, errors.As(err, &fatal), test.ShouldBeTrue)
+	test.That(t, fatal.Reason, test.ShouldEqual, "license_invalid")
+	test.That(t, fatal.Line, test.ShouldContainSubstring, "license expired")
+
+	// the matchers are gone, so their names are free again
+	for _, w := range []any{s.cmd.Stdout, s.cmd.Stderr} {
+		logger, ok := w.(*agent.MatchingLogger)
+		test.That(t, ok, test.ShouldBeTrue)
+		_, err := logger.AddMatcher("fatal0", regexp.MustCompile(`x`), false)
+		test.That(t, err, test.ShouldBeNil)
+		logger.DeleteMatcher("fatal0")
+	}
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
@@ -0,0 +1,41 @@
+//go:build faultinjection
+
+package viamserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent/testing/faultinjection"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestWaitPanicRecovery(t *testing.T) {
+	fakeViamServer(t)
+	t.Cleanup(faultinjection.ResetAll)
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+
+	faultinjection.PanicAt(SubsysName, "wait")
+	//nolint:errcheck
+	s.Start(ctx)
+
+	select {
+	case <-s.exitChan:
+	case <-time.After(time.Second * 10):
+		t.Fatal("exit channel not closed after panic")
+	}
+	s.mu.Lock()
+	test.That(t, s.running, test.ShouldBeFalse)
+	test.That(t, s.cmd.ProcessState, test.ShouldNotBeNil)
+	s.mu.Unlock()
+
+	// the hook only fires once, so the next start behaves normally
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	s.mu.Lock()
+	test.That(t, s.running, test.ShouldBeTrue)
+	s.mu.Unlock()
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
@@ -0,0 +1,136 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	errw "github.com/pkg/errors"
+)
+
+// HealthChecker checks a single healthcheck target, one of the URLs viam-server reports it's serving on.
+// Custom implementations can be added with RegisterHealthChecker, and selected by the healthcheck_type attribute.
+type HealthChecker interface {
+	Check(ctx context.Context, target string) error
+}
+
+// HealthCheckerFunc adapts a function to the HealthChecker interface.
+type HealthCheckerFunc func(ctx context.Context, target string) error
+
+// Check calls f.
+func (f HealthCheckerFunc) Check(ctx context.Context, target string) error {
+	return f(ctx, target)
+}
+
+const (
+	// HealthCheckHTTP makes a GET request, with any configured auth, and expects a 2xx response. This is the default.
+	HealthCheckHTTP = "http"
+	// HealthCheckTCP only checks that a connection can be made.
+	HealthCheckTCP = "tcp"
+	// HealthCheckProcess only checks that the process is running.
+	HealthCheckProcess = "process"
+)
+
+var (
+	healthCheckersMu sync.Mutex
+	healthCheckers   = map[string]HealthChecker{
+		HealthCheckHTTP:    HealthCheckerFunc(httpHealthCheck),
+		HealthCheckTCP:     HealthCheckerFunc(tcpHealthCheck),
+		HealthCheckProcess: HealthCheckerFunc(func(ctx context.Context, target string) error { return nil }),
+	}
+)
+
+const (
+	// how long a single healthcheck attempt may take.
+	healthCheckAttemptTimeout = time.Second * 10
+	defaultHealthCheckBackoff = time.Millisecond * 500
+)
+
+// checkWithRetry checks target up to attempts times, so a transient failure (like a refused connection while
+// viam-server rebinds) isn't reported as unhealthy. The wait between attempts starts at backoff and doubles.
+func checkWithRetry(ctx context.Context, checker HealthChecker, target string, attempts int, backoff time.Duration) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return errors.Join(err, ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, healthCheckAttemptTimeout)
+		err = checker.Check(timeoutCtx, target)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	if attempts > 1 {
+		return errw.Wrapf(err, "failed %d attempts", attempts)
+	}
+	return err
+}
+
+// RegisterHealthChecker makes checker selectable by setting the healthcheck_type attribute to name.
+func RegisterHealthChecker(name string, checker HealthChecker) {
+	healthCheckersMu.Lock()
+	defer healthCheckersMu.Unlock()
+	healthCheckers[name] = checker
+}
+
+func getHealthChecker(name string) (HealthChecker, error) {
+	healthCheckersMu.Lock()
+	defer healthCheckersMu.Unlock()
+	checker, ok := healthCheckers[name]
+	if !ok {
+		return nil, errw.Errorf("unknown healthcheck_type %q", name)
+	}
+	return checker, nil
+}
+
+func httpHealthCheck(ctx context.Context, target string) (errRet error) {
+	authHeader, authValue, err := healthCheckAuth(globalConfig.Load())
+	if err != nil {
+		return err
+	}
+	client, reqURL := healthCheckClient(target)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+	if authValue != "" {
+		req.Header.Set(authHeader, authValue)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errRet = errors.Join(errRet, resp.Body.Close())
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HTTPStatusError{Code: resp.StatusCode}
+	}
+	return nil
+}
+
+func tcpHealthCheck(ctx context.Context, target string) error {
+	network, addr, ok := endpointAddr(target)
+	if !ok {
+		return errw.Errorf("can't find address in %s", target)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
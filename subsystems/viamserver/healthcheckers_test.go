@@ -0,0 +1,116 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestHealthCheckers(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.That(t, err, test.ShouldBeNil)
+	target := "http://" + listener.Addr().String()
+
+	test.That(t, tcpHealthCheck(ctx, target), test.ShouldBeNil)
+	test.That(t, listener.Close(), test.ShouldBeNil)
+	test.That(t, tcpHealthCheck(ctx, target), test.ShouldNotBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: target, checkURLAlt: target}
+
+	// nothing is listening, but only the process is checked
+	globalConfig.Store(&viamServerConfig{healthCheckType: HealthCheckProcess})
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+
+	var checked []string
+	errCustom := errors.New("custom failure")
+	RegisterHealthChecker("custom", HealthCheckerFunc(func(ctx context.Context, target string) error {
+		checked = append(checked, target)
+		return errCustom
+	}))
+	globalConfig.Store(&viamServerConfig{healthCheckType: "custom"})
+	test.That(t, errors.Is(s.HealthCheck(ctx), errCustom), test.ShouldBeTrue)
+	test.That(t, checked, test.ShouldResemble, []string{target, target})
+
+	globalConfig.Store(&viamServerConfig{healthCheckType: "bogus"})
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+}
+
+func TestCheckWithRetry(t *testing.T) {
+	ctx := context.Background()
+	// reserve a port, then leave it refusing connections until the listener comes back
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.That(t, err, test.ShouldBeNil)
+	addr := listener.Addr().String()
+	test.That(t, listener.Close(), test.ShouldBeNil)
+	target := "http://" + addr
+	checker := HealthCheckerFunc(tcpHealthCheck)
+
+	test.That(t, checkWithRetry(ctx, checker, target, 2, time.Millisecond*10), test.ShouldNotBeNil)
+
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			close(listening)
+			return
+		}
+		listening <- l
+	}()
+	test.That(t, checkWithRetry(ctx, checker, target, 6, time.Millisecond*50), test.ShouldBeNil)
+	l, ok := <-listening
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, l.Close(), test.ShouldBeNil)
+
+	// a cancelled context stops the retries
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = checkWithRetry(cancelCtx, checker, target, 5, time.Hour)
+	test.That(t, errors.Is(err, context.Canceled), test.ShouldBeTrue)
+}
+
+func TestHealthCheckUnlocked(t *testing.T) {
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	checking := make(chan struct{}, 10)
+	release := make(chan struct{})
+	RegisterHealthChecker("blocking", HealthCheckerFunc(func(ctx context.Context, target string) error {
+		checking <- struct{}{}
+		<-release
+		return errors.New("still starting")
+	}))
+	globalConfig.Store(&viamServerConfig{healthCheckType: "blocking", healthCheckAttempts: 3, healthCheckBackoff: time.Millisecond})
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: "http://localhost:8080", checkURLAlt: "http://localhost:8081"}
+
+	done := make(chan error, 1)
+	go func() { done <- s.HealthCheck(context.Background()) }()
+	<-checking
+
+	// neither lock is held while the check is in progress
+	locked := make(chan struct{})
+	go func() {
+		s.startStopMu.Lock()
+		s.mu.Lock()
+		s.mu.Unlock()
+		s.startStopMu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second * 5):
+		t.Fatal("locks held during healthcheck")
+	}
+
+	close(release)
+	test.That(t, <-done, test.ShouldNotBeNil)
+	failure, _ := s.LastHealthCheckFailure()
+	test.That(t, failure, test.ShouldEqual, HealthCheckFailureOther)
+}
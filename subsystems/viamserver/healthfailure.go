@@ -0,0 +1,108 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// HealthCheckFailure is the kind of error a failed healthcheck got, so each kind can be handled differently with the
+// healthcheck_failure_actions attribute.
+type HealthCheckFailure string
+
+const (
+	// HealthCheckFailureConnectRefused means nothing is listening, usually because viam-server is wedged or gone.
+	HealthCheckFailureConnectRefused HealthCheckFailure = "connect_refused"
+	// HealthCheckFailureTimeout means the check didn't finish within its timeout.
+	HealthCheckFailureTimeout HealthCheckFailure = "timeout"
+	// HealthCheckFailureHTTP5xx means viam-server answered with a server error.
+	HealthCheckFailureHTTP5xx HealthCheckFailure = "http_5xx"
+	// HealthCheckFailureHTTPOther means viam-server answered with any other non-2xx code.
+	HealthCheckFailureHTTPOther HealthCheckFailure = "http_other"
+	// HealthCheckFailureOther is anything else.
+	HealthCheckFailureOther HealthCheckFailure = "other"
+)
+
+// HTTPStatusError is returned by the http healthcheck when the response isn't a 2xx.
+type HTTPStatusError struct {
+	Code int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("got code: %d", e.Code)
+}
+
+// classifyHealthCheckError returns the kind of failure err is, or "" for nil.
+func classifyHealthCheckError(err error) HealthCheckFailure {
+	if err == nil {
+		return ""
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.Code >= 500 && statusErr.Code < 600 {
+			return HealthCheckFailureHTTP5xx
+		}
+		return HealthCheckFailureHTTPOther
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return HealthCheckFailureConnectRefused
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return HealthCheckFailureTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return HealthCheckFailureTimeout
+	}
+	return HealthCheckFailureOther
+}
+
+// healthCheckFailureActionsFromProtoStruct parses a map like {"http_5xx": "alert", "connect_refused": "restart"},
+// skipping invalid entries. Categories that aren't set restart.
+func healthCheckFailureActionsFromProtoStruct(
+	logger logging.Logger, protoStruct *structpb.Struct, key string,
+) map[HealthCheckFailure]agent.UnhealthyAction {
+	if protoStruct == nil {
+		return nil
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return nil
+	}
+	actions, ok := raw.(map[string]any)
+	if !ok {
+		logger.Warnf("invalid %s: %v", key, raw)
+		return nil
+	}
+	ret := make(map[HealthCheckFailure]agent.UnhealthyAction, len(actions))
+	for category, rawAction := range actions {
+		switch HealthCheckFailure(category) {
+		case HealthCheckFailureConnectRefused, HealthCheckFailureTimeout, HealthCheckFailureHTTP5xx,
+			HealthCheckFailureHTTPOther, HealthCheckFailureOther:
+		default:
+			logger.Warnf("invalid %s category: %s", key, category)
+			continue
+		}
+		action, _ := rawAction.(string) //nolint:errcheck
+		switch agent.UnhealthyAction(action) {
+		case agent.UnhealthyActionRestart, agent.UnhealthyActionAlert, agent.UnhealthyActionNone:
+			ret[HealthCheckFailure(category)] = agent.UnhealthyAction(action)
+		default:
+			logger.Warnf("invalid %s action for %s: %v", key, category, rawAction)
+		}
+	}
+	return ret
+}
+
+// LastHealthCheckFailure returns the kind of failure the last healthcheck got, if it failed.
+func (s *viamServer) LastHealthCheckFailure() (HealthCheckFailure, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastHealthCheckFailure, s.lastHealthCheckFailure != ""
+}
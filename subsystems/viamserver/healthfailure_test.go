@@ -0,0 +1,93 @@
+package viamserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestClassifyHealthCheckError(t *testing.T) {
+	ctx := context.Background()
+	code := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{})
+
+	test.That(t, classifyHealthCheckError(httpHealthCheck(ctx, srv.URL)), test.ShouldEqual, HealthCheckFailureHTTP5xx)
+	code = http.StatusNotFound
+	test.That(t, classifyHealthCheckError(httpHealthCheck(ctx, srv.URL)), test.ShouldEqual, HealthCheckFailureHTTPOther)
+	code = http.StatusOK
+	test.That(t, classifyHealthCheckError(httpHealthCheck(ctx, srv.URL)), test.ShouldEqual, HealthCheckFailure(""))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.That(t, err, test.ShouldBeNil)
+	closed := "http://" + listener.Addr().String()
+	test.That(t, listener.Close(), test.ShouldBeNil)
+	test.That(t, classifyHealthCheckError(httpHealthCheck(ctx, closed)), test.ShouldEqual,
+		HealthCheckFailureConnectRefused)
+	test.That(t, classifyHealthCheckError(tcpHealthCheck(ctx, closed)), test.ShouldEqual,
+		HealthCheckFailureConnectRefused)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Nanosecond)
+	defer cancel()
+	<-timeoutCtx.Done()
+	test.That(t, classifyHealthCheckError(httpHealthCheck(timeoutCtx, srv.URL)), test.ShouldEqual,
+		HealthCheckFailureTimeout)
+}
+
+func TestHealthCheckFailureActions(t *testing.T) {
+	attrs, err := structpb.NewStruct(map[string]any{"healthcheck_failure_actions": map[string]any{
+		"http_5xx":        "alert",
+		"connect_refused": "restart",
+		"timeout":         "bogus",
+		"bogus":           "none",
+	}})
+	test.That(t, err, test.ShouldBeNil)
+	logger := logging.NewTestLogger(t)
+	actions := healthCheckFailureActionsFromProtoStruct(logger, attrs, "healthcheck_failure_actions")
+	test.That(t, actions, test.ShouldResemble, map[HealthCheckFailure]agent.UnhealthyAction{
+		HealthCheckFailureHTTP5xx:        agent.UnhealthyActionAlert,
+		HealthCheckFailureConnectRefused: agent.UnhealthyActionRestart,
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	ctx := context.Background()
+	s := &viamServer{logger: logger, running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+
+	// restarts by default
+	globalConfig.Store(&viamServerConfig{})
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+	category, failed := s.LastHealthCheckFailure()
+	test.That(t, failed, test.ShouldBeTrue)
+	test.That(t, category, test.ShouldEqual, HealthCheckFailureHTTP5xx)
+
+	// only alerted, but still not healthy
+	globalConfig.Store(&viamServerConfig{healthCheckFailureActions: actions})
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, s.healthySince.IsZero(), test.ShouldBeTrue)
+	category, _ = s.LastHealthCheckFailure()
+	test.That(t, category, test.ShouldEqual, HealthCheckFailureHTTP5xx)
+
+	// a refused connection still restarts
+	srv.Close()
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+	category, _ = s.LastHealthCheckFailure()
+	test.That(t, category, test.ShouldEqual, HealthCheckFailureConnectRefused)
+}
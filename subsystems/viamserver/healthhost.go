@@ -0,0 +1,34 @@
+package viamserver
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// overrideLoopbackHost replaces a loopback host in checkURL with host, keeping the scheme, port and path. It's for
+// when the agent runs in a different network namespace (a container, say) from viam-server, where the 127.0.0.1 or
+// localhost that viam-server advertises is the agent's own loopback rather than viam-server's. host should then be an
+// address the agent can reach viam-server on, such as the container's gateway. Anything else is returned unchanged.
+func overrideLoopbackHost(checkURL, host string) string {
+	if host == "" || strings.HasPrefix(checkURL, unixScheme) {
+		return checkURL
+	}
+	parsed, err := url.Parse(checkURL)
+	if err != nil {
+		return checkURL
+	}
+	hostname := parsed.Hostname()
+	if ip := net.ParseIP(hostname); hostname != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return checkURL
+	}
+	if port := parsed.Port(); port != "" {
+		parsed.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// a bare IPv6 address needs brackets
+		parsed.Host = "[" + host + "]"
+	} else {
+		parsed.Host = host
+	}
+	return parsed.String()
+}
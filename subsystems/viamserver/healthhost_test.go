@@ -0,0 +1,25 @@
+package viamserver
+
+import (
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestOverrideLoopbackHost(t *testing.T) {
+	for _, tc := range []struct {
+		checkURL, host, expected string
+	}{
+		{"http://127.0.0.1:8080", "172.17.0.1", "http://172.17.0.1:8080"},
+		{"https://localhost:8081/path", "gateway.local", "https://gateway.local:8081/path"},
+		{"http://[::1]:8080", "fd00::1", "http://[fd00::1]:8080"},
+		{"http://localhost", "fd00::1", "http://[fd00::1]"},
+		// not loopback
+		{"http://192.168.1.5:8080", "172.17.0.1", "http://192.168.1.5:8080"},
+		{"unix:///run/viam.sock", "172.17.0.1", "unix:///run/viam.sock"},
+		// no override
+		{"http://127.0.0.1:8080", "", "http://127.0.0.1:8080"},
+	} {
+		test.That(t, overrideLoopbackHost(tc.checkURL, tc.host), test.ShouldEqual, tc.expected)
+	}
+}
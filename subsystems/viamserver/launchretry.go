@@ -0,0 +1,32 @@
+package viamserver
+
+import (
+	"context"
+	"os/exec"
+	"time"
+
+	"go.viam.com/rdk/logging"
+)
+
+const defaultLaunchRetryBackoff = time.Second
+
+// launchWithRetry launches a command from newCmd, and if that fails, tries again with a fresh one up to retries more
+// times, as an exec.Cmd can't be started twice. The wait between attempts starts at backoff and doubles. These retries
+// are only for failing to launch at all (like ETXTBSY while the binary is being replaced), an exit after launching
+// counts toward crash_loop_threshold instead. Returns the last command tried.
+func launchWithRetry(
+	ctx context.Context, logger logging.Logger, newCmd func() *exec.Cmd, timeout time.Duration, retries int, backoff time.Duration,
+) (*exec.Cmd, error) {
+	for attempt := 0; ; attempt++ {
+		cmd := newCmd()
+		err := launch(ctx, logger, cmd, timeout)
+		if err == nil || attempt >= retries || ctx.Err() != nil {
+			return cmd, err
+		}
+		logger.Warnw("launch failed, retrying", "attempt", attempt+1, "retries", retries, "backoff", backoff, "error", err)
+		if err := sleepContext(ctx, backoff); err != nil {
+			return cmd, err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,52 @@
+package viamserver
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestLaunchWithRetry(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	missing := filepath.Join(t.TempDir(), "missing")
+	// fails to launch until the third attempt
+	var attempts int
+	newCmd := func() *exec.Cmd {
+		attempts++
+		if attempts < 3 {
+			return exec.Command(missing)
+		}
+		return exec.Command("true")
+	}
+
+	cmd, err := launchWithRetry(ctx, logger, newCmd, time.Second, 1, time.Millisecond)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, attempts, test.ShouldEqual, 2)
+	test.That(t, cmd.Process, test.ShouldBeNil)
+
+	attempts = 0
+	cmd, err = launchWithRetry(ctx, logger, newCmd, time.Second, 2, time.Millisecond)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, attempts, test.ShouldEqual, 3)
+	test.That(t, cmd.Wait(), test.ShouldBeNil)
+
+	// no retries by default
+	attempts = 0
+	_, err = launchWithRetry(ctx, logger, newCmd, time.Second, 0, time.Millisecond)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, attempts, test.ShouldEqual, 1)
+
+	// gives up once ctx is done
+	attempts = 0
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = launchWithRetry(cancelCtx, logger, newCmd, time.Second, 5, time.Millisecond)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, attempts, test.ShouldEqual, 1)
+}
@@ -0,0 +1,47 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+type fakeManifest map[string]agent.ManifestEntry
+
+func (f fakeManifest) Lookup(path string) (agent.ManifestEntry, bool) {
+	entry, ok := f[path]
+	return entry, ok
+}
+
+func TestStartVerifyManifest(t *testing.T) {
+	binPath := fakeViamServer(t)
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := *prevCfg
+	cfg.startTimeout = time.Second * 10
+	cfg.verifyManifest = true
+	globalConfig.Store(&cfg)
+
+	sum, err := agent.GetFileSum(binPath)
+	test.That(t, err, test.ShouldBeNil)
+	manifest := fakeManifest{binPath: {Path: binPath, SHA256: sum}}
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	WithManifest(manifest)(s)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte("#!/bin/sh\nexit 1\n"), 0o755), test.ShouldBeNil)
+	err = s.Start(ctx)
+	test.That(t, errors.Is(err, agent.ErrManifestMismatch), test.ShouldBeTrue)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	test.That(t, s.running, test.ShouldBeFalse)
+}
@@ -0,0 +1,98 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	defaultMemorySampleInterval        = time.Second * 10
+	defaultSoftEvictionWindow          = 3
+	defaultSoftEvictionRecoveryRatio   = 0.9
+	defaultSoftEvictionRecoveryTimeout = time.Minute
+)
+
+// helper to parse a number, otherwise return the default.
+func floatFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string, defaultValue float64) float64 {
+	if protoStruct == nil {
+		return defaultValue
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return defaultValue
+	}
+	val, ok := raw.(float64)
+	if !ok {
+		logger.Warnf("invalid number at %s: %v", key, raw)
+		return defaultValue
+	}
+	return val
+}
+
+// watchMemorySoftLimit samples viam-server's RSS every memorySampleInterval until done is closed. Once it's been over
+// memoryLimitSoftBytes for softEvictionWindow samples in a row, viam-server is sent SIGUSR2, asking it to shed memory
+// (such as caches.) If RSS doesn't then fall below the limit times softEvictionRecoveryRatio within
+// softEvictionRecoveryTimeout, viam-server is restarted gracefully (starting with SIGTERM, see stop_signal_sequence),
+// rather than waiting for a hard (cgroup or OOM) kill.
+func (s *viamServer) watchMemorySoftLimit(cfg *viamServerConfig, pid int, done <-chan struct{}) {
+	ticker := time.NewTicker(cfg.memorySampleInterval)
+	defer ticker.Stop()
+	recoveryBytes := uint64(float64(cfg.memoryLimitSoftBytes) * cfg.softEvictionRecoveryRatio)
+	var over int
+	var signaledAt time.Time
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		rss, err := agent.ReadRSSBytes(pid)
+		if err != nil {
+			s.logger.Debug(err)
+			continue
+		}
+
+		if !signaledAt.IsZero() {
+			if rss < recoveryBytes {
+				s.logger.Infof("%s memory recovered to %d bytes", SubsysName, rss)
+				signaledAt = time.Time{}
+				over = 0
+			} else if time.Since(signaledAt) >= cfg.softEvictionRecoveryTimeout {
+				break
+			}
+			continue
+		}
+
+		if rss <= cfg.memoryLimitSoftBytes {
+			over = 0
+			continue
+		}
+		over++
+		if over < cfg.softEvictionWindow {
+			continue
+		}
+		s.logger.Warnf("%s memory (%d bytes) over the soft limit of %d bytes for %d samples, sending SIGUSR2",
+			SubsysName, rss, cfg.memoryLimitSoftBytes, over)
+		if err := syscall.Kill(pid, syscall.SIGUSR2); err != nil {
+			s.logger.Error(errw.Wrapf(err, "signaling %s", SubsysName))
+			continue
+		}
+		signaledAt = time.Now()
+	}
+
+	s.logger.Warnf("%s memory didn't fall below %d bytes within %s, restarting it", SubsysName, recoveryBytes,
+		cfg.softEvictionRecoveryTimeout)
+	// the manager's healthchecks will retry if this fails
+	if err := s.restart(context.Background(), StartKindMemoryLimitRestart); errors.Is(err, errRestartSuperseded) {
+		s.logger.Infof("%s was stopped, not restarting it for its memory soft limit", SubsysName)
+	} else if err != nil {
+		s.logger.Error(errw.Wrap(err, "restarting over the memory soft limit"))
+	}
+}
@@ -0,0 +1,68 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestMemorySoftLimit(t *testing.T) {
+	binPath := fakeViamServer(t)
+	marker := filepath.Join(t.TempDir(), "usr2")
+	script := "#!/bin/sh\n" +
+		"trap 'echo usr2 >> " + marker + "' USR2\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"while true; do sleep 0.05; done\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := *prevCfg
+	cfg.startTimeout = time.Second * 10
+	// always over the limit, and never recovering
+	cfg.memoryLimitSoftBytes = 1
+	cfg.memorySampleInterval = time.Millisecond * 10
+	cfg.softEvictionWindow = 2
+	cfg.softEvictionRecoveryRatio = defaultSoftEvictionRecoveryRatio
+	cfg.softEvictionRecoveryTimeout = time.Millisecond * 200
+	globalConfig.Store(&cfg)
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	firstExit := s.exitChan
+
+	// only the first run is limited, so it isn't restarted over and over
+	noLimit := cfg
+	noLimit.memoryLimitSoftBytes = 0
+	globalConfig.Store(&noLimit)
+
+	select {
+	case <-firstExit:
+	case <-time.After(time.Second * 10):
+		t.Fatal("not restarted after exceeding the soft limit")
+	}
+	raw, err := os.ReadFile(marker)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(raw), test.ShouldStartWith, "usr2")
+
+	// restarted, rather than left stopped
+	deadline := time.Now().Add(time.Second * 10)
+	for {
+		s.mu.Lock()
+		restarted := s.running && s.exitChan != firstExit
+		s.mu.Unlock()
+		if restarted {
+			break
+		}
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond * 20)
+	}
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
@@ -0,0 +1,40 @@
+package viamserver
+
+import (
+	"context"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+	"github.com/viamrobotics/agent/subsystems"
+)
+
+const negotiateTimeout = time.Second * 10
+
+// NegotiateVersion runs viam-server with --negotiate-version, offering the given protocol versions, and returns the
+// one it chose (the last line of its output.)
+// Older versions don't support this, so it's only attempted if the negotiate_protocol attribute is set.
+func (s *viamServer) NegotiateVersion(ctx context.Context, offered []string) (string, error) {
+	if !globalConfig.Load().negotiateProtocol {
+		return "", subsystems.ErrVersionNegotiationUnsupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, negotiateTimeout)
+	defer cancel()
+
+	//nolint:gosec
+	cmd := exec.CommandContext(ctx, path.Join(agent.ViamDirs["bin"], SubsysName), "--negotiate-version", strings.Join(offered, ","))
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errw.Wrapf(err, "running %s --negotiate-version", SubsysName)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	chosen := strings.TrimSpace(lines[len(lines)-1])
+	if !slices.Contains(offered, chosen) {
+		return "", errw.Errorf("%s chose protocol version %q, which wasn't offered", SubsysName, chosen)
+	}
+	return chosen, nil
+}
@@ -0,0 +1,41 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/viamrobotics/agent/subsystems"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	binPath := fakeViamServer(t)
+	script := "#!/bin/sh\n" +
+		`[ "$1" = "--negotiate-version" ] || exit 1` + "\n" +
+		"echo 'some startup noise'\n" +
+		`echo "${2##*,}"` + "\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+
+	_, err := s.NegotiateVersion(ctx, []string{"v1", "v2"})
+	test.That(t, errors.Is(err, subsystems.ErrVersionNegotiationUnsupported), test.ShouldBeTrue)
+
+	globalConfig.Store(&viamServerConfig{negotiateProtocol: true})
+	version, err := s.NegotiateVersion(ctx, []string{"v1", "v2"})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, version, test.ShouldEqual, "v2")
+
+	// chose something not offered
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte("#!/bin/sh\necho v9\n"), 0o755), test.ShouldBeNil)
+	_, err = s.NegotiateVersion(ctx, []string{"v1"})
+	test.That(t, err, test.ShouldNotBeNil)
+}
@@ -0,0 +1,44 @@
+package viamserver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	errw "github.com/pkg/errors"
+)
+
+// inSeparateNetNamespace returns true if the process is in a different network namespace than the agent.
+func inSeparateNetNamespace(pid int) bool {
+	self, err := os.Readlink("/proc/self/ns/net")
+	if err != nil {
+		return false
+	}
+	target, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/ns/net")
+	if err != nil {
+		return false
+	}
+	return self != target
+}
+
+// namespaceHealthCheck checks url from inside the network namespace of pid, where viam-server's loopback
+// addresses are reachable even if they aren't from the host. Requires nsenter and curl.
+func namespaceHealthCheck(ctx context.Context, pid int, url, authHeader, authValue string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	args := []string{"--target", strconv.Itoa(pid), "--net", "--", "curl", "-skf", "-o", "/dev/null"}
+	if authValue != "" {
+		args = append(args, "-H", fmt.Sprintf("%s: %s", authHeader, authValue))
+	}
+	args = append(args, url)
+	//nolint:gosec
+	out, err := exec.CommandContext(timeoutCtx, "nsenter", args...).CombinedOutput()
+	if err != nil {
+		return errw.Wrapf(err, "checking %s status from its network namespace: %s", SubsysName, out)
+	}
+	return nil
+}
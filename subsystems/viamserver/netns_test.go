@@ -0,0 +1,14 @@
+package viamserver
+
+import (
+	"os"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+func TestInSeparateNetNamespace(t *testing.T) {
+	test.That(t, inSeparateNetNamespace(os.Getpid()), test.ShouldBeFalse)
+	// unknown processes are treated as sharing the namespace, so the host check result stands
+	test.That(t, inSeparateNetNamespace(-1), test.ShouldBeFalse)
+}
@@ -0,0 +1,120 @@
+package viamserver
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// how long to wait for a burst of netlink events to settle before refreshing.
+const netChangeSettleTime = time.Second * 2
+
+// watchNetworkChanges subscribes to netlink address/link events until done is closed,
+// refreshing the healthcheck URLs whenever the host's addresses change.
+func (s *viamServer) watchNetworkChanges(done <-chan struct{}) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		s.logger.Error(errw.Wrap(err, "opening netlink socket"))
+		return
+	}
+	err = unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	})
+	if err != nil {
+		s.logger.Error(errw.Wrap(err, "binding netlink socket"))
+		//nolint:errcheck
+		unix.Close(fd)
+		return
+	}
+	// wrapping as a non-blocking file uses the runtime poller, so Close() interrupts a pending Read()
+	sock := os.NewFile(uintptr(fd), "netlink")
+
+	events := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, os.Getpagesize())
+		for {
+			if _, err := sock.Read(buf); err != nil {
+				if !errors.Is(err, os.ErrClosed) {
+					s.logger.Error(errw.Wrap(err, "reading netlink socket"))
+				}
+				close(events)
+				return
+			}
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	defer func() {
+		//nolint:errcheck
+		sock.Close()
+		for range events {
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		}
+		// let the new addresses settle before checking
+		select {
+		case <-done:
+			return
+		case <-time.After(netChangeSettleTime):
+		}
+		s.refreshCheckURLs()
+	}
+}
+
+// refreshCheckURLs rewrites healthcheck URLs that point at addresses no longer assigned to this host,
+// as viam-server will still be reachable on the same port via localhost.
+func (s *viamServer) refreshCheckURLs() {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		s.logger.Error(errw.Wrap(err, "listing interface addresses"))
+		return
+	}
+	local := make(map[string]bool)
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			local[ipNet.IP.String()] = true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, checkURL := range []*string{&s.checkURL, &s.checkURLAlt} {
+		parsed, err := url.Parse(*checkURL)
+		if err != nil {
+			continue
+		}
+		ip := net.ParseIP(parsed.Hostname())
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || local[ip.String()] {
+			continue
+		}
+		// a healthcheck_host_override is reached from outside viam-server's namespace, so it's never local
+		if override := net.ParseIP(globalConfig.Load().healthCheckHostOverride); override != nil && override.Equal(ip) {
+			continue
+		}
+		stale := *checkURL
+		if port := parsed.Port(); port != "" {
+			parsed.Host = net.JoinHostPort("localhost", port)
+		} else {
+			parsed.Host = "localhost"
+		}
+		*checkURL = parsed.String()
+		s.logger.Warnf("address %s no longer assigned after network change, healthchecking %s instead of %s", ip, *checkURL, stale)
+	}
+}
@@ -0,0 +1,20 @@
+package viamserver
+
+import (
+	"testing"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestRefreshCheckURLs(t *testing.T) {
+	s := &viamServer{
+		logger: logging.NewTestLogger(t),
+		// 203.0.113.0/24 is reserved for documentation, so won't be assigned locally
+		checkURL:    "https://203.0.113.5:8080",
+		checkURLAlt: "http://localhost:8081",
+	}
+	s.refreshCheckURLs()
+	test.That(t, s.checkURL, test.ShouldEqual, "https://localhost:8080")
+	test.That(t, s.checkURLAlt, test.ShouldEqual, "http://localhost:8081")
+}
@@ -0,0 +1,77 @@
+package viamserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestOnHealthyHook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	out := filepath.Join(t.TempDir(), "out")
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{
+		onHealthyHook:    []string{"sh", "-c", "echo healthy >> " + out},
+		onHealthyTimeout: time.Second * 5,
+	})
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+
+	waitForRuns := func(runs int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second * 10)
+		for {
+			//nolint:gosec
+			data, _ := os.ReadFile(out)
+			if strings.Count(string(data), "healthy\n") >= runs {
+				break
+			}
+			test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+			time.Sleep(time.Millisecond * 20)
+		}
+		// and no more than that
+		time.Sleep(time.Millisecond * 100)
+		//nolint:gosec
+		data, err := os.ReadFile(out)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, strings.Count(string(data), "healthy\n"), test.ShouldEqual, runs)
+	}
+
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	waitForRuns(1)
+
+	// becoming healthy again in the same run doesn't repeat it
+	s.mu.Lock()
+	s.healthySince = time.Time{}
+	s.mu.Unlock()
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	waitForRuns(1)
+
+	// but a new run does, as start resets it
+	s.mu.Lock()
+	s.healthySince = time.Time{}
+	s.onHealthyRan = false
+	s.mu.Unlock()
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	waitForRuns(2)
+
+	// a failing hook doesn't affect health
+	globalConfig.Store(&viamServerConfig{onHealthyHook: []string{"false"}})
+	s.mu.Lock()
+	s.healthySince = time.Time{}
+	s.onHealthyRan = false
+	s.mu.Unlock()
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+}
@@ -0,0 +1,84 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	oomCrashReason = "killed by the kernel OOM killer"
+	// the kernel logs the kill before sending SIGKILL, but reading it can lag slightly behind Wait returning
+	oomNotifyGrace = time.Millisecond * 500
+)
+
+var (
+	// the kernel's OOM killer message, "Killed process" on newer kernels, "Kill process" on older ones.
+	oomKillRegex = regexp.MustCompile(`Out of memory: Kill(?:ed)? process (\d+)`)
+	// read for kernel messages, a var so tests can substitute a fifo.
+	kmsgPath = "/dev/kmsg"
+)
+
+// watchForOOMKill reads kernel messages until ctx is done, and sends on notifyCh (without blocking) if the OOM killer
+// kills pid. This is best-effort, reading /dev/kmsg needs root (or CAP_SYSLOG).
+func (s *viamServer) watchForOOMKill(ctx context.Context, pid int, notifyCh chan<- struct{}) {
+	kmsg, err := os.Open(kmsgPath)
+	if err != nil {
+		s.logger.Debugw("can't watch for OOM kills", "error", err)
+		return
+	}
+	// only new messages matter, an older kill may have been of a process that had the same pid. Not every file
+	// (such as a fifo) supports seeking, which is fine.
+	//nolint:errcheck
+	kmsg.Seek(0, io.SeekEnd)
+	go func() {
+		<-ctx.Done()
+		kmsg.Close()
+	}()
+
+	target := strconv.Itoa(pid)
+	// each read of /dev/kmsg returns a single record
+	buf := make([]byte, 8192)
+	for {
+		n, err := kmsg.Read(buf)
+		if err != nil {
+			// EPIPE means older records were overwritten before being read, and reading can continue
+			if errors.Is(err, syscall.EPIPE) {
+				continue
+			}
+			if ctx.Err() == nil && !errors.Is(err, io.EOF) {
+				s.logger.Debugw("stopped watching for OOM kills", "error", err)
+			}
+			return
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if matches := oomKillRegex.FindStringSubmatch(line); matches != nil && matches[1] == target {
+				select {
+				case notifyCh <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// killedByOOM returns whether the OOM killer was reported to have killed the process, given its exit state. Only a
+// SIGKILL can be an OOM kill, so only then does it wait (up to oomNotifyGrace) for the kernel message to be read.
+func killedByOOM(state *os.ProcessState, oomChan <-chan struct{}) bool {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() || status.Signal() != syscall.SIGKILL {
+		return false
+	}
+	select {
+	case <-oomChan:
+		return true
+	case <-time.After(oomNotifyGrace):
+		return false
+	}
+}
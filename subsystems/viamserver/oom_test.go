@@ -0,0 +1,67 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestWatchForOOMKill(t *testing.T) {
+	prevPath := kmsgPath
+	t.Cleanup(func() { kmsgPath = prevPath })
+	kmsgPath = filepath.Join(t.TempDir(), "kmsg")
+	test.That(t, syscall.Mkfifo(kmsgPath, 0o600), test.ShouldBeNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	notify := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		s.watchForOOMKill(ctx, 1234, notify)
+		close(done)
+	}()
+
+	// blocks until the watcher has opened the other end
+	kmsg, err := os.OpenFile(kmsgPath, os.O_WRONLY, 0)
+	test.That(t, err, test.ShouldBeNil)
+	defer kmsg.Close()
+
+	_, err = kmsg.WriteString("3,100,200,-;Out of memory: Killed process 12345 (other) total-vm:1024kB\n")
+	test.That(t, err, test.ShouldBeNil)
+	select {
+	case <-notify:
+		t.Fatal("notified for a different pid")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	_, err = kmsg.WriteString("3,101,300,-;Out of memory: Killed process 1234 (viam-server) total-vm:1024kB\n")
+	test.That(t, err, test.ShouldBeNil)
+	select {
+	case <-notify:
+	case <-time.After(time.Second * 5):
+		t.Fatal("not notified of OOM kill")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("watcher didn't stop")
+	}
+}
+
+func TestKilledByOOM(t *testing.T) {
+	oomChan := make(chan struct{}, 1)
+	// only a SIGKILL can be from the OOM killer
+	oomChan <- struct{}{}
+	test.That(t, killedByOOM(exitState(t, "kill -TERM $$"), oomChan), test.ShouldBeFalse)
+	test.That(t, killedByOOM(exitState(t, "kill -KILL $$"), oomChan), test.ShouldBeTrue)
+	test.That(t, killedByOOM(exitState(t, "kill -KILL $$"), oomChan), test.ShouldBeFalse)
+}
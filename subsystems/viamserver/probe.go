@@ -0,0 +1,66 @@
+package viamserver
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+)
+
+const (
+	defaultStartupProbeInterval = time.Second
+	defaultStartupProbeTimeout  = time.Second * 10
+	// how long to wait for output to be drained after a probe exits or is killed.
+	probeWaitDelay = time.Second
+)
+
+// runProbe runs a probe command (argv) once, returning an error if it doesn't exit zero within timeout.
+func runProbe(ctx context.Context, argv []string, timeout time.Duration) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	//nolint:gosec
+	cmd := exec.CommandContext(timeoutCtx, argv[0], argv[1:]...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return agent.KillProcessGroup(cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = probeWaitDelay
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if output := strings.TrimSpace(string(out)); output != "" {
+			return errw.Wrapf(err, "startup probe failed with output %q", output)
+		}
+		return errw.Wrap(err, "startup probe failed")
+	}
+	return nil
+}
+
+// pollStartupProbe runs the configured startup probe every interval until it succeeds, closing the returned channel
+// when it does. It gives up if ctx is cancelled or done is closed (the process exited.)
+func (s *viamServer) pollStartupProbe(ctx context.Context, cfg *viamServerConfig, done <-chan struct{}) <-chan struct{} {
+	ready := make(chan struct{})
+	go func() {
+		for {
+			err := runProbe(ctx, cfg.startupProbe, cfg.startupProbeTimeout)
+			if err == nil {
+				close(ready)
+				return
+			}
+			s.logger.Debug(err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-time.After(cfg.startupProbeInterval):
+			}
+		}
+	}()
+	return ready
+}
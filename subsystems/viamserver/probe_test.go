@@ -0,0 +1,45 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestStartupProbe(t *testing.T) {
+	binPath := fakeViamServer(t)
+	// never logs the serving line, so only the probe can signal readiness
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755), test.ShouldBeNil)
+	readyFile := filepath.Join(t.TempDir(), "ready")
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:         time.Second * 10,
+		launchTimeout:        defaultLaunchTimeout,
+		startupProbe:         []string{"test", "-e", readyFile},
+		startupProbeInterval: time.Millisecond * 50,
+		startupProbeTimeout:  time.Second,
+	})
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	go func() {
+		time.Sleep(time.Millisecond * 200)
+		//nolint:errcheck,gosec
+		os.WriteFile(readyFile, nil, 0o644)
+	}()
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.checkURL, test.ShouldEqual, "")
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+
+	test.That(t, os.Remove(readyFile), test.ShouldBeNil)
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
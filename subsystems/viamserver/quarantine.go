@@ -0,0 +1,71 @@
+package viamserver
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	errw "github.com/pkg/errors"
+)
+
+// suffix of the copy of ConfigFilePath kept once viam-server has been healthy with it, for config_quarantine.
+const lastGoodConfigSuffix = ".last-good"
+
+func lastGoodConfigPath() string {
+	return ConfigFilePath + lastGoodConfigSuffix
+}
+
+// saveLastGoodConfig copies ConfigFilePath aside, as viam-server has been healthy with it for the readiness steady
+// state. Must be called with mu held.
+func (s *viamServer) saveLastGoodConfig() {
+	//nolint:gosec
+	data, err := os.ReadFile(ConfigFilePath)
+	if err != nil {
+		s.logger.Warn(errw.Wrapf(err, "reading %s to save as last-known-good", ConfigFilePath))
+		return
+	}
+	//nolint:gosec
+	if prev, err := os.ReadFile(lastGoodConfigPath()); err == nil && bytes.Equal(prev, data) {
+		return
+	}
+	if err := writeFileAtomic(lastGoodConfigPath(), data); err != nil {
+		s.logger.Warn(errw.Wrap(err, "saving last-known-good config"))
+		return
+	}
+	s.logger.Infof("saved %s as the last-known-good config", ConfigFilePath)
+}
+
+// quarantineConfig moves ConfigFilePath aside with a timestamp, as viam-server is crash looping with it, and with
+// config_quarantine_restore puts the last-known-good config in its place. If that's restored, the crash loop is
+// cleared so it can be started again. With nothing to restore, the config is left where it is. Must be called with mu
+// held.
+func (s *viamServer) quarantineConfig(cfg *viamServerConfig) {
+	var lastGood []byte
+	if cfg.configQuarantineRestore {
+		var err error
+		//nolint:gosec
+		lastGood, err = os.ReadFile(lastGoodConfigPath())
+		if err != nil {
+			s.logger.Error(errw.Wrapf(err, "reading last-known-good config, not quarantining %s", ConfigFilePath))
+			return
+		}
+	}
+
+	quarantinePath := ConfigFilePath + ".quarantined-" + time.Now().UTC().Format("20060102T150405Z")
+	if err := os.Rename(ConfigFilePath, quarantinePath); err != nil {
+		s.logger.Error(errw.Wrapf(err, "quarantining %s", ConfigFilePath))
+		return
+	}
+	s.logger.Errorf("%s is crash looping, quarantined its config %s to %s", SubsysName, ConfigFilePath, quarantinePath)
+	if !cfg.configQuarantineRestore {
+		return
+	}
+
+	if err := writeFileAtomic(ConfigFilePath, lastGood); err != nil {
+		s.logger.Error(errw.Wrapf(err, "restoring last-known-good config to %s", ConfigFilePath))
+		return
+	}
+	s.logger.Errorf("restored the last-known-good config to %s, %s will be started with it", ConfigFilePath, SubsysName)
+	s.crashLooping = false
+	s.recentExits = nil
+}
@@ -0,0 +1,87 @@
+package viamserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestConfigQuarantine(t *testing.T) {
+	prevPath := ConfigFilePath
+	t.Cleanup(func() { ConfigFilePath = prevPath })
+	dir := t.TempDir()
+	ConfigFilePath = filepath.Join(dir, "viam.json")
+	test.That(t, os.WriteFile(ConfigFilePath, []byte(`{"good": true}`), 0o600), test.ShouldBeNil)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := &viamServerConfig{
+		configQuarantine:        true,
+		configQuarantineRestore: true,
+		crashLoopThreshold:      2,
+		crashLoopWindow:         time.Minute,
+	}
+	globalConfig.Store(cfg)
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+
+	// saved once healthy for the (zero) steady state
+	test.That(t, s.HealthCheck(context.Background()), test.ShouldBeNil)
+	//nolint:gosec
+	saved, err := os.ReadFile(lastGoodConfigPath())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(saved), test.ShouldEqual, `{"good": true}`)
+
+	// a bad config that crash loops is moved aside, and the good one restored
+	test.That(t, os.WriteFile(ConfigFilePath, []byte(`{"good": false}`), 0o600), test.ShouldBeNil)
+	s.mu.Lock()
+	s.recordUnexpectedExit(cfg, ExitRecord{Time: time.Now(), Code: 1})
+	s.recordUnexpectedExit(cfg, ExitRecord{Time: time.Now(), Code: 1})
+	crashLooping := s.crashLooping
+	s.mu.Unlock()
+	test.That(t, crashLooping, test.ShouldBeFalse)
+	//nolint:gosec
+	restored, err := os.ReadFile(ConfigFilePath)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(restored), test.ShouldEqual, `{"good": true}`)
+	quarantined, err := filepath.Glob(ConfigFilePath + ".quarantined-*")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, quarantined, test.ShouldHaveLength, 1)
+	//nolint:gosec
+	bad, err := os.ReadFile(quarantined[0])
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, string(bad), test.ShouldEqual, `{"good": false}`)
+
+	// nothing to restore, so the config is left in place
+	test.That(t, os.Remove(lastGoodConfigPath()), test.ShouldBeNil)
+	s.mu.Lock()
+	s.recordUnexpectedExit(cfg, ExitRecord{Time: time.Now(), Code: 1})
+	s.recordUnexpectedExit(cfg, ExitRecord{Time: time.Now(), Code: 1})
+	crashLooping = s.crashLooping
+	s.crashLooping = false
+	s.recentExits = nil
+	s.mu.Unlock()
+	test.That(t, crashLooping, test.ShouldBeTrue)
+	_, err = os.Stat(ConfigFilePath)
+	test.That(t, err, test.ShouldBeNil)
+
+	// without restoring, it stays crash looping with no config
+	test.That(t, os.Remove(quarantined[0]), test.ShouldBeNil)
+	cfg.configQuarantineRestore = false
+	s.mu.Lock()
+	s.recordUnexpectedExit(cfg, ExitRecord{Time: time.Now(), Code: 1})
+	s.recordUnexpectedExit(cfg, ExitRecord{Time: time.Now(), Code: 1})
+	crashLooping = s.crashLooping
+	s.mu.Unlock()
+	test.That(t, crashLooping, test.ShouldBeTrue)
+	_, err = os.Stat(ConfigFilePath)
+	test.That(t, os.IsNotExist(err), test.ShouldBeTrue)
+}
@@ -0,0 +1,59 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent/subsystems"
+)
+
+// errRestartSuperseded is returned by restart when viam-server was stopped by something else, so it's left stopped.
+var errRestartSuperseded = errw.New("restart superseded by a stop")
+
+// restart stops and relaunches viam-server for the subsystem's own reasons (like the memory limit or restart
+// schedule). Stop may be called concurrently, such as by the manager shutting down, and always wins: if viam-server
+// was already stopped, or Stop is called before the relaunch, it's left stopped and errRestartSuperseded is returned.
+// It goes through the agent's RestartWrapper, if one was set.
+func (s *viamServer) restart(ctx context.Context, kind StartKind) error {
+	var epoch uint64
+	stop := func(ctx context.Context) error {
+		running, stopEpoch, err := s.stop(ctx)
+		if err != nil {
+			return errw.Wrapf(err, "stopping %s", SubsysName)
+		}
+		if !running {
+			return errRestartSuperseded
+		}
+		epoch = stopEpoch
+		return nil
+	}
+	start := func(ctx context.Context) error {
+		if err := s.start(ctx, &epoch, kind); err != nil {
+			if errors.Is(err, errRestartSuperseded) {
+				return err
+			}
+			return errw.Wrapf(err, "starting %s", SubsysName)
+		}
+		return nil
+	}
+
+	s.mu.Lock()
+	wrap := s.restartWrapper
+	s.mu.Unlock()
+	if wrap == nil {
+		if err := stop(ctx); err != nil {
+			return err
+		}
+		return start(ctx)
+	}
+	return wrap(ctx, stop, start)
+}
+
+// SetRestartWrapper sets the wrapper the restarts for restart_schedule and the memory soft limit go through, so the
+// agent's hooks and bookkeeping run for them too.
+func (s *viamServer) SetRestartWrapper(wrap subsystems.RestartWrapper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.restartWrapper = wrap
+}
@@ -0,0 +1,91 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestRestartSupersededByStop(t *testing.T) {
+	fakeViamServer(t)
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+
+	// nothing to restart
+	test.That(t, errors.Is(s.restart(ctx, StartKindScheduledRestart), errRestartSuperseded), test.ShouldBeTrue)
+	test.That(t, s.running, test.ShouldBeFalse)
+
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.restart(ctx, StartKindScheduledRestart), test.ShouldBeNil)
+	s.mu.Lock()
+	test.That(t, s.running, test.ShouldBeTrue)
+	s.mu.Unlock()
+
+	// a stop between stopping and relaunching discards the relaunch
+	_, epoch, err := s.stop(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, errors.Is(s.start(ctx, &epoch, StartKindScheduledRestart), errRestartSuperseded), test.ShouldBeTrue)
+	test.That(t, s.running, test.ShouldBeFalse)
+}
+
+func TestRestartStopStress(t *testing.T) {
+	fakeViamServer(t)
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+
+	for i := 0; i < 20; i++ {
+		test.That(t, s.Start(ctx), test.ShouldBeNil)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := s.restart(ctx, StartKindScheduledRestart); err != nil && !errors.Is(err, errRestartSuperseded) {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := s.Stop(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+		wg.Wait()
+		// however they interleave, Stop wins
+		s.mu.Lock()
+		running := s.running
+		s.mu.Unlock()
+		if running {
+			test.That(t, s.Stop(ctx), test.ShouldBeNil)
+			t.Fatalf("viam-server still running after Stop on iteration %d", i)
+		}
+	}
+}
+
+func TestRestartWrapper(t *testing.T) {
+	fakeViamServer(t)
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	var wrapped int
+	s.SetRestartWrapper(func(ctx context.Context, stop, start func(ctx context.Context) error) error {
+		wrapped++
+		if err := stop(ctx); err != nil {
+			return err
+		}
+		return start(ctx)
+	})
+
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.restart(ctx, StartKindScheduledRestart), test.ShouldBeNil)
+	test.That(t, wrapped, test.ShouldEqual, 1)
+	test.That(t, s.LastStartKind(), test.ShouldEqual, StartKindScheduledRestart)
+
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, errors.Is(s.restart(ctx, StartKindScheduledRestart), errRestartSuperseded), test.ShouldBeTrue)
+	test.That(t, wrapped, test.ShouldEqual, 2)
+	test.That(t, s.running, test.ShouldBeFalse)
+}
@@ -0,0 +1,30 @@
+package viamserver
+
+import (
+	errw "github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// raiseFDLimit raises the soft RLIMIT_NOFILE of the (already started) process to limit, or to the
+// hard limit if limit is zero. The agent's own limits are left untouched.
+func (s *viamServer) raiseFDLimit(pid int, limit uint64) error {
+	var current unix.Rlimit
+	if err := unix.Prlimit(pid, unix.RLIMIT_NOFILE, nil, &current); err != nil {
+		return errw.Wrapf(err, "getting file descriptor limit of %s", SubsysName)
+	}
+	if limit == 0 || limit > current.Max {
+		if limit > current.Max {
+			s.logger.Warnf("requested file descriptor limit %d is above the hard limit, using %d", limit, current.Max)
+		}
+		limit = current.Max
+	}
+	if limit > current.Cur {
+		desired := unix.Rlimit{Cur: limit, Max: current.Max}
+		if err := unix.Prlimit(pid, unix.RLIMIT_NOFILE, &desired, nil); err != nil {
+			return errw.Wrapf(err, "setting file descriptor limit of %s", SubsysName)
+		}
+		current.Cur = limit
+	}
+	s.logger.Infof("%s file descriptor limits: soft %d, hard %d", SubsysName, current.Cur, current.Max)
+	return nil
+}
@@ -0,0 +1,39 @@
+package viamserver
+
+import (
+	"os/exec"
+	"testing"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"golang.org/x/sys/unix"
+)
+
+func TestRaiseFDLimit(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	test.That(t, cmd.Start(), test.ShouldBeNil)
+	defer func() {
+		//nolint:errcheck
+		cmd.Process.Kill()
+		//nolint:errcheck
+		cmd.Wait()
+	}()
+	pid := cmd.Process.Pid
+
+	var before unix.Rlimit
+	test.That(t, unix.Prlimit(pid, unix.RLIMIT_NOFILE, nil, &before), test.ShouldBeNil)
+	if before.Cur < 2 {
+		t.Skip("soft file descriptor limit too low to lower for testing")
+	}
+	// lower the child's soft limit so there's room to raise it
+	lowered := unix.Rlimit{Cur: before.Cur / 2, Max: before.Max}
+	test.That(t, unix.Prlimit(pid, unix.RLIMIT_NOFILE, &lowered, nil), test.ShouldBeNil)
+
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.raiseFDLimit(pid, 0), test.ShouldBeNil)
+
+	var after unix.Rlimit
+	test.That(t, unix.Prlimit(pid, unix.RLIMIT_NOFILE, nil, &after), test.ShouldBeNil)
+	test.That(t, after.Cur, test.ShouldEqual, before.Max)
+	test.That(t, after.Max, test.ShouldEqual, before.Max)
+}
@@ -0,0 +1,78 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	errw "github.com/pkg/errors"
+)
+
+// daily scheduled restarts are skipped if viam-server has been up for less than this.
+const scheduledRestartMinUptime = time.Hour
+
+// restartSchedule is either a fixed interval since start, or a daily time of day.
+type restartSchedule struct {
+	interval time.Duration
+	// used if interval is zero, as an offset from local midnight
+	daily time.Duration
+}
+
+// parseRestartSchedule accepts a duration ("24h"), or a daily local time ("03:30").
+func parseRestartSchedule(raw string) (*restartSchedule, error) {
+	if interval, err := time.ParseDuration(raw); err == nil {
+		if interval <= 0 {
+			return nil, errw.Errorf("restart interval must be positive: %s", raw)
+		}
+		return &restartSchedule{interval: interval}, nil
+	}
+	clock, err := time.Parse("15:04", raw)
+	if err != nil {
+		return nil, errw.Errorf("restart schedule must be a duration or a time of day (HH:MM): %s", raw)
+	}
+	return &restartSchedule{daily: time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute}, nil
+}
+
+// next returns the first scheduled restart after now, for a process started at started.
+func (r *restartSchedule) next(started, now time.Time) time.Time {
+	if r.interval > 0 {
+		next := started.Add(r.interval)
+		if next.Before(now) {
+			return now
+		}
+		return next
+	}
+	year, month, day := now.Date()
+	next := time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Add(r.daily)
+	if !next.After(now) {
+		next = time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()).Add(r.daily)
+	}
+	return next
+}
+
+// runRestartSchedule gracefully restarts viam-server at the next scheduled time, unless done is closed first.
+func (s *viamServer) runRestartSchedule(schedule *restartSchedule, started time.Time, done <-chan struct{}) {
+	for {
+		next := schedule.next(started, time.Now())
+		s.logger.Debugf("next scheduled restart of %s at %s", SubsysName, next)
+		select {
+		case <-done:
+			return
+		case <-time.After(time.Until(next)):
+		}
+		// intervals already count from the start, but a daily time may come right after a restart
+		if uptime := time.Since(started); schedule.interval == 0 && uptime < scheduledRestartMinUptime {
+			s.logger.Infof("skipping scheduled restart of %s, only up for %s", SubsysName, uptime.Round(time.Second))
+			continue
+		}
+		break
+	}
+
+	s.logger.Infof("scheduled restart of %s", SubsysName)
+	// the manager's healthchecks will retry if this fails
+	if err := s.restart(context.Background(), StartKindScheduledRestart); errors.Is(err, errRestartSuperseded) {
+		s.logger.Infof("%s was stopped, skipping scheduled restart", SubsysName)
+	} else if err != nil {
+		s.logger.Error(errw.Wrap(err, "scheduled restart"))
+	}
+}
@@ -0,0 +1,30 @@
+package viamserver
+
+import (
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+)
+
+func TestRestartSchedule(t *testing.T) {
+	_, err := parseRestartSchedule("nightly")
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = parseRestartSchedule("-1h")
+	test.That(t, err, test.ShouldNotBeNil)
+
+	interval, err := parseRestartSchedule("6h")
+	test.That(t, err, test.ShouldBeNil)
+	started := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	test.That(t, interval.next(started, started.Add(time.Hour)), test.ShouldEqual, started.Add(time.Hour*6))
+	// overdue restarts happen immediately
+	late := started.Add(time.Hour * 7)
+	test.That(t, interval.next(started, late), test.ShouldEqual, late)
+
+	daily, err := parseRestartSchedule("03:30")
+	test.That(t, err, test.ShouldBeNil)
+	before := time.Date(2024, 6, 1, 1, 0, 0, 0, time.UTC)
+	test.That(t, daily.next(before, before), test.ShouldEqual, time.Date(2024, 6, 1, 3, 30, 0, 0, time.UTC))
+	after := time.Date(2024, 6, 1, 3, 30, 0, 0, time.UTC)
+	test.That(t, daily.next(after, after), test.ShouldEqual, time.Date(2024, 6, 2, 3, 30, 0, 0, time.UTC))
+}
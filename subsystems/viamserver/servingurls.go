@@ -0,0 +1,100 @@
+package viamserver
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// matches the line viam-server logs once it's serving, capturing the URL and alt URL.
+var servingURLRegex = regexp.MustCompile(
+	`serving\W*{"url":\W*"(https?://[\w\.:-]+|unix://[\w\./-]+)".*"alt_url":\W*"(https?://[\w\.:-]+|unix://[\w\./-]+)"}`)
+
+// servingURL is a URL and alt URL pair from one of viam-server's serving lines, as logged.
+type servingURL struct {
+	url string
+	alt string
+}
+
+// matches reports whether pref is the scheme or host of either URL, for healthcheck_url_prefer.
+func (u servingURL) matches(pref string) bool {
+	for _, raw := range []string{u.url, u.alt} {
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(parsed.Scheme, pref) || strings.EqualFold(parsed.Hostname(), pref) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURLs returns the URLs (and alt URLs) from every serving line viam-server has logged during this run, in the
+// order they were logged.
+func (s *viamServer) CheckURLs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ret []string
+	for _, u := range s.servingURLs {
+		ret = append(ret, u.url, u.alt)
+	}
+	return ret
+}
+
+// collectServingURLs records every serving line matched on c until it's closed, as viam-server logs one per listener.
+// The first is selected by start once it's seen, later ones may change the selection.
+func (s *viamServer) collectServingURLs(cfg *viamServerConfig, c <-chan []string) {
+	for matches := range c {
+		s.mu.Lock()
+		if s.addServingURL(matches) && len(s.servingURLs) > 1 {
+			s.selectCheckURL(cfg)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// addServingURL records the URL pair from a serving line, returning false if it was already known. Must be called
+// with mu held.
+func (s *viamServer) addServingURL(matches []string) bool {
+	u := servingURL{url: matches[1], alt: matches[2]}
+	for _, known := range s.servingURLs {
+		if known == u {
+			return false
+		}
+	}
+	s.servingURLs = append(s.servingURLs, u)
+	return true
+}
+
+// selectCheckURL sets the healthcheck URLs from the serving line chosen by healthcheck_url_prefer or
+// healthcheck_url_index, falling back to the first until the chosen one has been logged. Must be called with mu held.
+func (s *viamServer) selectCheckURL(cfg *viamServerConfig) {
+	if len(s.servingURLs) == 0 {
+		return
+	}
+	chosen := s.servingURLs[0]
+	if cfg.healthCheckURLIndex > 0 && cfg.healthCheckURLIndex < len(s.servingURLs) {
+		chosen = s.servingURLs[cfg.healthCheckURLIndex]
+	}
+	if cfg.healthCheckURLPrefer != "" {
+		for _, u := range s.servingURLs {
+			if u.matches(cfg.healthCheckURLPrefer) {
+				chosen = u
+				break
+			}
+		}
+	}
+
+	checkURL := chosen.url
+	checkURLAlt := strings.Replace(chosen.alt, "0.0.0.0", "localhost", 1)
+	if host := cfg.healthCheckHostOverride; host != "" {
+		checkURL = overrideLoopbackHost(checkURL, host)
+		checkURLAlt = overrideLoopbackHost(checkURLAlt, host)
+	}
+	if checkURL == s.checkURL && checkURLAlt == s.checkURLAlt {
+		return
+	}
+	s.checkURL, s.checkURLAlt = checkURL, checkURLAlt
+	s.logger.Infof("healthcheck URLs: %s %s", s.checkURL, s.checkURLAlt)
+}
@@ -0,0 +1,87 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestCheckURLs(t *testing.T) {
+	binPath := fakeViamServer(t)
+	script := "#!/bin/sh\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://0.0.0.0:8081"}'` + "\n" +
+		"sleep 0.2\n" +
+		`echo 'serving {"url": "https://192.168.1.5:8443", "alt_url": "https://localhost:8444"}'` + "\n" +
+		"exec sleep 30\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+
+	waitForURLs := func() {
+		t.Helper()
+		deadline := time.Now().Add(time.Second * 10)
+		for len(s.CheckURLs()) < 4 {
+			test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+			time.Sleep(time.Millisecond * 20)
+		}
+	}
+
+	// the first by default
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.checkURL, test.ShouldEqual, "http://localhost:8080")
+	waitForURLs()
+	test.That(t, s.CheckURLs(), test.ShouldResemble,
+		[]string{"http://localhost:8080", "http://0.0.0.0:8081", "https://192.168.1.5:8443", "https://localhost:8444"})
+	s.mu.Lock()
+	test.That(t, s.checkURL, test.ShouldEqual, "http://localhost:8080")
+	test.That(t, s.checkURLAlt, test.ShouldEqual, "http://localhost:8081")
+	s.mu.Unlock()
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+
+	// switched once the chosen one is logged
+	cfg := *prevCfg
+	cfg.healthCheckURLIndex = 1
+	globalConfig.Store(&cfg)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	waitForURLs()
+	s.mu.Lock()
+	test.That(t, s.checkURL, test.ShouldEqual, "https://192.168.1.5:8443")
+	test.That(t, s.checkURLAlt, test.ShouldEqual, "https://localhost:8444")
+	s.mu.Unlock()
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
+
+func TestSelectCheckURL(t *testing.T) {
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	s.addServingURL([]string{"", "http://localhost:8080", "http://0.0.0.0:8081"})
+	s.addServingURL([]string{"", "https://192.168.1.5:8443", "unix:///tmp/viam.sock"})
+	test.That(t, s.addServingURL([]string{"", "http://localhost:8080", "http://0.0.0.0:8081"}), test.ShouldBeFalse)
+	test.That(t, s.servingURLs, test.ShouldHaveLength, 2)
+
+	for _, tc := range []struct {
+		name   string
+		index  int
+		prefer string
+		url    string
+	}{
+		{"default", 0, "", "http://localhost:8080"},
+		{"index", 1, "", "https://192.168.1.5:8443"},
+		{"index not logged yet", 2, "", "http://localhost:8080"},
+		{"prefer scheme", 0, "https", "https://192.168.1.5:8443"},
+		{"prefer alt scheme", 0, "unix", "https://192.168.1.5:8443"},
+		{"prefer host", 1, "localhost", "http://localhost:8080"},
+		{"prefer unmatched", 1, "example.com", "https://192.168.1.5:8443"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s.selectCheckURL(&viamServerConfig{healthCheckURLIndex: tc.index, healthCheckURLPrefer: tc.prefer})
+			test.That(t, s.checkURL, test.ShouldEqual, tc.url)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package viamserver
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	errw "github.com/pkg/errors"
+	"go.viam.com/rdk/logging"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ErrConditionUnmet is returned by Start, without launching anything, while the start_condition isn't met. Callers
+// can keep retrying Start until it is, such as after an external provisioning step creates (or removes) the file.
+var ErrConditionUnmet = errw.New("start condition unmet")
+
+// startCondition gates starting on whether a sentinel file exists.
+type startCondition struct {
+	path string
+	// true if the file must exist, false if it must not
+	present bool
+}
+
+// startConditionFromProtoStruct parses {"path": "/etc/viam/activate", "present": true}, otherwise returns nil.
+// present defaults to true.
+func startConditionFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string) *startCondition {
+	if protoStruct == nil {
+		return nil
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return nil
+	}
+	attrs, ok := raw.(map[string]any)
+	if !ok {
+		logger.Warnf("invalid %s: %v", key, raw)
+		return nil
+	}
+	path, _ := attrs["path"].(string) //nolint:errcheck
+	if path == "" {
+		logger.Warnf("invalid %s, missing path: %v", key, raw)
+		return nil
+	}
+	cond := &startCondition{path: path, present: true}
+	if rawPresent, ok := attrs["present"]; ok {
+		present, ok := rawPresent.(bool)
+		if !ok {
+			logger.Warnf("invalid %s, present must be a bool: %v", key, raw)
+			return nil
+		}
+		cond.present = present
+	}
+	return cond
+}
+
+// check returns an ErrConditionUnmet error if the file's presence isn't as required.
+func (c *startCondition) check() error {
+	_, err := os.Stat(c.path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errw.Wrapf(err, "checking start condition %s", c.path)
+	}
+	switch {
+	case c.present && !exists:
+		return errw.Wrapf(ErrConditionUnmet, "%s does not exist", c.path)
+	case !c.present && exists:
+		return errw.Wrapf(ErrConditionUnmet, "%s exists", c.path)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestStartCondition(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	attrs, err := structpb.NewStruct(map[string]any{
+		"default": map[string]any{"path": "/a"},
+		"absent":  map[string]any{"path": "/b", "present": false},
+		"no_path": map[string]any{"present": false},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, startConditionFromProtoStruct(logger, attrs, "default"), test.ShouldResemble, &startCondition{path: "/a", present: true})
+	test.That(t, startConditionFromProtoStruct(logger, attrs, "absent"), test.ShouldResemble, &startCondition{path: "/b", present: false})
+	test.That(t, startConditionFromProtoStruct(logger, attrs, "no_path"), test.ShouldBeNil)
+
+	fakeViamServer(t)
+	sentinel := filepath.Join(t.TempDir(), "activate")
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := *prevCfg
+	cfg.startCondition = &startCondition{path: sentinel, present: true}
+	globalConfig.Store(&cfg)
+
+	ctx := context.Background()
+	s := &viamServer{logger: logger}
+	err = s.Start(ctx)
+	test.That(t, errors.Is(err, ErrConditionUnmet), test.ShouldBeTrue)
+	test.That(t, s.cmd, test.ShouldBeNil)
+
+	test.That(t, os.WriteFile(sentinel, nil, 0o600), test.ShouldBeNil)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+
+	// and the reverse, while the file exists
+	cfg.startCondition = &startCondition{path: sentinel, present: false}
+	err = s.Start(ctx)
+	test.That(t, errors.Is(err, ErrConditionUnmet), test.ShouldBeTrue)
+}
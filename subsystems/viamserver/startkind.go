@@ -0,0 +1,26 @@
+package viamserver
+
+import "github.com/viamrobotics/agent/subsystems"
+
+// StartKind is why viam-server was launched, as recorded in its Timeline and by LastStartKind.
+type StartKind = subsystems.StartKind
+
+const (
+	// StartKindFirstStart is the first launch since the subsystem was created.
+	StartKindFirstStart StartKind = "first_start"
+	// StartKindRestartAfterCrash is a Start after viam-server exited without being stopped.
+	StartKindRestartAfterCrash StartKind = "restart_after_crash"
+	// StartKindRestartAfterStop is a Start after it was stopped, such as by the manager restarting it.
+	StartKindRestartAfterStop StartKind = "restart_after_stop"
+	// StartKindScheduledRestart is a restart from restart_schedule.
+	StartKindScheduledRestart StartKind = "scheduled_restart"
+	// StartKindMemoryLimitRestart is a restart for staying over memory_limit_soft_bytes.
+	StartKindMemoryLimitRestart StartKind = "memory_limit_restart"
+)
+
+// LastStartKind returns why viam-server was last launched, "" if it hasn't been.
+func (s *viamServer) LastStartKind() StartKind {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastStartKind
+}
@@ -0,0 +1,53 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestStartKind(t *testing.T) {
+	binPath := fakeViamServer(t)
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.LastStartKind(), test.ShouldEqual, StartKind(""))
+
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.LastStartKind(), test.ShouldEqual, StartKindFirstStart)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.LastStartKind(), test.ShouldEqual, StartKindRestartAfterStop)
+	test.That(t, s.restart(ctx, StartKindScheduledRestart), test.ShouldBeNil)
+	test.That(t, s.LastStartKind(), test.ShouldEqual, StartKindScheduledRestart)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+
+	script := "#!/bin/sh\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"sleep 0.2\nexit 3\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	select {
+	case <-s.exitChan:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for exit")
+	}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.LastStartKind(), test.ShouldEqual, StartKindRestartAfterCrash)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+
+	var details []string
+	for _, event := range s.Timeline() {
+		if event.State == StateStarted || event.State == StateRestarted {
+			details = append(details, event.Detail)
+		}
+	}
+	test.That(t, details, test.ShouldResemble, []string{
+		string(StartKindFirstStart), string(StartKindRestartAfterStop), string(StartKindScheduledRestart),
+		string(StartKindRestartAfterStop), string(StartKindRestartAfterCrash),
+	})
+}
@@ -0,0 +1,74 @@
+package viamserver
+
+import (
+	"strings"
+	"time"
+
+	"github.com/viamrobotics/agent"
+)
+
+// logProcessStats logs per-interval kernel statistics for viam-server's process until done is closed.
+func (s *viamServer) logProcessStats(pid int, interval time.Duration, done <-chan struct{}) {
+	var sampler agent.DeltaSampler
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats, err := agent.ReadProcessStats(pid)
+		if err != nil {
+			s.logger.Debug(err)
+		} else if delta, ok := sampler.Sample(stats); ok {
+			s.logger.Infow(SubsysName+" process stats",
+				"interval", delta.Interval.Round(time.Millisecond).String(),
+				"voluntary_ctxt_switches", delta.VoluntaryContextSwitches,
+				"involuntary_ctxt_switches", delta.InvoluntaryContextSwitches,
+				"major_page_faults", delta.MajorPageFaults,
+				"minor_page_faults", delta.MinorPageFaults,
+				"blocked_io_wait_secs", delta.BlockedIOWaitSeconds,
+				"open_fds", delta.OpenFileDescriptors,
+				"threads", delta.Threads,
+				"rss_bytes", delta.RSSBytes,
+			)
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// the /proc/<pid>/status fields kept by captureLastStatus.
+var capturedStatusFields = []string{"State", "VmRSS", "VmHWM", "VmSwap", "Threads"}
+
+// captureLastStatus samples /proc/<pid>/status every interval until done is closed, keeping the latest sample so it's
+// available after an unexpected exit (by then the process is gone, and its status with it.) This is best-effort.
+func (s *viamServer) captureLastStatus(pid int, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status, err := agent.ReadProcStatus(pid)
+		// once it's exiting (its memory released) or a zombie, the interesting fields are already gone
+		_, hasMem := status["VmRSS"]
+		if err == nil && hasMem && !strings.HasPrefix(status["State"], "Z") {
+			sample := make(map[string]string, len(capturedStatusFields))
+			for _, field := range capturedStatusFields {
+				if val, ok := status[field]; ok {
+					sample[field] = val
+				}
+			}
+			s.mu.Lock()
+			// the process may have exited (and been reaped) while reading
+			select {
+			case <-done:
+			default:
+				s.lastStatus = sample
+			}
+			s.mu.Unlock()
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
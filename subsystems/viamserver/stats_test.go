@@ -0,0 +1,44 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestCaptureLastStatus(t *testing.T) {
+	binPath := fakeViamServer(t)
+	script := "#!/bin/sh\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"sleep 0.5\nexit 3\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:          time.Second * 10,
+		launchTimeout:         defaultLaunchTimeout,
+		crashLoopThreshold:    5,
+		crashLoopWindow:       time.Minute,
+		statusCaptureInterval: time.Millisecond * 50,
+	})
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	<-s.exitChan
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	test.That(t, s.recentExits, test.ShouldHaveLength, 1)
+	status := s.recentExits[0].Status
+	test.That(t, status, test.ShouldNotBeNil)
+	test.That(t, status["State"], test.ShouldNotStartWith, "Z")
+	test.That(t, status["VmRSS"], test.ShouldEndWith, "kB")
+	test.That(t, status["Threads"], test.ShouldNotBeEmpty)
+}
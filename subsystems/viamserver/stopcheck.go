@@ -0,0 +1,75 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// how often to retry connecting while verifying nothing is still listening.
+const stopCheckInterval = time.Millisecond * 250
+
+// endpointAddr returns the network and address to dial for a healthcheck URL.
+func endpointAddr(checkURL string) (string, string, bool) {
+	if socketPath, ok := strings.CutPrefix(checkURL, unixScheme); ok {
+		return "unix", socketPath, true
+	}
+	parsed, err := url.Parse(checkURL)
+	if err != nil || parsed.Hostname() == "" {
+		return "", "", false
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return "tcp", net.JoinHostPort(parsed.Hostname(), port), true
+}
+
+// verifyStopped confirms nothing is still accepting connections on the healthcheck endpoint after the process has exited,
+// as viam-server may have forked a listener that outlived it. This is opt-in, and a lingering listener is only logged.
+func (s *viamServer) verifyStopped(ctx context.Context) {
+	cfg := globalConfig.Load()
+	if cfg.verifyStopTimeout <= 0 {
+		return
+	}
+	s.mu.Lock()
+	checkURL := s.checkURL
+	s.mu.Unlock()
+
+	network, addr, ok := endpointAddr(checkURL)
+	if !ok {
+		return
+	}
+	if stillListening(ctx, network, addr, cfg.verifyStopTimeout) {
+		s.logger.Warnf("something is still listening on %s after %s exited, it may have left an orphaned process", addr, SubsysName)
+	}
+}
+
+// stillListening returns true if connections to addr aren't refused within timeout.
+func stillListening(ctx context.Context, network, addr string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	for {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err == nil {
+			//nolint:errcheck
+			conn.Close()
+		} else if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT) {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return true
+		case <-time.After(stopCheckInterval):
+		}
+	}
+}
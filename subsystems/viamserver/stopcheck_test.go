@@ -0,0 +1,42 @@
+package viamserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+)
+
+func TestEndpointAddr(t *testing.T) {
+	for _, tc := range []struct {
+		url     string
+		network string
+		addr    string
+	}{
+		{"http://localhost:8080", "tcp", "localhost:8080"},
+		{"https://10.1.2.3", "tcp", "10.1.2.3:443"},
+		{"http://[::1]", "tcp", "[::1]:80"},
+		{"unix:///tmp/viam.sock", "unix", "/tmp/viam.sock"},
+	} {
+		network, addr, ok := endpointAddr(tc.url)
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, network, test.ShouldEqual, tc.network)
+		test.That(t, addr, test.ShouldEqual, tc.addr)
+	}
+	_, _, ok := endpointAddr("not a url")
+	test.That(t, ok, test.ShouldBeFalse)
+}
+
+func TestStillListening(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.That(t, err, test.ShouldBeNil)
+	addr := listener.Addr().String()
+
+	test.That(t, stillListening(ctx, "tcp", addr, time.Millisecond*100), test.ShouldBeTrue)
+	test.That(t, listener.Close(), test.ShouldBeNil)
+	test.That(t, stillListening(ctx, "tcp", addr, time.Second), test.ShouldBeFalse)
+	test.That(t, stillListening(ctx, "unix", t.TempDir()+"/missing.sock", time.Second), test.ShouldBeFalse)
+}
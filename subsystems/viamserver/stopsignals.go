@@ -0,0 +1,127 @@
+package viamserver
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ErrCannotStop is returned by Stop when the process is still running after the whole stop signal sequence.
+var ErrCannotStop = errw.New("process couldn't be stopped")
+
+// StopSignal is one step of stopping viam-server: send Signal, then wait up to WaitDuration for it to exit.
+type StopSignal struct {
+	Signal       syscall.Signal
+	WaitDuration time.Duration
+	// send Signal to just viam-server rather than its whole process group, ignored for SIGKILL
+	ProcessOnly bool
+}
+
+// used when stop_signal_sequence isn't set. SIGTERM goes to viam-server alone, as it stops its modules itself.
+var defaultStopSignalSequence = []StopSignal{
+	{Signal: syscall.SIGTERM, WaitDuration: stopTermTimeout, ProcessOnly: true},
+	{Signal: syscall.SIGKILL, WaitDuration: stopKillTimeout},
+}
+
+// stopSequenceFromProtoStruct parses stop_signal_sequence, a list like [{"signal": "SIGINT", "wait": "30s"}, ...],
+// falling back to the default sequence, and bounds it by stop_timeout (see boundStopSequence.) Steps signal the whole
+// process group unless they set "group": false.
+func stopSequenceFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct) []StopSignal {
+	sequence := stopStepsFromProtoStruct(logger, protoStruct, "stop_signal_sequence")
+	if sequence == nil {
+		sequence = defaultStopSignalSequence
+	}
+	return boundStopSequence(sequence, durationFromProtoStruct(logger, protoStruct, "stop_timeout", 0))
+}
+
+// stopStepsFromProtoStruct parses a list of stop steps, otherwise returns nil.
+func stopStepsFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string) []StopSignal {
+	if protoStruct == nil {
+		return nil
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return nil
+	}
+	list, ok := raw.([]any)
+	if !ok || len(list) == 0 {
+		logger.Warnf("invalid %s: %v", key, raw)
+		return nil
+	}
+	ret := make([]StopSignal, 0, len(list))
+	for _, item := range list {
+		step, ok := item.(map[string]any)
+		if !ok {
+			logger.Warnf("invalid %s: %v", key, raw)
+			return nil
+		}
+		name, _ := step["signal"].(string) //nolint:errcheck
+		if !strings.HasPrefix(name, "SIG") {
+			name = "SIG" + name
+		}
+		sig := unix.SignalNum(strings.ToUpper(name))
+		waitStr, _ := step["wait"].(string) //nolint:errcheck
+		wait, err := time.ParseDuration(waitStr)
+		group, groupOK := step["group"].(bool)
+		if _, ok := step["group"]; !ok {
+			group = true
+		} else if !groupOK {
+			err = errors.New("invalid group")
+		}
+		if sig == 0 || err != nil || wait <= 0 {
+			logger.Warnf("invalid %s step: %v", key, item)
+			return nil
+		}
+		ret = append(ret, StopSignal{Signal: sig, WaitDuration: wait, ProcessOnly: !group})
+	}
+	return ret
+}
+
+// boundStopSequence shortens the waits of sequence so they total no more than timeout, if it's set. The last step keeps
+// as much of its wait as fits, and earlier steps share the rest in order, being dropped once it runs out, so the final
+// signal (usually SIGKILL) is always sent.
+func boundStopSequence(sequence []StopSignal, timeout time.Duration) []StopSignal {
+	if timeout <= 0 || len(sequence) == 0 {
+		return sequence
+	}
+	last := sequence[len(sequence)-1]
+	last.WaitDuration = min(last.WaitDuration, timeout)
+	remaining := timeout - last.WaitDuration
+	ret := make([]StopSignal, 0, len(sequence))
+	for _, step := range sequence[:len(sequence)-1] {
+		if remaining <= 0 {
+			break
+		}
+		step.WaitDuration = min(step.WaitDuration, remaining)
+		remaining -= step.WaitDuration
+		ret = append(ret, step)
+	}
+	return append(ret, last)
+}
+
+// sendStopSignal signals the process group, or just the process for a ProcessOnly step. SIGKILL always goes to the whole
+// process group, so nothing is left behind, and with stop_kill_strays also to descendants that left the group.
+func (s *viamServer) sendStopSignal(step StopSignal) error {
+	var err error
+	switch {
+	case step.Signal == syscall.SIGKILL && globalConfig.Load().stopKillStrays:
+		err = s.killGroupMembers(s.cmd.Process.Pid)
+	case step.Signal == syscall.SIGKILL || !step.ProcessOnly:
+		err = agent.KillProcessGroup(s.cmd.Process.Pid, step.Signal)
+	default:
+		return s.cmd.Process.Signal(step.Signal)
+	}
+	if errors.Is(err, agent.ErrSameProcessGroup) {
+		// never signal our own group, so only the main process can be killed
+		s.logger.Error(err)
+		err = s.cmd.Process.Signal(step.Signal)
+	}
+	return err
+}
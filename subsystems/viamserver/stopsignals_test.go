@@ -0,0 +1,203 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestStopSequenceFromProtoStruct(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	attrs, err := structpb.NewStruct(map[string]any{
+		"valid": []any{
+			map[string]any{"signal": "SIGTERM", "wait": "5s"},
+			map[string]any{"signal": "int", "wait": "1s", "group": false},
+			map[string]any{"signal": "SIGKILL", "wait": "10s"},
+		},
+		"bad_signal": []any{map[string]any{"signal": "SIGNOPE", "wait": "5s"}},
+		"bad_group":  []any{map[string]any{"signal": "SIGTERM", "wait": "5s", "group": "yes"}},
+		"bad_wait":   []any{map[string]any{"signal": "SIGTERM"}},
+		"empty":      []any{},
+	})
+	test.That(t, err, test.ShouldBeNil)
+
+	test.That(t, stopStepsFromProtoStruct(logger, attrs, "valid"), test.ShouldResemble, []StopSignal{
+		{Signal: syscall.SIGTERM, WaitDuration: time.Second * 5},
+		{Signal: syscall.SIGINT, WaitDuration: time.Second, ProcessOnly: true},
+		{Signal: syscall.SIGKILL, WaitDuration: time.Second * 10},
+	})
+	for _, key := range []string{"bad_signal", "bad_wait", "bad_group", "empty", "missing"} {
+		test.That(t, stopStepsFromProtoStruct(logger, attrs, key), test.ShouldBeNil)
+	}
+
+	// stop_timeout applies to the default sequence too
+	test.That(t, stopSequenceFromProtoStruct(logger, nil), test.ShouldResemble, defaultStopSignalSequence)
+	attrs, err = structpb.NewStruct(map[string]any{"stop_timeout": "1m"})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, stopSequenceFromProtoStruct(logger, attrs), test.ShouldResemble, []StopSignal{
+		{Signal: syscall.SIGTERM, WaitDuration: time.Second * 50, ProcessOnly: true},
+		{Signal: syscall.SIGKILL, WaitDuration: stopKillTimeout},
+	})
+}
+
+func TestBoundStopSequence(t *testing.T) {
+	sequence := []StopSignal{
+		{Signal: syscall.SIGTERM, WaitDuration: time.Second * 20},
+		{Signal: syscall.SIGINT, WaitDuration: time.Second * 10},
+		{Signal: syscall.SIGKILL, WaitDuration: time.Second * 10},
+	}
+	for _, tc := range []struct {
+		name    string
+		timeout time.Duration
+		waits   map[syscall.Signal]time.Duration
+	}{
+		{"unset", 0, map[syscall.Signal]time.Duration{
+			syscall.SIGTERM: time.Second * 20, syscall.SIGINT: time.Second * 10, syscall.SIGKILL: time.Second * 10,
+		}},
+		{"fits", time.Minute, map[syscall.Signal]time.Duration{
+			syscall.SIGTERM: time.Second * 20, syscall.SIGINT: time.Second * 10, syscall.SIGKILL: time.Second * 10,
+		}},
+		{"shortened", time.Second * 35, map[syscall.Signal]time.Duration{
+			syscall.SIGTERM: time.Second * 20, syscall.SIGINT: time.Second * 5, syscall.SIGKILL: time.Second * 10,
+		}},
+		{"dropped", time.Second * 15, map[syscall.Signal]time.Duration{
+			syscall.SIGTERM: time.Second * 5, syscall.SIGKILL: time.Second * 10,
+		}},
+		{"only the last", time.Second * 3, map[syscall.Signal]time.Duration{
+			syscall.SIGKILL: time.Second * 3,
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bounded := boundStopSequence(sequence, tc.timeout)
+			waits := map[syscall.Signal]time.Duration{}
+			var total time.Duration
+			for _, step := range bounded {
+				waits[step.Signal] = step.WaitDuration
+				total += step.WaitDuration
+			}
+			test.That(t, waits, test.ShouldResemble, tc.waits)
+			test.That(t, bounded[len(bounded)-1].Signal, test.ShouldEqual, syscall.SIGKILL)
+			if tc.timeout > 0 {
+				test.That(t, total, test.ShouldBeLessThanOrEqualTo, tc.timeout)
+			}
+		})
+	}
+	// the original is untouched
+	test.That(t, sequence[1].WaitDuration, test.ShouldEqual, time.Second*10)
+}
+
+func TestStopSignalSequence(t *testing.T) {
+	binPath := fakeViamServer(t)
+	// ignores SIGTERM, but exits on SIGINT
+	script := "#!/bin/sh\ntrap '' TERM\ntrap 'exit 0' INT\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"while true; do sleep 0.05; done\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:  time.Second * 10,
+		launchTimeout: defaultLaunchTimeout,
+		stopSignalSequence: []StopSignal{
+			{Signal: syscall.SIGTERM, WaitDuration: time.Millisecond * 300},
+			{Signal: syscall.SIGINT, WaitDuration: time.Second * 5},
+			{Signal: syscall.SIGKILL, WaitDuration: time.Second * 5},
+		},
+	})
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, s.lastExit, test.ShouldEqual, 0)
+
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:       time.Second * 10,
+		launchTimeout:      defaultLaunchTimeout,
+		stopSignalSequence: []StopSignal{{Signal: syscall.SIGTERM, WaitDuration: time.Millisecond * 300}},
+	})
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, errors.Is(s.Stop(ctx), ErrCannotStop), test.ShouldBeTrue)
+
+	globalConfig.Store(&viamServerConfig{stopSignalSequence: []StopSignal{{Signal: syscall.SIGKILL, WaitDuration: time.Second * 5}}})
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
+
+func TestStopSignalGroup(t *testing.T) {
+	binPath := fakeViamServer(t)
+	dir := t.TempDir()
+	ready := filepath.Join(dir, "child-ready")
+	marker := filepath.Join(dir, "child-stopped")
+	// the child (in the same process group) records getting SIGTERM, once it's ready to
+	script := "#!/bin/sh\n" +
+		"sh -c \"trap 'touch " + marker + "; exit 0' TERM; touch " + ready + "; while true; do sleep 0.05; done\" &\n" +
+		"trap 'wait; exit 0' TERM\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"while true; do sleep 0.05; done\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:  time.Second * 10,
+		launchTimeout: defaultLaunchTimeout,
+		stopSignalSequence: []StopSignal{
+			{Signal: syscall.SIGTERM, WaitDuration: time.Second * 5},
+			{Signal: syscall.SIGKILL, WaitDuration: time.Second * 5},
+		},
+	})
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	deadline := time.Now().Add(time.Second * 10)
+	for {
+		if _, err := os.Stat(ready); err == nil {
+			break
+		}
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond * 20)
+	}
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, s.lastExit, test.ShouldEqual, 0)
+	_, err := os.Stat(marker)
+	test.That(t, err, test.ShouldBeNil)
+}
+
+func TestStopTimeout(t *testing.T) {
+	binPath := fakeViamServer(t)
+	script := "#!/bin/sh\ntrap '' TERM INT\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"while true; do sleep 0.05; done\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:  time.Second * 10,
+		launchTimeout: defaultLaunchTimeout,
+		stopSignalSequence: boundStopSequence([]StopSignal{
+			{Signal: syscall.SIGTERM, WaitDuration: time.Second * 30},
+			{Signal: syscall.SIGINT, WaitDuration: time.Second * 30},
+			{Signal: syscall.SIGKILL, WaitDuration: time.Second * 5},
+		}, time.Second),
+	})
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	start := time.Now()
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, time.Since(start), test.ShouldBeLessThan, time.Second*2)
+	test.That(t, s.lastExitSignal, test.ShouldEqual, syscall.SIGKILL)
+}
@@ -0,0 +1,66 @@
+package viamserver
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"syscall"
+
+	errw "github.com/pkg/errors"
+	"github.com/viamrobotics/agent"
+)
+
+// groupMembers returns the other members of the process group led by pid, and the descendants of pid that have left
+// it (with setpgid or setsid), which signaling the group would miss. Descendants that were already reparented away
+// (double forked) can't be traced back, and are only found if they kept the group.
+func groupMembers(procs []agent.ProcessEntry, pid int) (members, strays []int) {
+	children := make(map[int][]int)
+	for _, proc := range procs {
+		children[proc.PPid] = append(children[proc.PPid], proc.Pid)
+		if proc.Pgid == pid && proc.Pid != pid {
+			members = append(members, proc.Pid)
+		}
+	}
+	pgids := make(map[int]int, len(procs))
+	for _, proc := range procs {
+		pgids[proc.Pid] = proc.Pgid
+	}
+	queue := slices.Clone(children[pid])
+	for len(queue) > 0 {
+		child := queue[0]
+		queue = queue[1:]
+		if pgids[child] != pid {
+			strays = append(strays, child)
+		}
+		queue = append(queue, children[child]...)
+	}
+	slices.Sort(members)
+	slices.Sort(strays)
+	return members, strays
+}
+
+// killGroupMembers SIGKILLs the process group led by pid, and then each process groupMembers finds individually, so
+// nothing survives by having changed its group. The process list is taken first, while pid is still alive to trace
+// descendants through.
+func (s *viamServer) killGroupMembers(pid int) error {
+	procs, listErr := agent.ListProcesses()
+	if listErr != nil {
+		listErr = errw.Wrap(listErr, "listing processes, only the process group will be killed")
+	}
+	err := errors.Join(listErr, agent.KillProcessGroup(pid, syscall.SIGKILL))
+	members, strays := groupMembers(procs, pid)
+	if len(strays) > 0 {
+		s.logger.Warnw("killing processes that left "+SubsysName+"'s process group", "pids", strays)
+	}
+	self := os.Getpid()
+	for _, member := range append(members, strays...) {
+		if member == self {
+			continue
+		}
+		// already gone is fine, most members were killed with the group
+		if killErr := syscall.Kill(member, syscall.SIGKILL); killErr != nil && !errors.Is(killErr, syscall.ESRCH) {
+			err = errors.Join(err, errw.Wrapf(killErr, "killing pid %d", member))
+		}
+	}
+	return err
+}
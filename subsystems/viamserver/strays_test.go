@@ -0,0 +1,98 @@
+package viamserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestGroupMembers(t *testing.T) {
+	procs := []agent.ProcessEntry{
+		{Pid: 1, PPid: 0, Pgid: 1},
+		{Pid: 100, PPid: 1, Pgid: 100},
+		// a child and grandchild in the group
+		{Pid: 101, PPid: 100, Pgid: 100},
+		{Pid: 102, PPid: 101, Pgid: 100},
+		// a child that called setsid, and its child
+		{Pid: 103, PPid: 100, Pgid: 103},
+		{Pid: 104, PPid: 103, Pgid: 103},
+		// reparented, but still in the group
+		{Pid: 105, PPid: 1, Pgid: 100},
+		// unrelated
+		{Pid: 200, PPid: 1, Pgid: 200},
+	}
+	members, strays := groupMembers(procs, 100)
+	test.That(t, members, test.ShouldResemble, []int{101, 102, 105})
+	test.That(t, strays, test.ShouldResemble, []int{103, 104})
+}
+
+// processGone returns true once pid has exited, even if nothing has reaped it yet.
+func processGone(pid int) bool {
+	raw, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	idx := strings.LastIndexByte(string(raw), ')')
+	return idx >= 0 && strings.HasPrefix(strings.TrimSpace(string(raw[idx+1:])), "Z")
+}
+
+func TestStopKillsStrays(t *testing.T) {
+	binPath := fakeViamServer(t)
+	pidFile := filepath.Join(t.TempDir(), "stray")
+	// the stray leaves the process group (and session), so signaling the group misses it
+	script := "#!/bin/sh\n" +
+		"setsid sleep 30 >/dev/null 2>&1 &\n" +
+		"echo $! > " + pidFile + "\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"while true; do sleep 0.05; done\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:       time.Second * 10,
+		launchTimeout:      defaultLaunchTimeout,
+		stopSignalSequence: []StopSignal{{Signal: syscall.SIGKILL, WaitDuration: time.Second * 5}},
+		stopKillStrays:     true,
+	})
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	raw, err := os.ReadFile(pidFile)
+	test.That(t, err, test.ShouldBeNil)
+	stray, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	test.That(t, err, test.ShouldBeNil)
+	t.Cleanup(func() {
+		//nolint:errcheck
+		syscall.Kill(stray, syscall.SIGKILL)
+	})
+	// setsid may not have run yet
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		pgid, err := syscall.Getpgid(stray)
+		test.That(t, err, test.ShouldBeNil)
+		if pgid != s.cmd.Process.Pid {
+			break
+		}
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	deadline = time.Now().Add(time.Second * 5)
+	for !processGone(stray) {
+		test.That(t, time.Now().Before(deadline), test.ShouldBeTrue)
+		time.Sleep(time.Millisecond * 20)
+	}
+}
@@ -0,0 +1,60 @@
+package viamserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/viamrobotics/agent/subsystems"
+)
+
+// how many StateEvents Timeline keeps.
+const timelineSize = 100
+
+// State is a state viam-server has transitioned to, as recorded in its Timeline.
+type State = subsystems.State
+
+const (
+	StateStarted   State = "started"
+	StateRestarted State = "restarted"
+	StateHealthy   State = "healthy"
+	StateUnhealthy State = "unhealthy"
+	StateCrashed   State = "crashed"
+	StateStopped   State = "stopped"
+)
+
+// StateEvent is a single transition in viam-server's Timeline.
+type StateEvent = subsystems.StateEvent
+
+// timeline is a fixed size ring of StateEvents. It has its own lock, so it can be recorded to with or without mu held.
+type timeline struct {
+	mu     sync.Mutex
+	events [timelineSize]StateEvent
+	next   int
+	full   bool
+}
+
+func (t *timeline) record(state State, detail string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events[t.next] = StateEvent{Time: time.Now(), State: state, Detail: detail}
+	t.next = (t.next + 1) % timelineSize
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// snapshot returns the events, oldest first.
+func (t *timeline) snapshot() []StateEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]StateEvent(nil), t.events[:t.next]...)
+	}
+	return append(append(make([]StateEvent, 0, timelineSize), t.events[t.next:]...), t.events[:t.next]...)
+}
+
+// Timeline returns viam-server's most recent state transitions, oldest first, for diagnosing what it did without
+// going through the logs.
+func (s *viamServer) Timeline() []StateEvent {
+	return s.timeline.snapshot()
+}
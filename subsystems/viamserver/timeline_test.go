@@ -0,0 +1,98 @@
+package viamserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+func TestTimelineRing(t *testing.T) {
+	var tl timeline
+	test.That(t, tl.snapshot(), test.ShouldBeEmpty)
+	for i := 0; i < timelineSize+5; i++ {
+		tl.record(StateStarted, fmt.Sprint(i))
+	}
+	events := tl.snapshot()
+	test.That(t, events, test.ShouldHaveLength, timelineSize)
+	// the oldest were overwritten
+	test.That(t, events[0].Detail, test.ShouldEqual, "5")
+	test.That(t, events[timelineSize-1].Detail, test.ShouldEqual, fmt.Sprint(timelineSize+4))
+}
+
+func states(events []StateEvent) []State {
+	ret := make([]State, 0, len(events))
+	for _, e := range events {
+		ret = append(ret, e.State)
+	}
+	return ret
+}
+
+func TestTimeline(t *testing.T) {
+	binPath := fakeViamServer(t)
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	cfg := *prevCfg
+	cfg.startTimeout = time.Second * 10
+	globalConfig.Store(&cfg)
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	test.That(t, states(s.Timeline()), test.ShouldResemble, []State{StateStarted, StateStopped})
+
+	script := "#!/bin/sh\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"sleep 0.2\nexit 3\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	<-s.exitChan
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	<-s.exitChan
+
+	events := s.Timeline()
+	test.That(t, states(events), test.ShouldResemble, []State{
+		StateStarted, StateStopped, StateStarted, StateCrashed, StateRestarted, StateCrashed,
+	})
+	test.That(t, events[3].Detail, test.ShouldEqual, "exit code 3")
+	for i := 1; i < len(events); i++ {
+		test.That(t, events[i].Time.Before(events[i-1].Time), test.ShouldBeFalse)
+	}
+}
+
+func TestTimelineHealth(t *testing.T) {
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{startTimeout: defaultStartTimeout})
+
+	healthy := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+	// only changes are recorded
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	healthy = false
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+	healthy = true
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+
+	events := s.Timeline()
+	test.That(t, states(events), test.ShouldResemble, []State{StateHealthy, StateUnhealthy, StateHealthy})
+	test.That(t, events[1].Detail, test.ShouldNotBeEmpty)
+}
@@ -5,41 +5,230 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"net"
 	"net/http"
+	"os"
 	"os/exec"
 	"path"
-	"regexp"
+	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/edaniels/zeroconf"
 	errw "github.com/pkg/errors"
 	"github.com/viamrobotics/agent"
+	"github.com/viamrobotics/agent/otellogs"
 	"github.com/viamrobotics/agent/subsystems"
 	"github.com/viamrobotics/agent/subsystems/registry"
+	"github.com/viamrobotics/agent/testing/faultinjection"
+	"go.uber.org/zap/zapcore"
 	pb "go.viam.com/api/app/agent/v1"
 	"go.viam.com/rdk/logging"
+	"golang.org/x/sys/unix"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
 func init() {
-	globalConfig.Store(&viamServerConfig{startTimeout: defaultStartTimeout})
+	globalConfig.Store(&viamServerConfig{
+		startTimeout:         defaultStartTimeout,
+		readinessSteadyState: defaultReadinessSteadyState,
+		preconditionTimeout:  defaultPreconditionTimeout,
+		launchTimeout:        defaultLaunchTimeout,
+		stopKillStrays:       true,
+	})
 	registry.Register(SubsysName, NewSubsystem, DefaultConfig)
 }
 
 type viamServerConfig struct {
 	startTimeout time.Duration
+
+	// sampling for chatty info/debug output, disabled when logSampleInterval is zero.
+	logSampleInterval   time.Duration
+	logSampleFirst      int
+	logSampleThereafter int
+
+	// commands (argv) to run before launching and after stopping viam-server
+	preStartHook    []string
+	preStartTimeout time.Duration
+	postStopHook    []string
+	postStopTimeout time.Duration
+
+	// command (argv) to run once per run, after the first healthcheck passes, see runOnHealthyHook
+	onHealthyHook    []string
+	onHealthyTimeout time.Duration
+
+	// refresh the healthcheck URLs when the host's network addresses change
+	watchNetworkChanges bool
+
+	// how long viam-server must be continuously healthy before it's considered ready
+	readinessSteadyState time.Duration
+
+	// non-zero exit codes that are intentional, and so aren't logged as errors or restarted
+	expectedExitCodes []int
+
+	// raise viam-server's soft file descriptor limit to fdLimit, or the hard limit if fdLimit is zero
+	raiseFDLimit bool
+	fdLimit      uint64
+
+	// credentials presented on healthchecks, for when viam-server is behind an authenticating proxy.
+	// healthCheckAuthTokenFile takes priority, and is re-read on every check so the token can be rotated.
+	healthCheckAuthToken     string
+	healthCheckAuthTokenFile string
+	healthCheckAuthHeader    string
+
+	// cap on the total time spent running start preconditions
+	preconditionTimeout time.Duration
+
+	// how long to wait for the process to be launched (fork/exec), separate from startTimeout
+	launchTimeout time.Duration
+	// how many more times to try launching if it fails, and the initial wait between tries, see launchWithRetry
+	launchRetries      int
+	launchRetryBackoff time.Duration
+
+	// advertise this robot via mDNS for local discovery while viam-server is running
+	advertiseCapabilities bool
+
+	// optional, for periodic graceful restarts
+	restartSchedule *restartSchedule
+
+	// how often to log process statistics (context switches, page faults, etc.), zero to disable
+	processStatsInterval time.Duration
+
+	// optional OpenTelemetry collector (OTLP/HTTP) to forward viam-server's logs to
+	otelLogsEndpoint string
+	// cap on bytes/sec forwarded to otelLogsEndpoint, lines beyond it are dropped (except errors), zero for no limit
+	otelLogsMaxBPS int64
+
+	// after stopping, verify nothing is still listening on the healthcheck endpoint for up to this long, zero to disable
+	verifyStopTimeout time.Duration
+
+	// optional command (argv) run until it exits zero, as an alternative to waiting for the serving line in the logs
+	startupProbe         []string
+	startupProbeInterval time.Duration
+	startupProbeTimeout  time.Duration
+
+	// stop restarting after this many unexpected exits within crashLoopWindow, zero to disable
+	crashLoopThreshold int
+	crashLoopWindow    time.Duration
+
+	// run viam-server --negotiate-version after starting, to confirm protocol compatibility
+	negotiateProtocol bool
+
+	// how often to sample /proc/<pid>/status, so the last sample can be reported after an unexpected exit, zero to disable
+	statusCaptureInterval time.Duration
+
+	// which HealthChecker to use, see RegisterHealthChecker
+	healthCheckType string
+
+	// signals sent in turn to stop viam-server, see StopSignal, already bounded by stop_timeout
+	stopSignalSequence []StopSignal
+
+	// tries per healthcheck target, waiting healthCheckBackoff (doubling each time) between them, for every healthcheck_type
+	healthCheckAttempts int
+	healthCheckBackoff  time.Duration
+
+	// log lines that fail Start immediately, rather than waiting for startTimeout
+	startupFatalPatterns []fatalPattern
+
+	// how often to re-hash ConfigFilePath to detect modifications while running, zero to disable
+	configIntegrityInterval time.Duration
+	// stop viam-server (and refuse to start it) if the config file is modified while running
+	stopOnConfigTamper bool
+
+	// dedicated directory (relative to the viam dir, if not absolute) used as viam-server's working dir and HOME,
+	// created with dataDirMode if missing. Empty to share the viam dir.
+	dataDir     string
+	dataDirMode fs.FileMode
+
+	// ask viam-server to shed memory (then restart it) when its RSS stays over memoryLimitSoftBytes, zero to disable.
+	// See watchMemorySoftLimit.
+	memoryLimitSoftBytes        uint64
+	memorySampleInterval        time.Duration
+	softEvictionWindow          int
+	softEvictionRecoveryRatio   float64
+	softEvictionRecoveryTimeout time.Duration
+
+	// minimum time between an exit and relaunching, so the OS can finish cleaning up (closing ports, releasing fds)
+	restartDelay time.Duration
+	// a random extra wait of up to this long before each restart, after restartDelay, see restartJitter
+	restartJitterMax time.Duration
+
+	// check the binary against the install manifest before each launch, see agent.VerifyManifest
+	verifyManifest bool
+
+	// optional, a sentinel file that must exist (or not) for viam-server to be started
+	startCondition *startCondition
+
+	// "json" writes viam-server's (non-uploaded) lines as JSON records, otherwise they're printed as they are
+	logEncoding string
+
+	// optional, replaces a loopback host in the healthcheck URLs, see overrideLoopbackHost
+	healthCheckHostOverride string
+
+	// optional, which serving line's URLs to healthcheck when viam-server logs several (one per listener), see
+	// selectCheckURL. The first if neither is set.
+	healthCheckURLIndex  int
+	healthCheckURLPrefer string
+
+	// optional, components that must be reported ready (from readyComponentsPath) before viam-server is, see
+	// waitForComponents. "*" for all of them.
+	readyComponents        []string
+	readyComponentsPath    string
+	readyComponentsTimeout time.Duration
+
+	// when killing viam-server, also kill descendants that moved out of its process group, see killGroupMembers
+	stopKillStrays bool
+
+	// what a failed healthcheck does, by the kind of failure, see classifyHealthCheckError. Unset kinds restart.
+	healthCheckFailureActions map[HealthCheckFailure]agent.UnhealthyAction
+
+	// optional, a command (argv) that must also exit zero for viam-server to be healthy, see NewExecHealthCheck
+	execHealthCheck        []string
+	execHealthCheckTimeout time.Duration
+
+	// move the config aside when crash looping, and restore the one saved after the last sustained health, see
+	// quarantineConfig
+	configQuarantine        bool
+	configQuarantineRestore bool
 }
 
 const (
-	defaultStartTimeout = time.Minute * 5
+	logEncodingJSON    = "json"
+	logEncodingConsole = "console"
+)
+
+const (
+	defaultStartTimeout         = time.Minute * 5
+	defaultReadinessSteadyState = time.Second * 30
+	defaultPreconditionTimeout  = time.Minute
+	defaultLaunchTimeout        = time.Second * 30
+	defaultRestartDelay         = time.Millisecond * 100
+	// match zap's production sampling defaults.
+	defaultLogSampleFirst      = 100
+	defaultLogSampleThereafter = 100
 	// stopTermTimeout must be higher than viam-server shutdown timeout of 90 secs.
 	stopTermTimeout = time.Minute * 2
 	stopKillTimeout = time.Second * 10
 	fastStartName   = "fast_start"
 	SubsysName      = "viam-server"
+
+	// bounds for the startup banner captured from the first lines of output.
+	bannerMaxLines = 40
+	bannerMaxBytes = 8192
+	// longest partial log line held while waiting for its newline
+	logMaxLineBytes = 64 * 1024
+	// how long Wait keeps copying output after the process exits, see exec.Cmd.WaitDelay
+	waitDelay = time.Second * 5
+	// prefix of serving addresses that are unix sockets rather than TCP
+	unixScheme = "unix://"
 )
 
 var (
@@ -49,6 +238,13 @@ var (
 	// Set if (cached or cloud) config has the "fast_start" attribute set on the viam-server subsystem.
 	FastStart    atomic.Bool
 	globalConfig atomic.Pointer[viamServerConfig]
+
+	// ErrLaunchTimeout is returned when launching the process itself hangs, before it can produce any output.
+	ErrLaunchTimeout = errw.New("launch timed out")
+	// ErrStartupTimeout is returned when the process launched, but didn't report it was serving in time.
+	ErrStartupTimeout = errw.New("startup timed out")
+	// ErrBinaryMissing is returned instead of trying to launch a binary that has been moved or deleted.
+	ErrBinaryMissing = errw.New("binary missing")
 )
 
 type viamServer struct {
@@ -60,6 +256,56 @@ type viamServer struct {
 	exitChan    chan struct{}
 	checkURL    string
 	checkURLAlt string
+	banner      *startupBanner
+	// start of the current run of successful healthchecks, zero if the last one failed
+	healthySince time.Time
+	// last exit code was in expectedExitCodes
+	expectedExit bool
+	// why the last exit was a crash (fatal signal or go panic), if it was one
+	lastCrash string
+	// why the last exit looked like the process detached rather than exiting, if it did
+	detached string
+	// when the last process exited, zero if there hasn't been one
+	lastExitTime time.Time
+	// the signal that terminated the last process, zero if it exited by itself (lastExit is -1 when signaled)
+	lastExitSignal syscall.Signal
+	// unexpected exits within the crash loop window, and whether there have been too many
+	recentExits  []ExitRecord
+	crashLooping bool
+	// the config file was modified while running, and stop_on_config_tamper is set
+	configTampered bool
+	// ConfigFilePath's hash at start, if config_integrity_interval is set
+	configHash []byte
+	// latest sample from captureLastStatus
+	lastStatus map[string]string
+	// checked before each start, from the registry
+	preconditions []subsystems.Precondition
+	// set while advertising via mDNS
+	mdnsServer *zeroconf.Server
+
+	// optional, see WithConfigStore
+	configStore ConfigStore
+	// checked before launch with verify_manifest, the install cache unless set with WithManifest
+	manifest agent.Manifest
+
+	// recent state transitions, see Timeline
+	timeline timeline
+	// incremented by every stop, so an internal restart can tell if something else stopped viam-server meanwhile
+	stopEpoch uint64
+	// the kind of failure the last healthcheck got, "" if it passed
+	lastHealthCheckFailure HealthCheckFailure
+	// whether the config has been saved as last-known-good during this run, see config_quarantine
+	lastGoodSaved bool
+	// whether the on-healthy hook has been started during this run
+	onHealthyRan bool
+	// why viam-server was last launched, see LastStartKind
+	lastStartKind StartKind
+	// from every serving line logged during this run, see CheckURLs
+	servingURLs []servingURL
+	// from the last Update, so an expected exit is only cleared when the config changes
+	updateConf *pb.DeviceSubsystemConfig
+	// set by the agent, see SetRestartWrapper
+	restartWrapper subsystems.RestartWrapper
 
 	// for blocking start/stop/check ops while another is in progress
 	startStopMu sync.Mutex
@@ -92,138 +338,784 @@ func durationFromProtoStruct(
 	return durt
 }
 
+// helper to parse an integer, otherwise return a default.
+func intFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string, defaultValue int) int {
+	if protoStruct == nil {
+		return defaultValue
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return defaultValue
+	}
+	num, ok := raw.(float64)
+	if !ok {
+		logger.Warnf("invalid number at %s: %v", key, raw)
+		return defaultValue
+	}
+	return int(num)
+}
+
+// helper to parse a list of integers, otherwise return nil.
+func intSliceFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string) []int {
+	if protoStruct == nil {
+		return nil
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		logger.Warnf("invalid list at %s: %v", key, raw)
+		return nil
+	}
+	ret := make([]int, 0, len(list))
+	for _, item := range list {
+		num, ok := item.(float64)
+		if !ok {
+			logger.Warnf("invalid list at %s: %v", key, raw)
+			return nil
+		}
+		ret = append(ret, int(num))
+	}
+	return ret
+}
+
+// helper to parse a boolean, otherwise return a default.
+func boolFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string, defaultValue bool) bool {
+	if protoStruct == nil {
+		return defaultValue
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return defaultValue
+	}
+	val, ok := raw.(bool)
+	if !ok {
+		logger.Warnf("invalid boolean at %s: %v", key, raw)
+		return defaultValue
+	}
+	return val
+}
+
+// helper to parse a string, otherwise return a default.
+func stringFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key, defaultValue string) string {
+	if protoStruct == nil {
+		return defaultValue
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return defaultValue
+	}
+	val, ok := raw.(string)
+	if !ok {
+		logger.Warnf("invalid string at %s: %v", key, raw)
+		return defaultValue
+	}
+	return val
+}
+
+// helper to parse a list of strings, otherwise return nil.
+func stringSliceFromProtoStruct(logger logging.Logger, protoStruct *structpb.Struct, key string) []string {
+	if protoStruct == nil {
+		return nil
+	}
+	raw, ok := protoStruct.AsMap()[key]
+	if !ok {
+		return nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		logger.Warnf("invalid list at %s: %v", key, raw)
+		return nil
+	}
+	ret := make([]string, 0, len(list))
+	for _, item := range list {
+		str, ok := item.(string)
+		if !ok {
+			logger.Warnf("invalid list at %s: %v", key, raw)
+			return nil
+		}
+		ret = append(ret, str)
+	}
+	return ret
+}
+
 func configFromProto(logger logging.Logger, updateConf *pb.DeviceSubsystemConfig) *viamServerConfig {
-	ret := &viamServerConfig{}
+	ret := &viamServerConfig{
+		startTimeout:         defaultStartTimeout,
+		readinessSteadyState: defaultReadinessSteadyState,
+		preconditionTimeout:  defaultPreconditionTimeout,
+		launchTimeout:        defaultLaunchTimeout,
+		restartDelay:         defaultRestartDelay,
+		stopKillStrays:       true,
+	}
 	if updateConf != nil {
-		ret.startTimeout = durationFromProtoStruct(logger, updateConf.GetAttributes(), "start_timeout", defaultStartTimeout)
+		attrs := updateConf.GetAttributes()
+		ret.startTimeout = durationFromProtoStruct(logger, attrs, "start_timeout", defaultStartTimeout)
+		ret.logSampleInterval = durationFromProtoStruct(logger, attrs, "log_sample_interval", 0)
+		ret.logSampleFirst = intFromProtoStruct(logger, attrs, "log_sample_first", defaultLogSampleFirst)
+		ret.logSampleThereafter = intFromProtoStruct(logger, attrs, "log_sample_thereafter", defaultLogSampleThereafter)
+		ret.preStartHook = stringSliceFromProtoStruct(logger, attrs, "pre_start_hook")
+		ret.preStartTimeout = durationFromProtoStruct(logger, attrs, "pre_start_timeout", agent.DefaultHookTimeout)
+		ret.postStopHook = stringSliceFromProtoStruct(logger, attrs, "post_stop_hook")
+		ret.postStopTimeout = durationFromProtoStruct(logger, attrs, "post_stop_timeout", agent.DefaultHookTimeout)
+		ret.onHealthyHook = stringSliceFromProtoStruct(logger, attrs, "on_healthy_hook")
+		ret.onHealthyTimeout = durationFromProtoStruct(logger, attrs, "on_healthy_timeout", agent.DefaultHookTimeout)
+		ret.watchNetworkChanges = boolFromProtoStruct(logger, attrs, "watch_network_changes", false)
+		ret.readinessSteadyState = durationFromProtoStruct(logger, attrs, "readiness_steady_state", defaultReadinessSteadyState)
+		ret.expectedExitCodes = intSliceFromProtoStruct(logger, attrs, "expected_exit_codes")
+		ret.raiseFDLimit = boolFromProtoStruct(logger, attrs, "raise_fd_limit", false)
+		if fdLimit := intFromProtoStruct(logger, attrs, "fd_limit", 0); fdLimit > 0 {
+			ret.fdLimit = uint64(fdLimit)
+		}
+		ret.healthCheckAuthToken = stringFromProtoStruct(logger, attrs, "healthcheck_auth_token", "")
+		ret.healthCheckAuthTokenFile = stringFromProtoStruct(logger, attrs, "healthcheck_auth_token_file", "")
+		ret.healthCheckAuthHeader = stringFromProtoStruct(logger, attrs, "healthcheck_auth_header", "")
+		ret.preconditionTimeout = durationFromProtoStruct(logger, attrs, "precondition_timeout", defaultPreconditionTimeout)
+		ret.launchTimeout = durationFromProtoStruct(logger, attrs, "launch_timeout", defaultLaunchTimeout)
+		ret.launchRetries = intFromProtoStruct(logger, attrs, "launch_retries", 0)
+		ret.launchRetryBackoff = durationFromProtoStruct(logger, attrs, "launch_retry_backoff", defaultLaunchRetryBackoff)
+		ret.advertiseCapabilities = boolFromProtoStruct(logger, attrs, "advertise_capabilities", false)
+		ret.processStatsInterval = durationFromProtoStruct(logger, attrs, "process_stats_interval", 0)
+		ret.otelLogsEndpoint = stringFromProtoStruct(logger, attrs, "otel_logs_endpoint", "")
+		ret.otelLogsMaxBPS = int64(intFromProtoStruct(logger, attrs, "otel_logs_max_bytes_per_sec", 0))
+		ret.verifyStopTimeout = durationFromProtoStruct(logger, attrs, "verify_stop_timeout", 0)
+		ret.startupProbe = stringSliceFromProtoStruct(logger, attrs, "startup_probe")
+		ret.startupProbeInterval = durationFromProtoStruct(logger, attrs, "startup_probe_interval", defaultStartupProbeInterval)
+		ret.startupProbeTimeout = durationFromProtoStruct(logger, attrs, "startup_probe_timeout", defaultStartupProbeTimeout)
+		ret.crashLoopThreshold = intFromProtoStruct(logger, attrs, "crash_loop_threshold", 0)
+		ret.crashLoopWindow = durationFromProtoStruct(logger, attrs, "crash_loop_window", defaultCrashLoopWindow)
+		ret.negotiateProtocol = boolFromProtoStruct(logger, attrs, "negotiate_protocol", false)
+		ret.statusCaptureInterval = durationFromProtoStruct(logger, attrs, "status_capture_interval", 0)
+		ret.healthCheckType = stringFromProtoStruct(logger, attrs, "healthcheck_type", HealthCheckHTTP)
+		ret.stopSignalSequence = stopSequenceFromProtoStruct(logger, attrs)
+		ret.healthCheckAttempts = intFromProtoStruct(logger, attrs, "healthcheck_attempts", 1)
+		ret.healthCheckBackoff = durationFromProtoStruct(logger, attrs, "healthcheck_backoff", defaultHealthCheckBackoff)
+		ret.startupFatalPatterns = fatalPatternsFromProtoStruct(logger, attrs, "startup_fatal_patterns")
+		ret.configIntegrityInterval = durationFromProtoStruct(logger, attrs, "config_integrity_interval", 0)
+		ret.stopOnConfigTamper = boolFromProtoStruct(logger, attrs, "stop_on_config_tamper", false)
+		ret.dataDir = stringFromProtoStruct(logger, attrs, "data_dir", "")
+		ret.dataDirMode = fileModeFromProtoStruct(logger, attrs, "data_dir_mode", defaultDataDirMode)
+		ret.memoryLimitSoftBytes = uint64(max(intFromProtoStruct(logger, attrs, "memory_limit_soft_bytes", 0), 0))
+		ret.memorySampleInterval = durationFromProtoStruct(logger, attrs, "memory_sample_interval", defaultMemorySampleInterval)
+		ret.softEvictionWindow = intFromProtoStruct(logger, attrs, "soft_eviction_window", defaultSoftEvictionWindow)
+		ret.softEvictionRecoveryRatio = floatFromProtoStruct(logger, attrs, "soft_eviction_recovery_ratio",
+			defaultSoftEvictionRecoveryRatio)
+		ret.softEvictionRecoveryTimeout = durationFromProtoStruct(logger, attrs, "soft_eviction_recovery_timeout",
+			defaultSoftEvictionRecoveryTimeout)
+		ret.restartDelay = durationFromProtoStruct(logger, attrs, "restart_delay", defaultRestartDelay)
+		ret.restartJitterMax = durationFromProtoStruct(logger, attrs, "restart_jitter_max", 0)
+		ret.verifyManifest = boolFromProtoStruct(logger, attrs, "verify_manifest", false)
+		ret.startCondition = startConditionFromProtoStruct(logger, attrs, "start_condition")
+		ret.logEncoding = stringFromProtoStruct(logger, attrs, "log_encoding", "")
+		if ret.logEncoding != "" && ret.logEncoding != logEncodingJSON && ret.logEncoding != logEncodingConsole {
+			logger.Warnf("invalid log_encoding: %s", ret.logEncoding)
+			ret.logEncoding = ""
+		}
+		ret.healthCheckHostOverride = stringFromProtoStruct(logger, attrs, "healthcheck_host_override", "")
+		ret.healthCheckURLIndex = intFromProtoStruct(logger, attrs, "healthcheck_url_index", 0)
+		if ret.healthCheckURLIndex < 0 {
+			logger.Warnf("invalid healthcheck_url_index: %d", ret.healthCheckURLIndex)
+			ret.healthCheckURLIndex = 0
+		}
+		ret.healthCheckURLPrefer = stringFromProtoStruct(logger, attrs, "healthcheck_url_prefer", "")
+		if raw := stringFromProtoStruct(logger, attrs, "restart_schedule", ""); raw != "" {
+			schedule, err := parseRestartSchedule(raw)
+			if err != nil {
+				logger.Warn(err)
+			} else {
+				ret.restartSchedule = schedule
+			}
+		}
+		ret.stopKillStrays = boolFromProtoStruct(logger, attrs, "stop_kill_strays", true)
+		ret.healthCheckFailureActions = healthCheckFailureActionsFromProtoStruct(logger, attrs,
+			"healthcheck_failure_actions")
+		ret.execHealthCheck = stringSliceFromProtoStruct(logger, attrs, "exec_healthcheck")
+		ret.execHealthCheckTimeout = durationFromProtoStruct(logger, attrs, "exec_healthcheck_timeout",
+			defaultExecHealthCheckTimeout)
+		ret.configQuarantine = boolFromProtoStruct(logger, attrs, "config_quarantine", false)
+		ret.configQuarantineRestore = boolFromProtoStruct(logger, attrs, "config_quarantine_restore", true)
+		ret.readyComponents = stringSliceFromProtoStruct(logger, attrs, "ready_components")
+		ret.readyComponentsPath = stringFromProtoStruct(logger, attrs, "ready_components_path", defaultReadyComponentsPath)
+		ret.readyComponentsTimeout = durationFromProtoStruct(logger, attrs, "ready_components_timeout",
+			defaultReadyComponentsTimeout)
+		if boolFromProtoStruct(logger, attrs, "auto_correct", false) {
+			for _, change := range correctConfig(ret) {
+				logger.Warnf("auto-corrected %s config: %s", SubsysName, change)
+			}
+		}
 	}
 	return ret
 }
 
 func (s *viamServer) Start(ctx context.Context) error {
+	return s.start(ctx, nil, "")
+}
+
+// start launches viam-server. With an epoch (from stop), it's only launched if nothing has called Stop since. kind is
+// the caller's reason for an internal restart, otherwise it's worked out from the last exit.
+func (s *viamServer) start(ctx context.Context, epoch *uint64, kind StartKind) error {
 	s.startStopMu.Lock()
 	defer s.startStopMu.Unlock()
 
 	s.mu.Lock()
-
 	if s.running {
 		s.mu.Unlock()
 		return nil
 	}
-	if s.shouldRun {
+	if epoch != nil && *epoch != s.stopEpoch {
+		s.mu.Unlock()
+		return errRestartSuperseded
+	}
+	if s.crashLooping {
+		err := &CrashLoopError{Exits: slices.Clone(s.recentExits)}
+		s.mu.Unlock()
+		return err
+	}
+	if s.configTampered {
+		s.mu.Unlock()
+		return errw.Wrapf(ErrConfigTampered, "not starting %s until cleared", SubsysName)
+	}
+	// an expected exit stays that way until it's stopped or reconfigured, though the manager keeps calling Start
+	if s.expectedExit && kind == "" {
+		s.logger.Debugf("%s exited with expected code %d, not restarting until stopped or reconfigured", SubsysName, s.lastExit)
+		s.mu.Unlock()
+		return nil
+	}
+	lastExitTime := s.lastExitTime
+	s.mu.Unlock()
+
+	cfg := globalConfig.Load()
+	if cfg.startCondition != nil {
+		if err := cfg.startCondition.check(); err != nil {
+			return err
+		}
+	}
+
+	// a running process keeps its binary open, so an update may have removed it since the last start
+	binPath := path.Join(agent.ViamDirs["bin"], SubsysName)
+	if _, err := os.Stat(binPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return errw.Wrapf(ErrBinaryMissing, "%s not found at %s", SubsysName, binPath)
+		}
+		return errw.Wrapf(err, "checking %s binary", SubsysName)
+	}
+	if cfg.verifyManifest && s.manifest != nil {
+		verified, err := agent.VerifyManifest(s.manifest, binPath)
+		if err != nil {
+			return err
+		}
+		if !verified {
+			s.logger.Warnf("%s isn't in the install manifest, so it can't be verified", binPath)
+		}
+	}
+
+	if err := waitRestartDelay(ctx, lastExitTime, cfg.restartDelay); err != nil {
+		return err
+	}
+	// only restarts are jittered, not the first start
+	if jitter := restartJitter(cfg.restartJitterMax); jitter > 0 && !lastExitTime.IsZero() {
+		s.logger.Debugf("waiting %s (restart jitter) before starting %s", jitter, SubsysName)
+		if err := sleepContext(ctx, jitter); err != nil {
+			return err
+		}
+	}
+	if err := subsystems.RunPreconditions(ctx, s.preconditions, cfg.preconditionTimeout); err != nil {
+		return err
+	}
+	if err := agent.RunHook(ctx, s.logger, "pre-start", cfg.preStartHook, cfg.preStartTimeout); err != nil {
+		return err
+	}
+
+	var dataDir string
+	if cfg.dataDir != "" {
+		dataDir = resolveDataDir(cfg.dataDir)
+		if err := ensureDataDir(s.logger, dataDir, cfg.dataDirMode); err != nil {
+			return err
+		}
+	}
+
+	if s.configStore != nil {
+		data, err := s.configStore.Read(ctx)
+		if err != nil {
+			return errw.Wrap(err, "reading config from the config store")
+		}
+		if _, err := s.syncConfigFromStore(data); err != nil {
+			return err
+		}
+	}
+
+	var configHash []byte
+	if cfg.configIntegrityInterval > 0 {
+		var err error
+		configHash, err = agent.GetFileSum(ConfigFilePath)
+		if err != nil {
+			s.logger.Warn(errw.Wrapf(err, "hashing %s, its integrity won't be checked", ConfigFilePath))
+		}
+	}
+
+	s.mu.Lock()
+	startedState := StateStarted
+	switch {
+	case kind != "":
+		s.logger.Infof("Restarting %s (%s)", SubsysName, kind)
+		s.shouldRun = true
+	case s.shouldRun:
 		s.logger.Warnf("Restarting %s after unexpected exit", SubsysName)
-	} else {
+		startedState = StateRestarted
+		kind = StartKindRestartAfterCrash
+	default:
 		s.logger.Infof("Starting %s", SubsysName)
 		s.shouldRun = true
+		kind = StartKindFirstStart
+		if !s.lastExitTime.IsZero() {
+			kind = StartKindRestartAfterStop
+		}
 	}
+	s.lastStartKind = kind
+	// not held while launching, which can take a while with launch_retries
+	s.mu.Unlock()
 
-	stdio := agent.NewMatchingLogger(s.logger, false, false)
-	stderr := agent.NewMatchingLogger(s.logger, true, false)
-	//nolint:gosec
-	s.cmd = exec.Command(path.Join(agent.ViamDirs["bin"], SubsysName), "-config", ConfigFilePath)
-	s.cmd.Dir = agent.ViamDirs["viam"]
-	s.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-	s.cmd.Stdout = stdio
-	s.cmd.Stderr = stderr
+	sampling := agent.WithSampling(cfg.logSampleInterval, cfg.logSampleFirst, cfg.logSampleThereafter)
+	logOpts := []agent.MatchingLoggerOption{sampling, agent.WithLineBuffering(logMaxLineBytes)}
+	var exporter *otellogs.Exporter
+	emit := func(level zapcore.Level, line string) {}
+	if cfg.otelLogsEndpoint != "" {
+		exportOpts := []otellogs.Option{otellogs.WithErrorHandler(func(err error) {
+			s.logger.Debug(err)
+		})}
+		if cfg.otelLogsMaxBPS > 0 {
+			limiter := &agent.TokenBucket{}
+			limiter.SetLimitBPS(cfg.otelLogsMaxBPS)
+			exportOpts = append(exportOpts, otellogs.WithRateLimit(limiter))
+		}
+		exporter = otellogs.New(cfg.otelLogsEndpoint, SubsysName, exportOpts...)
+		emit = func(level zapcore.Level, line string) {
+			exporter.Emit(otellogs.Record{Time: time.Now(), Level: level, Body: line})
+		}
+		logOpts = append(logOpts, agent.WithLineSink(emit))
+	}
+	panics := &panicWatcher{}
+	stderrOpts := append(slices.Clip(logOpts), agent.WithLineSink(func(level zapcore.Level, line string) {
+		panics.observe(line)
+		emit(level, line)
+	}))
+	// once launched, the exporter is closed after the process exits
+	launched := false
+	defer func() {
+		if exporter != nil && !launched {
+			exporter.Close()
+		}
+	}()
+	stdioOpts := logOpts
+	if cfg.logEncoding == logEncodingJSON {
+		stdioOpts = append(slices.Clip(stdioOpts), agent.WithJSONOutput(os.Stdout, SubsysName, "stdout"))
+		stderrOpts = append(stderrOpts, agent.WithJSONOutput(os.Stdout, SubsysName, "stderr"))
+	}
+	// everything logged until startup completes is kept as the startup banner
+	banner := &startupBanner{}
+	stdioOpts = append(slices.Clip(stdioOpts), agent.WithRawLineSink(banner.add))
+	stdio := agent.NewMatchingLogger(s.logger, false, false, stdioOpts...)
+	stderr := agent.NewMatchingLogger(s.logger, true, false, stderrOpts...)
+	args := append([]string{"-config", ConfigFilePath}, extraArgsFromEnv(s.logger)...)
+	newCmd := func() *exec.Cmd {
+		//nolint:gosec
+		cmd := exec.Command(binPath, args...)
+		cmd.Dir = agent.ViamDirs["viam"]
+		if dataDir != "" {
+			// viam-server keeps its caches under $HOME/.viam
+			cmd.Dir = dataDir
+			cmd.Env = append(os.Environ(), "HOME="+dataDir)
+		}
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Stdout = stdio
+		cmd.Stderr = stderr
+		// a process left behind (such as by double-forking) can hold the output pipes open, which would block Wait forever
+		cmd.WaitDelay = waitDelay
+		return cmd
+	}
 
 	// watch for this line in the logs to indicate successful startup
-	c, err := stdio.AddMatcher(
-		"checkURL",
-		regexp.MustCompile(`serving\W*{"url":\W*"(https?://[\w\.:-]+)".*"alt_url":\W*"(https?://[\w\.:-]+)"}`),
-		false,
-	)
+	c, err := stdio.AddMatcher("checkURL", servingURLRegex, false)
 	if err != nil {
-		s.mu.Unlock()
 		return err
 	}
 	defer stdio.DeleteMatcher("checkURL")
+	// unlike checkURL, this one lasts the whole run, see collectServingURLs
+	servingChan, err := stdio.AddMatcher("servingURLs", servingURLRegex, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if !launched {
+			stdio.DeleteMatcher("servingURLs")
+		}
+	}()
+	s.mu.Lock()
+	s.servingURLs = nil
+	s.banner = banner
+	s.mu.Unlock()
+	go s.collectServingURLs(cfg, servingChan)
+	defer banner.close()
 
-	err = s.cmd.Start()
+	fatalChan, deleteFatalMatchers, err := watchFatalPatterns(cfg.startupFatalPatterns, stdio, stderr)
 	if err != nil {
-		s.mu.Unlock()
-		return errw.Wrapf(err, "starting %s", SubsysName)
+		return err
+	}
+	defer deleteFatalMatchers()
+
+	cmd, err := launchWithRetry(ctx, s.logger, newCmd, cfg.launchTimeout, cfg.launchRetries, cfg.launchRetryBackoff)
+	if err != nil {
+		return err
+	}
+	launched = true
+	if cfg.raiseFDLimit {
+		// go can't set rlimits between fork and exec, so this is applied immediately after instead
+		if err := s.raiseFDLimit(cmd.Process.Pid, cfg.fdLimit); err != nil {
+			s.logger.Warn(err)
+		}
 	}
+
+	s.mu.Lock()
+	s.cmd = cmd
 	s.running = true
+	s.healthySince = time.Time{}
+	s.expectedExit = false
+	s.lastCrash = ""
+	s.detached = ""
+	s.configHash = configHash
+	s.lastGoodSaved = false
+	s.onHealthyRan = false
+	s.lastStatus = nil
 	s.exitChan = make(chan struct{})
+	exitChan := s.exitChan
+
+	oomChan := make(chan struct{}, 1)
+	oomCtx, cancelOOM := context.WithCancel(context.Background())
+	go s.watchForOOMKill(oomCtx, cmd.Process.Pid, oomChan)
 
 	// must be unlocked before spawning goroutine
 	s.mu.Unlock()
 	go func() {
-		err := s.cmd.Wait()
+		defer s.recoverWaitPanic(cmd, exitChan)
+		defer cancelOOM()
+		if inject := faultinjection.Check(SubsysName, "wait"); inject != nil {
+			inject()
+		}
+		err := cmd.Wait()
+		oomKilled := cmd.ProcessState != nil && killedByOOM(cmd.ProcessState, oomChan)
+		cancelOOM()
+		s.mu.Lock()
+		shouldRun, checkURL := s.shouldRun, s.checkURL
+		s.mu.Unlock()
+		// checked while it still might be serving, but only if the exit wasn't asked for
+		var detached string
+		if shouldRun {
+			detached = s.checkDetached(cmd.ProcessState, checkURL)
+		}
+		stdio.Flush()
+		stderr.Flush()
+		stdio.DeleteMatcher("servingURLs")
+		if exporter != nil {
+			exporter.Close()
+			if limited := exporter.RateLimited(); limited > 0 {
+				s.logger.Warnf("%d %s log lines weren't forwarded, as they exceeded otel_logs_max_bytes_per_sec", limited, SubsysName)
+			}
+		}
 		s.mu.Lock()
 		defer s.mu.Unlock()
 		s.running = false
-		s.logger.Infof("%s exited", SubsysName)
-		if err != nil {
-			s.logger.Errorw("error while getting process status", "error", err)
+		s.healthySince = time.Time{}
+		s.detached = detached
+		s.lastExitTime = time.Now()
+		s.lastExitSignal, _ = exitSignal(cmd.ProcessState)
+		if s.lastExitSignal != 0 {
+			s.logger.Infof("%s exited, killed by %s", SubsysName, unix.SignalName(s.lastExitSignal))
+		} else {
+			s.logger.Infof("%s exited", SubsysName)
 		}
-		if s.cmd.ProcessState != nil {
-			s.lastExit = s.cmd.ProcessState.ExitCode()
-			if s.lastExit != 0 {
+		if cmd.ProcessState != nil {
+			s.lastExit = cmd.ProcessState.ExitCode()
+			s.lastCrash = crashReason(cmd.ProcessState, panics.panicLine())
+			if oomKilled {
+				s.lastCrash = oomCrashReason
+			}
+			// a crash is never intentional, whatever the exit code
+			s.expectedExit = s.lastCrash == "" && slices.Contains(cfg.expectedExitCodes, s.lastExit)
+		}
+		if s.lastCrash != "" {
+			s.logger.Errorw(fmt.Sprintf("%s crashed", SubsysName), "crash", s.lastCrash, "exit code", s.lastExit)
+		} else if s.expectedExit {
+			s.logger.Infow("expected exit code, not restarting", "exit code", s.lastExit)
+			s.timeline.record(StateStopped, fmt.Sprintf("expected exit code %d", s.lastExit))
+		} else {
+			if err != nil {
+				s.logger.Errorw("error while getting process status", "error", err)
+			}
+			if s.lastExitSignal != 0 {
+				s.logger.Errorw("killed by signal", "signal", unix.SignalName(s.lastExitSignal))
+			} else if cmd.ProcessState != nil && s.lastExit != 0 {
 				s.logger.Errorw("non-zero exit code", "exit code", s.lastExit)
 			}
 		}
-		close(s.exitChan)
+		// exits while stopping were asked for
+		if s.shouldRun && !s.expectedExit {
+			if s.lastStatus != nil {
+				s.logger.Warnw(fmt.Sprintf("last known %s process status", SubsysName), "status", s.lastStatus)
+			}
+			s.recordUnexpectedExit(cfg, ExitRecord{Time: time.Now(), Code: s.lastExit, Crash: s.lastCrash, Status: s.lastStatus})
+			detail := s.lastCrash
+			if detail == "" && s.lastExitSignal != 0 {
+				detail = "killed by " + unix.SignalName(s.lastExitSignal)
+			} else if detail == "" {
+				detail = fmt.Sprintf("exit code %d", s.lastExit)
+			}
+			s.timeline.record(StateCrashed, detail)
+		}
+		close(exitChan)
 	}()
 
+	// started now rather than with the other background tasks, as a crash during startup is worth diagnosing too
+	if cfg.statusCaptureInterval > 0 {
+		go s.captureLastStatus(cmd.Process.Pid, cfg.statusCaptureInterval, exitChan)
+	}
+
+	// nil (never ready) unless a startup probe is configured
+	var probeChan <-chan struct{}
+	if len(cfg.startupProbe) > 0 {
+		probeCtx, cancelProbe := context.WithCancel(ctx)
+		defer cancelProbe()
+		probeChan = s.pollStartupProbe(probeCtx, cfg, exitChan)
+	}
+
 	select {
 	case matches := <-c:
-		s.checkURL = matches[1]
-		s.checkURLAlt = strings.Replace(matches[2], "0.0.0.0", "localhost", 1)
-		s.logger.Infof("healthcheck URLs: %s %s", s.checkURL, s.checkURLAlt)
+		s.mu.Lock()
+		s.addServingURL(matches)
+		s.checkURL, s.checkURLAlt = "", ""
+		s.selectCheckURL(cfg)
+		checkURL := s.checkURL
+		s.mu.Unlock()
+		if len(cfg.readyComponents) > 0 {
+			if err := s.waitForComponents(ctx, cfg, checkURL, exitChan); err != nil {
+				return err
+			}
+		}
 		s.logger.Infof("%s started", SubsysName)
+		s.timeline.record(startedState, string(kind))
+		s.startBackgroundTasks(cfg, cmd.Process.Pid, exitChan)
 		return nil
+	case <-probeChan:
+		s.logger.Infof("%s started (startup probe succeeded)", SubsysName)
+		s.timeline.record(startedState, string(kind)+", startup probe succeeded")
+		s.startBackgroundTasks(cfg, cmd.Process.Pid, exitChan)
+		return nil
+	case fatal := <-fatalChan:
+		return fatal
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-time.After(globalConfig.Load().startTimeout):
-		return errw.New("startup timed out")
-	case <-s.exitChan:
+	case <-time.After(cfg.startTimeout):
+		return ErrStartupTimeout
+	case <-exitChan:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.detached != "" {
+			return errw.Wrapf(ErrProcessDetached, "%s exited during startup, but %s", SubsysName, s.detached)
+		}
 		return errw.New("startup failed")
 	}
 }
 
+// LastExitSignal returns the signal that terminated viam-server the last time it exited, if one did.
+func (s *viamServer) LastExitSignal() (syscall.Signal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastExitSignal, s.lastExitSignal != 0
+}
+
+// waitRestartDelay waits until at least delay has passed since lastExit (if there was one), or ctx is done.
+func waitRestartDelay(ctx context.Context, lastExit time.Time, delay time.Duration) error {
+	if lastExit.IsZero() {
+		return nil
+	}
+	return sleepContext(ctx, delay-time.Since(lastExit))
+}
+
+// restartJitter returns a uniformly random duration in [0, jitterMax), so a fleet of robots restarting for the same
+// reason at the same time (such as an update) don't all hit shared services at once. Zero if jitterMax isn't positive.
+func restartJitter(jitterMax time.Duration) time.Duration {
+	if jitterMax <= 0 {
+		return 0
+	}
+	//nolint:gosec
+	return time.Duration(rand.Int63n(int64(jitterMax)))
+}
+
+// sleepContext waits for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// startBackgroundTasks starts the configured goroutines that run alongside the process, until exitChan is closed.
+func (s *viamServer) startBackgroundTasks(cfg *viamServerConfig, pid int, exitChan chan struct{}) {
+	if cfg.watchNetworkChanges {
+		go s.watchNetworkChanges(exitChan)
+	}
+	if cfg.processStatsInterval > 0 {
+		go s.logProcessStats(pid, cfg.processStatsInterval, exitChan)
+	}
+	if cfg.restartSchedule != nil {
+		go s.runRestartSchedule(cfg.restartSchedule, time.Now(), exitChan)
+	}
+	if cfg.memoryLimitSoftBytes > 0 && cfg.memorySampleInterval > 0 {
+		go s.watchMemorySoftLimit(cfg, pid, exitChan)
+	}
+	if cfg.configIntegrityInterval > 0 && s.configHash != nil {
+		go s.watchConfigIntegrity(cfg, exitChan)
+	}
+	if s.configStore != nil {
+		go s.watchConfigStore(exitChan)
+	}
+}
+
+// recoverWaitPanic is deferred by the goroutine waiting on the process. A panic there would otherwise take down the
+// whole agent, so instead the process (which can no longer be tracked) is killed, and treated as having exited.
+func (s *viamServer) recoverWaitPanic(cmd *exec.Cmd, exitChan chan struct{}) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	s.logger.Errorw(fmt.Sprintf("recovered from panic while waiting on %s", SubsysName), "panic", r, "stack", string(debug.Stack()))
+	if cmd.ProcessState == nil {
+		if err := agent.KillProcessGroup(cmd.Process.Pid, syscall.SIGKILL); err != nil {
+			s.logger.Error(err)
+		}
+		//nolint:errcheck
+		cmd.Wait()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.healthySince = time.Time{}
+	// only the waiting goroutine closes this, so it's safe to check first
+	select {
+	case <-exitChan:
+	default:
+		close(exitChan)
+	}
+}
+
+// launch starts cmd, but gives up waiting after timeout. cmd.Start() can't be interrupted, so if it does eventually
+// succeed, the abandoned process is killed and reaped in the background.
+func launch(ctx context.Context, logger logging.Logger, cmd *exec.Cmd, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := make(chan error, 1)
+	go func() {
+		started <- cmd.Start()
+	}()
+
+	select {
+	case err := <-started:
+		return errw.Wrapf(err, "starting %s", SubsysName)
+	case <-ctx.Done():
+		go func() {
+			if err := <-started; err == nil {
+				logger.Warnf("killing %s launched after the launch timeout", SubsysName)
+				if err := agent.KillProcessGroup(cmd.Process.Pid, syscall.SIGKILL); err != nil {
+					logger.Error(err)
+				}
+				//nolint:errcheck
+				cmd.Wait()
+			}
+		}()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrLaunchTimeout
+		}
+		return ctx.Err()
+	}
+}
+
 func (s *viamServer) Stop(ctx context.Context) error {
+	_, _, err := s.stop(ctx)
+	return err
+}
+
+// stop stops viam-server, returning whether it was running and the new stopEpoch.
+func (s *viamServer) stop(ctx context.Context) (bool, uint64, error) {
 	s.startStopMu.Lock()
 	defer s.startStopMu.Unlock()
 
 	s.mu.Lock()
 	running := s.running
 	s.shouldRun = false
+	s.expectedExit = false
+	s.stopEpoch++
+	epoch := s.stopEpoch
 	s.mu.Unlock()
 
 	if !running {
-		return nil
+		return false, epoch, nil
 	}
 
 	// interrupt early in startup
 	if s.cmd == nil {
-		return nil
+		return true, epoch, nil
 	}
 
 	s.logger.Infof("Stopping %s", SubsysName)
 
-	err := s.cmd.Process.Signal(syscall.SIGTERM)
-	if err != nil {
-		s.logger.Error(err)
+	sequence := globalConfig.Load().stopSignalSequence
+	if len(sequence) == 0 {
+		sequence = defaultStopSignalSequence
 	}
-
-	if s.waitForExit(ctx, stopTermTimeout) {
-		s.logger.Infof("%s successfully stopped", SubsysName)
-		return nil
+	for i, step := range sequence {
+		if i > 0 {
+			s.logger.Warnf("%s refused to exit, sending %s", SubsysName, unix.SignalName(step.Signal))
+		}
+		if err := s.sendStopSignal(step); err != nil {
+			s.logger.Error(err)
+		}
+		if s.waitForExit(ctx, step.WaitDuration) {
+			s.logger.Infof("%s successfully stopped by %s", SubsysName, unix.SignalName(step.Signal))
+			s.timeline.record(StateStopped, "by "+unix.SignalName(step.Signal))
+			s.verifyStopped(ctx)
+			s.runPostStopHook(ctx)
+			return true, epoch, nil
+		}
 	}
 
-	s.logger.Warnf("%s refused to exit, killing", SubsysName)
-	err = syscall.Kill(-s.cmd.Process.Pid, syscall.SIGKILL)
-	if err != nil {
+	return true, epoch, errw.Wrapf(ErrCannotStop, "%s still running after %s", SubsysName,
+		unix.SignalName(sequence[len(sequence)-1].Signal))
+}
+
+// runPostStopHook runs the configured post-stop hook, if any. Failures are only logged, as the process is already stopped.
+func (s *viamServer) runPostStopHook(ctx context.Context) {
+	cfg := globalConfig.Load()
+	if err := agent.RunHook(ctx, s.logger, "post-stop", cfg.postStopHook, cfg.postStopTimeout); err != nil {
 		s.logger.Error(err)
 	}
+}
 
-	if s.waitForExit(ctx, stopKillTimeout) {
-		s.logger.Infof("%s successfully killed", SubsysName)
-		return nil
+// runOnHealthyHook runs the configured on-healthy hook, if any. Failures are only logged, as viam-server is already
+// healthy either way.
+func (s *viamServer) runOnHealthyHook() {
+	cfg := globalConfig.Load()
+	if err := agent.RunHook(context.Background(), s.logger, "on-healthy", cfg.onHealthyHook, cfg.onHealthyTimeout); err != nil {
+		s.logger.Error(err)
 	}
-
-	return errw.Errorf("%s process couldn't be killed", SubsysName)
 }
 
 func (s *viamServer) waitForExit(ctx context.Context, timeout time.Duration) bool {
@@ -247,52 +1139,213 @@ func (s *viamServer) waitForExit(ctx context.Context, timeout time.Duration) boo
 }
 
 func (s *viamServer) HealthCheck(ctx context.Context) (errRet error) {
+	// the checks themselves run unlocked, as with retries or exec_healthcheck they can take a while, which mustn't
+	// hold up Stop or the exit being recorded
 	s.startStopMu.Lock()
-	defer s.startStopMu.Unlock()
+	s.mu.Lock()
+	run := s.exitChan
+	running := s.running
+	checkURL, checkURLAlt := s.checkURL, s.checkURLAlt
+	var pid int
+	if s.cmd != nil && s.cmd.Process != nil {
+		pid = s.cmd.Process.Pid
+	}
+	if !running {
+		errRet = s.notRunningError()
+	}
+	s.mu.Unlock()
+	s.startStopMu.Unlock()
+
+	// a failure that healthcheck_failure_actions says not to restart for, still unhealthy for readiness
+	var tolerated error
+	var failure HealthCheckFailure
+	if running {
+		failure, tolerated, errRet = s.checkRunning(ctx, checkURL, checkURLAlt, pid)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if !s.running {
-		return errw.Errorf("%s not running", SubsysName)
+	if s.exitChan != run {
+		// restarted meanwhile, so this says nothing about the current process
+		return errRet
+	}
+	if running {
+		s.lastHealthCheckFailure = failure
+	}
+	unhealthy := errRet
+	if unhealthy == nil {
+		unhealthy = tolerated
 	}
-	if s.checkURL == "" {
-		return errw.Errorf("can't find listening URL for %s", SubsysName)
+	if unhealthy != nil || !s.running {
+		if unhealthy != nil && !s.healthySince.IsZero() {
+			s.timeline.record(StateUnhealthy, unhealthy.Error())
+		}
+		s.healthySince = time.Time{}
+	} else if s.healthySince.IsZero() {
+		s.healthySince = time.Now()
+		s.timeline.record(StateHealthy, "")
+		if !s.onHealthyRan {
+			s.onHealthyRan = true
+			go s.runOnHealthyHook()
+		}
+	}
+	if cfg := globalConfig.Load(); cfg.configQuarantine && !s.lastGoodSaved && !s.healthySince.IsZero() &&
+		time.Since(s.healthySince) >= cfg.readinessSteadyState {
+		s.lastGoodSaved = true
+		s.saveLastGoodConfig()
 	}
+	return errRet
+}
 
-	for _, url := range []string{s.checkURL, s.checkURLAlt} {
-		s.logger.Debugf("starting healthcheck for %s using %s", SubsysName, url)
+// notRunningError is HealthCheck's result while viam-server isn't running. Must be called with mu held.
+func (s *viamServer) notRunningError() error {
+	if s.expectedExit {
+		s.logger.Debugf("%s exited with expected code %d", SubsysName, s.lastExit)
+		return nil
+	}
+	if s.lastCrash != "" {
+		return errw.Errorf("%s not running, crashed: %s", SubsysName, s.lastCrash)
+	}
+	if s.lastExitSignal != 0 {
+		return errw.Errorf("%s not running, killed by %s", SubsysName, unix.SignalName(s.lastExitSignal))
+	}
+	return errw.Errorf("%s not running", SubsysName)
+}
 
-		timeoutCtx, cancelFunc := context.WithTimeout(ctx, time.Second*10)
-		defer cancelFunc()
+// checkRunning does the healthchecks of a running viam-server, with the URLs and pid as they were when HealthCheck
+// was called. It returns the kind of failure (empty if it passed), and a failure that's tolerated per
+// healthcheck_failure_actions separately from err. It's called without mu held.
+func (s *viamServer) checkRunning(
+	ctx context.Context, checkURL, checkURLAlt string, pid int,
+) (failure HealthCheckFailure, tolerated, errRet error) {
+	if checkURL == "" {
+		// started via the startup probe without ever logging the serving URLs, so the probe is all there is to check
+		if cfg := globalConfig.Load(); len(cfg.startupProbe) > 0 {
+			return "", nil, runProbe(ctx, cfg.startupProbe, cfg.startupProbeTimeout)
+		}
+		return "", nil, errw.Errorf("can't find listening URL for %s", SubsysName)
+	}
 
-		req, err := http.NewRequestWithContext(timeoutCtx, http.MethodGet, url, nil)
-		if err != nil {
+	cfg := globalConfig.Load()
+	checkType := cfg.healthCheckType
+	if checkType == "" {
+		checkType = HealthCheckHTTP
+	}
+	checker, err := getHealthChecker(checkType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// viam-server was reachable, but the exec_healthcheck command failed
+	var execFailed bool
+	for _, url := range []string{checkURL, checkURLAlt} {
+		s.logger.Debugf("starting %s healthcheck for %s using %s", checkType, SubsysName, url)
+
+		if err := checkWithRetry(ctx, checker, url, cfg.healthCheckAttempts, cfg.healthCheckBackoff); err != nil {
 			errRet = errors.Join(errRet, errw.Wrapf(err, "checking %s status", SubsysName))
 			continue
 		}
+		s.logger.Debugf("healthcheck for %s is good", SubsysName)
+		if len(cfg.execHealthCheck) > 0 {
+			if err := NewExecHealthCheck(cfg.execHealthCheck, cfg.execHealthCheckTimeout, pid).Check(ctx, url); err != nil {
+				errRet = err
+				execFailed = true
+				break
+			}
+		}
+		return "", nil, nil
+	}
 
-		// disabling the cert verification because it doesn't work in offline mode (when connecting to localhost)
-		//nolint:gosec
-		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
-
-		resp, err := client.Do(req)
+	// if viam-server is in its own network namespace, the host may not be able to reach it even though it's fine
+	if !execFailed && checkType == HealthCheckHTTP && !strings.HasPrefix(checkURL, unixScheme) &&
+		pid != 0 && inSeparateNetNamespace(pid) {
+		authHeader, authValue, err := healthCheckAuth(cfg)
 		if err != nil {
-			errRet = errors.Join(errRet, errw.Wrapf(err, "checking %s status", SubsysName))
-			continue
+			return classifyHealthCheckError(errRet), nil, errors.Join(errRet, err)
 		}
+		if err := namespaceHealthCheck(ctx, pid, checkURL, authHeader, authValue); err != nil {
+			return classifyHealthCheckError(errRet), nil, errors.Join(errRet, err)
+		}
+		// restarting won't fix this, so it's reported but not treated as a failure
+		s.logger.Errorw(fmt.Sprintf("%s is healthy inside its network namespace but unreachable from the host, "+
+			"this is a namespace networking issue", SubsysName), "error", errRet)
+		return "", nil, nil
+	}
 
-		defer func() {
-			errRet = errors.Join(errRet, resp.Body.Close())
-		}()
+	failure = classifyHealthCheckError(errRet)
+	switch cfg.healthCheckFailureActions[failure] {
+	case agent.UnhealthyActionAlert:
+		s.logger.Errorw(fmt.Sprintf("%s healthcheck failed, not restarting per healthcheck_failure_actions", SubsysName),
+			"category", failure, "error", errRet)
+		return failure, errRet, nil
+	case agent.UnhealthyActionNone:
+		s.logger.Debugw(fmt.Sprintf("%s healthcheck failed, ignored per healthcheck_failure_actions", SubsysName),
+			"category", failure, "error", errRet)
+		return failure, errRet, nil
+	}
+	return failure, nil, errRet
+}
 
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			errRet = errors.Join(errRet, errw.Wrapf(err, "checking %s status, got code: %d", SubsysName, resp.StatusCode))
-			continue
+// healthCheckClient returns the client and request URL to healthcheck checkURL with. For a unix socket
+// (unix:///path/to.sock), the client dials the socket and the URL gets a placeholder host.
+func healthCheckClient(checkURL string) (*http.Client, string) {
+	// disabling the cert verification because it doesn't work in offline mode (when connecting to localhost)
+	//nolint:gosec
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	socketPath, ok := strings.CutPrefix(checkURL, unixScheme)
+	if !ok {
+		return &http.Client{Transport: transport}, checkURL
+	}
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+	return &http.Client{Transport: transport}, "http://localhost/"
+}
+
+// healthCheckAuth returns the header name and value to authenticate healthchecks with, or empty strings if not configured.
+// Without a custom header name, the token is sent as a bearer token.
+func healthCheckAuth(cfg *viamServerConfig) (string, string, error) {
+	token := cfg.healthCheckAuthToken
+	if cfg.healthCheckAuthTokenFile != "" {
+		//nolint:gosec
+		raw, err := os.ReadFile(cfg.healthCheckAuthTokenFile)
+		if err != nil {
+			return "", "", errw.Wrap(err, "reading healthcheck auth token")
 		}
-		s.logger.Debugf("healthcheck for %s is good", SubsysName)
-		return nil
+		token = strings.TrimSpace(string(raw))
+	}
+	if token == "" {
+		return "", "", nil
 	}
+	if cfg.healthCheckAuthHeader != "" {
+		return cfg.healthCheckAuthHeader, token, nil
+	}
+	return "Authorization", "Bearer " + token, nil
+}
 
-	return errRet
+// Readiness runs a fresh HealthCheck, and then also requires viam-server to have been continuously healthy
+// for the configured steady state duration, and any ready_components to be ready. Unlike HealthCheck failures,
+// this doesn't cause a restart.
+func (s *viamServer) Readiness(ctx context.Context) error {
+	if err := s.HealthCheck(ctx); err != nil {
+		return err
+	}
+	cfg := globalConfig.Load()
+	s.mu.Lock()
+	running, healthySince, checkURL := s.running, s.healthySince, s.checkURL
+	s.mu.Unlock()
+	if !running {
+		return errw.Errorf("%s not running", SubsysName)
+	}
+	if healthyFor := time.Since(healthySince); healthyFor < cfg.readinessSteadyState {
+		return errw.Errorf("%s has only been healthy for %s of %s", SubsysName, healthyFor.Round(time.Second),
+			cfg.readinessSteadyState)
+	}
+	if len(cfg.readyComponents) > 0 && checkURL != "" {
+		return checkComponents(ctx, cfg, checkURL)
+	}
+	return nil
 }
 
 func (s *viamServer) Update(ctx context.Context, cfg *pb.DeviceSubsystemConfig, newVersion bool) (bool, error) {
@@ -303,16 +1356,51 @@ func (s *viamServer) Update(ctx context.Context, cfg *pb.DeviceSubsystemConfig,
 		s.logger.Info("awaiting user restart to run new viam-server version")
 		s.shouldRun = false
 	}
+	if !proto.Equal(cfg, s.updateConf) {
+		if s.expectedExit {
+			s.logger.Infof("config changed, %s can be started again after its expected exit", SubsysName)
+			s.expectedExit = false
+		}
+		s.updateConf = cfg
+	}
 	globalConfig.Store(configFromProto(s.logger, cfg))
 	// always return false on the needRestart flag, as we await the user to kill/restart viam-server directly
 	return false, nil
 }
 
 func NewSubsystem(ctx context.Context, logger logging.Logger, updateConf *pb.DeviceSubsystemConfig) (subsystems.Subsystem, error) {
+	return newSubsystem(ctx, logger, updateConf)
+}
+
+func newSubsystem(
+	ctx context.Context, logger logging.Logger, updateConf *pb.DeviceSubsystemConfig, opts ...Option,
+) (subsystems.Subsystem, error) {
+	if err := agent.EnsureViamDirs(agent.ViamDirs); err != nil {
+		return nil, err
+	}
 	setFastStart(updateConf)
 
 	globalConfig.Store(configFromProto(logger, updateConf))
-	return agent.NewAgentSubsystem(ctx, SubsysName, logger, &viamServer{logger: logger})
+	vs := &viamServer{logger: logger, preconditions: registry.GetPreconditions(SubsysName), updateConf: updateConf}
+	for _, opt := range opts {
+		opt(vs)
+	}
+	var sub *agent.AgentSubsystem
+	sub, err := agent.NewAgentSubsystem(ctx, SubsysName, logger, vs,
+		// hooks run with the AgentSubsystem locked, so its cache can be read directly
+		agent.WithPostStartHook(func(ctx context.Context) error {
+			return vs.advertise(sub.CacheData.CurrentVersion)
+		}),
+		agent.WithPreStopHook(func(ctx context.Context) error {
+			vs.stopAdvertising()
+			return nil
+		}),
+	)
+	// read without locking, like the hooks, see AgentSubsystem.Lookup
+	if err == nil && vs.manifest == nil {
+		vs.manifest = sub
+	}
+	return sub, err
 }
 
 func setFastStart(cfg *pb.DeviceSubsystemConfig) {
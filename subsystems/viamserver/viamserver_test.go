@@ -0,0 +1,264 @@
+package viamserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/viamrobotics/agent"
+	pb "go.viam.com/api/app/agent/v1"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestReadiness(t *testing.T) {
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{startTimeout: defaultStartTimeout, readinessSteadyState: time.Millisecond * 100})
+
+	healthy := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+
+	// healthy, but not yet for long enough
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, s.Readiness(ctx), test.ShouldNotBeNil)
+
+	time.Sleep(time.Millisecond * 150)
+	test.That(t, s.Readiness(ctx), test.ShouldBeNil)
+
+	// a failed check restarts the steady state period
+	healthy = false
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+	healthy = true
+	test.That(t, s.Readiness(ctx), test.ShouldNotBeNil)
+}
+
+func TestHealthCheckNon2xx(t *testing.T) {
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	globalConfig.Store(&viamServerConfig{startTimeout: defaultStartTimeout})
+
+	ctx := context.Background()
+	for _, code := range []int{http.StatusOK, http.StatusNoContent, http.StatusNotFound, http.StatusServiceUnavailable} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+		s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+		err := s.HealthCheck(ctx)
+		srv.Close()
+		if code < 300 {
+			test.That(t, err, test.ShouldBeNil)
+		} else {
+			test.That(t, err, test.ShouldNotBeNil)
+		}
+	}
+}
+
+func TestExpectedExitHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t), lastExit: 3}
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+
+	s.expectedExit = true
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, s.Readiness(ctx), test.ShouldNotBeNil)
+}
+
+func TestExpectedExitNoRestart(t *testing.T) {
+	binPath := fakeViamServer(t)
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte("#!/bin/sh\n"+
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'`+"\n"+
+		"sleep 0.2\nexit 3\n"), 0o755), test.ShouldBeNil)
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	newConf := func(code float64) *pb.DeviceSubsystemConfig {
+		attrs, err := structpb.NewStruct(map[string]any{"expected_exit_codes": []any{code}})
+		test.That(t, err, test.ShouldBeNil)
+		return &pb.DeviceSubsystemConfig{Attributes: attrs}
+	}
+	globalConfig.Store(configFromProto(logger, newConf(3)))
+	s := &viamServer{logger: logger, updateConf: newConf(3)}
+
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	firstCmd := s.cmd
+	test.That(t, s.waitForExit(ctx, time.Second*10), test.ShouldBeTrue)
+	s.mu.Lock()
+	test.That(t, s.expectedExit, test.ShouldBeTrue)
+	s.mu.Unlock()
+
+	// the manager calls Update and Start every cycle, which mustn't relaunch it while the config is the same
+	_, err := s.Update(ctx, newConf(3), false)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.cmd, test.ShouldEqual, firstCmd)
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+
+	// a changed config clears it
+	_, err = s.Update(ctx, newConf(4), false)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.cmd, test.ShouldNotEqual, firstCmd)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+
+	// as does a stop
+	s.mu.Lock()
+	s.expectedExit = true
+	s.mu.Unlock()
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	stoppedCmd := s.cmd
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.cmd, test.ShouldNotEqual, stoppedCmd)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+}
+
+func TestHealthCheckAuth(t *testing.T) {
+	prevCfg := globalConfig.Load()
+	t.Cleanup(func() { globalConfig.Store(prevCfg) })
+
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: srv.URL, checkURLAlt: srv.URL}
+
+	globalConfig.Store(&viamServerConfig{healthCheckAuthToken: "static"})
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, gotHeader.Get("Authorization"), test.ShouldEqual, "Bearer static")
+
+	// the file is re-read on each check, so rotations are picked up
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	test.That(t, os.WriteFile(tokenFile, []byte("first\n"), 0o600), test.ShouldBeNil)
+	globalConfig.Store(&viamServerConfig{healthCheckAuthToken: "static", healthCheckAuthTokenFile: tokenFile, healthCheckAuthHeader: "X-Device-Token"})
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, gotHeader.Get("X-Device-Token"), test.ShouldEqual, "first")
+
+	test.That(t, os.WriteFile(tokenFile, []byte("second"), 0o600), test.ShouldBeNil)
+	test.That(t, s.HealthCheck(ctx), test.ShouldBeNil)
+	test.That(t, gotHeader.Get("X-Device-Token"), test.ShouldEqual, "second")
+
+	test.That(t, os.Remove(tokenFile), test.ShouldBeNil)
+	test.That(t, s.HealthCheck(ctx), test.ShouldNotBeNil)
+}
+
+// fakeViamServer installs a script as the viam-server binary, in temporary ViamDirs.
+func fakeViamServer(t *testing.T) string {
+	t.Helper()
+	orig := make(map[string]string)
+	root := t.TempDir()
+	for k, v := range agent.ViamDirs {
+		orig[k] = v
+		agent.ViamDirs[k] = filepath.Join(root, k)
+		test.That(t, os.MkdirAll(agent.ViamDirs[k], 0o755), test.ShouldBeNil)
+	}
+	t.Cleanup(func() {
+		for k, v := range orig {
+			agent.ViamDirs[k] = v
+		}
+	})
+
+	binPath := filepath.Join(agent.ViamDirs["bin"], SubsysName)
+	script := "#!/bin/sh\n" +
+		`echo 'serving {"url": "http://localhost:8080", "alt_url": "http://localhost:8081"}'` + "\n" +
+		"exec sleep 30\n"
+	//nolint:gosec
+	test.That(t, os.WriteFile(binPath, []byte(script), 0o755), test.ShouldBeNil)
+	return binPath
+}
+
+func TestStartBinaryMissing(t *testing.T) {
+	binPath := fakeViamServer(t)
+	ctx := context.Background()
+	s := &viamServer{logger: logging.NewTestLogger(t)}
+
+	test.That(t, s.Start(ctx), test.ShouldBeNil)
+	test.That(t, s.checkURL, test.ShouldEqual, "http://localhost:8080")
+
+	// the running process is unaffected, but it can't be started again
+	test.That(t, os.Remove(binPath), test.ShouldBeNil)
+	test.That(t, s.Stop(ctx), test.ShouldBeNil)
+	err := s.Start(ctx)
+	test.That(t, errors.Is(err, ErrBinaryMissing), test.ShouldBeTrue)
+	test.That(t, s.cmd.ProcessState, test.ShouldNotBeNil)
+}
+
+func TestHealthCheckUnixSocket(t *testing.T) {
+	// unix socket paths have a short length limit, so t.TempDir() may be too deep
+	dir, err := os.MkdirTemp("", "viamserver")
+	test.That(t, err, test.ShouldBeNil)
+	t.Cleanup(func() {
+		//nolint:errcheck
+		os.RemoveAll(dir)
+	})
+	socketPath := filepath.Join(dir, "viam.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	test.That(t, err, test.ShouldBeNil)
+	srv := &http.Server{
+		ReadHeaderTimeout: time.Second,
+		Handler:           http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	}
+	//nolint:errcheck
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	checkURL := "unix://" + socketPath
+	s := &viamServer{logger: logging.NewTestLogger(t), running: true, checkURL: checkURL, checkURLAlt: checkURL}
+	test.That(t, s.HealthCheck(context.Background()), test.ShouldBeNil)
+
+	srv.Close()
+	test.That(t, s.HealthCheck(context.Background()), test.ShouldNotBeNil)
+}
+
+func TestWaitRestartDelay(t *testing.T) {
+	ctx := context.Background()
+	// never exited, or exited long enough ago
+	test.That(t, waitRestartDelay(ctx, time.Time{}, time.Hour), test.ShouldBeNil)
+	test.That(t, waitRestartDelay(ctx, time.Now().Add(-time.Second), time.Millisecond*500), test.ShouldBeNil)
+
+	start := time.Now()
+	test.That(t, waitRestartDelay(ctx, start, time.Millisecond*100), test.ShouldBeNil)
+	test.That(t, time.Since(start), test.ShouldBeGreaterThanOrEqualTo, time.Millisecond*100)
+
+	// a shutdown during the delay aborts it
+	cancelCtx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(time.Millisecond*50, cancel)
+	start = time.Now()
+	err := waitRestartDelay(cancelCtx, start, time.Hour)
+	test.That(t, errors.Is(err, context.Canceled), test.ShouldBeTrue)
+	test.That(t, time.Since(start), test.ShouldBeLessThan, time.Second*5)
+}
+
+func TestRestartJitter(t *testing.T) {
+	test.That(t, restartJitter(0), test.ShouldEqual, 0)
+	test.That(t, restartJitter(-time.Second), test.ShouldEqual, 0)
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		jitter := restartJitter(time.Second)
+		test.That(t, jitter, test.ShouldBeGreaterThanOrEqualTo, 0)
+		test.That(t, jitter, test.ShouldBeLessThan, time.Second)
+		seen[jitter] = true
+	}
+	// actually random, rather than a fixed offset
+	test.That(t, len(seen), test.ShouldBeGreaterThan, 1)
+}
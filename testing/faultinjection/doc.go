@@ -0,0 +1,3 @@
+// Package faultinjection lets tests force panics inside subsystem goroutines, to exercise recovery code.
+// Hooks can only be installed in builds with the faultinjection tag; otherwise Check always returns nil.
+package faultinjection
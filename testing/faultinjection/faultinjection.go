@@ -0,0 +1,43 @@
+//go:build faultinjection
+
+package faultinjection
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	mu    sync.Mutex
+	hooks = map[string]func(){}
+)
+
+func hookKey(subsysName, goroutine string) string {
+	return subsysName + "/" + goroutine
+}
+
+// PanicAt makes the next Check for the named goroutine in the named subsystem return a func that panics.
+func PanicAt(subsysName, goroutine string) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks[hookKey(subsysName, goroutine)] = func() {
+		panic(fmt.Sprintf("faultinjection: injected panic in %s %s goroutine", subsysName, goroutine))
+	}
+}
+
+// ResetAll removes all installed hooks.
+func ResetAll() {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = map[string]func(){}
+}
+
+// Check returns the hook installed for the goroutine, if any, and removes it so it only fires once.
+func Check(subsysName, goroutine string) func() {
+	mu.Lock()
+	defer mu.Unlock()
+	key := hookKey(subsysName, goroutine)
+	hook := hooks[key]
+	delete(hooks, key)
+	return hook
+}
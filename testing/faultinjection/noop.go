@@ -0,0 +1,8 @@
+//go:build !faultinjection
+
+package faultinjection
+
+// Check returns nil, as hooks can't be installed without the faultinjection build tag.
+func Check(subsysName, goroutine string) func() {
+	return nil
+}
@@ -3,6 +3,7 @@ package agent
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"errors"
@@ -14,10 +15,13 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 	errw "github.com/pkg/errors"
 	"github.com/ulikunitz/xz"
 	"golang.org/x/sys/unix"
@@ -25,6 +29,9 @@ import (
 
 var ViamDirs = map[string]string{"viam": "/opt/viam"}
 
+// ErrSameProcessGroup is returned when a process group kill would also signal the agent itself.
+var ErrSameProcessGroup = errors.New("target shares the agent's process group")
+
 func init() {
 	ViamDirs["bin"] = filepath.Join(ViamDirs["viam"], "bin")
 	ViamDirs["cache"] = filepath.Join(ViamDirs["viam"], "cache")
@@ -32,6 +39,43 @@ func init() {
 	ViamDirs["etc"] = filepath.Join(ViamDirs["viam"], "etc")
 }
 
+// EnsureViamDirs creates any of dirs (keyed by name, like ViamDirs) that don't exist, and verifies they're all directories
+// (or symlinks to them.) Unlike InitPaths, it doesn't check ownership or permissions of existing directories.
+func EnsureViamDirs(dirs map[string]string) error {
+	names := make([]string, 0, len(dirs))
+	for name := range dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := dirs[name]
+		//nolint:gosec
+		if err := os.MkdirAll(p, 0o755); err != nil {
+			return errw.Wrapf(unwrapPathError(err), "failed to create viam dir '%s' at %s", name, p)
+		}
+		info, err := os.Lstat(p)
+		if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			info, err = os.Stat(p)
+		}
+		if err != nil {
+			return errw.Wrapf(unwrapPathError(err), "failed to check viam dir '%s' at %s", name, p)
+		}
+		if !info.IsDir() {
+			return errw.Errorf("viam dir '%s' at %s is not a directory", name, p)
+		}
+	}
+	return nil
+}
+
+// unwrapPathError drops the operation and path from a *fs.PathError, for when the caller's message already has the path.
+func unwrapPathError(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
 func InitPaths() error {
 	uid := os.Getuid()
 	for _, p := range ViamDirs {
@@ -99,7 +143,7 @@ func DownloadFile(ctx context.Context, rawURL string) (outPath string, errRet er
 			}
 		}()
 
-		_, err = io.Copy(outfd, infd)
+		_, err = io.Copy(outfd, &limitedReader{ctx: ctx, reader: infd, limiter: GlobalBandwidthLimiter})
 		if err != nil {
 			return "", err
 		}
@@ -115,6 +159,7 @@ func DownloadFile(ctx context.Context, rawURL string) (outPath string, errRet er
 	if err != nil {
 		return "", errw.Wrap(err, "checking viam-server status")
 	}
+	req.Header.Set("Accept-Encoding", "zstd, br, gzip")
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -124,6 +169,12 @@ func DownloadFile(ctx context.Context, rawURL string) (outPath string, errRet er
 		errRet = errors.Join(errRet, resp.Body.Close())
 	}()
 
+	encoding := resp.Header.Get("Content-Encoding")
+	if encoding == "" && strings.HasSuffix(parsedURL.Path, ".zst") {
+		encoding = "zstd"
+		outPath = strings.TrimSuffix(outPath, ".zst")
+	}
+
 	//nolint:gosec
 	if err := os.MkdirAll(ViamDirs["tmp"], 0o755); err != nil {
 		return "", err
@@ -140,15 +191,79 @@ func DownloadFile(ctx context.Context, rawURL string) (outPath string, errRet er
 		}
 	}()
 
-	_, err = io.Copy(out, resp.Body)
+	written, err := io.Copy(out, &limitedReader{ctx: ctx, reader: resp.Body, limiter: GlobalBandwidthLimiter})
 	if err != nil && !os.IsNotExist(err) {
 		errRet = errors.Join(errRet, err)
 	}
 
+	// make sure the (possibly compressed) transfer is complete before decompressing it
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		errRet = errors.Join(errRet, errw.Errorf("downloaded %d bytes but Content-Length is %d", written, resp.ContentLength))
+	}
+	if encoding != "" && errRet == nil {
+		errRet = errors.Join(errRet, decodeFile(out.Name(), encoding))
+	}
+
 	errRet = errors.Join(errRet, os.Rename(out.Name(), outPath), SyncFS(outPath))
 	return outPath, errRet
 }
 
+// DecompressingReader wraps r to decode the given content encoding: "zstd", "gzip", "br", or "" for none.
+func DecompressingReader(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(r), nil
+	case "zstd":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "br":
+		return io.NopCloser(brotli.NewReader(r)), nil
+	default:
+		return nil, errw.Errorf("unsupported content encoding %s", encoding)
+	}
+}
+
+// decodeFile decompresses a downloaded file in place.
+func decodeFile(filePath, encoding string) (errRet error) {
+	//nolint:gosec
+	in, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errRet = errors.Join(errRet, in.Close())
+	}()
+
+	reader, err := DecompressingReader(bufio.NewReader(in), encoding)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errRet = errors.Join(errRet, reader.Close())
+	}()
+
+	out, err := os.CreateTemp(filepath.Dir(filePath), "*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		errRet = errors.Join(errRet, out.Close())
+		if err := os.Remove(out.Name()); err != nil && !os.IsNotExist(err) {
+			errRet = errors.Join(errRet, err)
+		}
+	}()
+
+	if _, err := io.Copy(out, reader); err != nil {
+		return errw.Wrapf(err, "decoding %s download", encoding)
+	}
+	return os.Rename(out.Name(), filePath)
+}
+
 // DecompressFile extracts a compressed file and returns the path to the extracted file.
 func DecompressFile(inPath string) (outPath string, errRet error) {
 	//nolint:gosec
@@ -269,3 +384,16 @@ func SyncFS(syncPath string) (errRet error) {
 	}
 	return errors.Join(errRet, file.Close())
 }
+
+// KillProcessGroup sends a signal to the process group led by pid. As a guard against the agent killing itself
+// (for example if Setpgid didn't take effect), it refuses if that process is in the agent's own process group.
+func KillProcessGroup(pid int, sig syscall.Signal) error {
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil {
+		return errw.Wrapf(err, "getting process group for pid %d", pid)
+	}
+	if pgid == syscall.Getpgrp() {
+		return errw.Wrapf(ErrSameProcessGroup, "refusing to signal process group %d of pid %d", pgid, pid)
+	}
+	return syscall.Kill(-pid, sig)
+}
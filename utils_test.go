@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"go.viam.com/test"
+)
+
+func TestKillProcessGroup(t *testing.T) {
+	t.Run("refuses-own-group", func(t *testing.T) {
+		err := KillProcessGroup(os.Getpid(), syscall.Signal(0))
+		test.That(t, errors.Is(err, ErrSameProcessGroup), test.ShouldBeTrue)
+	})
+
+	t.Run("kills-child-group", func(t *testing.T) {
+		cmd := exec.Command("sleep", "30")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		test.That(t, cmd.Start(), test.ShouldBeNil)
+		test.That(t, KillProcessGroup(cmd.Process.Pid, syscall.SIGKILL), test.ShouldBeNil)
+		test.That(t, cmd.Wait(), test.ShouldNotBeNil)
+		test.That(t, cmd.ProcessState.Sys().(syscall.WaitStatus).Signal(), test.ShouldEqual, syscall.SIGKILL)
+	})
+}
+
+// useTempViamDirs points ViamDirs at a temporary directory for the duration of a test.
+func useTempViamDirs(t *testing.T) {
+	t.Helper()
+	orig := make(map[string]string)
+	for k, v := range ViamDirs {
+		orig[k] = v
+	}
+	root := t.TempDir()
+	for k := range ViamDirs {
+		ViamDirs[k] = filepath.Join(root, k)
+		test.That(t, os.MkdirAll(ViamDirs[k], 0o755), test.ShouldBeNil)
+	}
+	t.Cleanup(func() {
+		for k, v := range orig {
+			ViamDirs[k] = v
+		}
+	})
+}
+
+func TestDownloadFileEncodings(t *testing.T) {
+	useTempViamDirs(t)
+	payload := bytes.Repeat([]byte("viam-server binary contents "), 1000)
+
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"zstd": func(w io.Writer) io.WriteCloser {
+			enc, err := zstd.NewWriter(w)
+			test.That(t, err, test.ShouldBeNil)
+			return enc
+		},
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"br":   func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) },
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.TrimPrefix(r.URL.Path, "/")
+		if strings.HasSuffix(encoding, ".zst") {
+			encoding = "zstd"
+		} else {
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		var buf bytes.Buffer
+		enc := encoders[encoding](&buf)
+		_, err := enc.Write(payload)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, enc.Close(), test.ShouldBeNil)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		_, err = w.Write(buf.Bytes())
+		test.That(t, err, test.ShouldBeNil)
+	}))
+	defer server.Close()
+
+	for _, name := range []string{"zstd", "gzip", "br", "binary.zst"} {
+		t.Run(name, func(t *testing.T) {
+			outPath, err := DownloadFile(context.Background(), server.URL+"/"+name)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, filepath.Base(outPath), test.ShouldEqual, strings.TrimSuffix(name, ".zst"))
+			contents, err := os.ReadFile(outPath)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, contents, test.ShouldResemble, payload)
+		})
+	}
+
+	_, err := DecompressingReader(bytes.NewReader(nil), "compress")
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestEnsureViamDirs(t *testing.T) {
+	root := t.TempDir()
+	linked := filepath.Join(root, "linked")
+	test.That(t, os.Mkdir(filepath.Join(root, "real"), 0o755), test.ShouldBeNil)
+	test.That(t, os.Symlink(filepath.Join(root, "real"), linked), test.ShouldBeNil)
+	dirs := map[string]string{
+		"viam":   filepath.Join(root, "viam"),
+		"certs":  filepath.Join(root, "viam", "etc", "certs"),
+		"linked": linked,
+	}
+	test.That(t, EnsureViamDirs(dirs), test.ShouldBeNil)
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, info.IsDir(), test.ShouldBeTrue)
+	}
+	// already existing is fine
+	test.That(t, EnsureViamDirs(dirs), test.ShouldBeNil)
+
+	file := filepath.Join(root, "file")
+	test.That(t, os.WriteFile(file, nil, 0o600), test.ShouldBeNil)
+	err := EnsureViamDirs(map[string]string{"bin": file})
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldStartWith, "failed to create viam dir 'bin' at "+file+": ")
+
+	err = EnsureViamDirs(map[string]string{"bin": filepath.Join(file, "bin")})
+	test.That(t, err.Error(), test.ShouldEqual, "failed to create viam dir 'bin' at "+filepath.Join(file, "bin")+": not a directory")
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	errw "github.com/pkg/errors"
+	"go.viam.com/rdk/logging"
+)
+
+const (
+	DefaultWatchdogDevice      = "/dev/watchdog"
+	DefaultWatchdogPetInterval = time.Second * 30
+)
+
+// WatchdogPetter is a watchdog that must be petted periodically, or else it will reboot the host.
+type WatchdogPetter interface {
+	Open() error
+	Pet() error
+	Close() error
+}
+
+// NoopWatchdogPetter is used when no watchdog is configured.
+type NoopWatchdogPetter struct{}
+
+// Open does nothing.
+func (NoopWatchdogPetter) Open() error { return nil }
+
+// Pet does nothing.
+func (NoopWatchdogPetter) Pet() error { return nil }
+
+// Close does nothing.
+func (NoopWatchdogPetter) Close() error { return nil }
+
+// LinuxWatchdogPetter pets a Linux watchdog device, such as /dev/watchdog.
+type LinuxWatchdogPetter struct {
+	Device string
+	file   *os.File
+}
+
+// Open opens the device, which arms the watchdog.
+func (w *LinuxWatchdogPetter) Open() error {
+	file, err := os.OpenFile(w.Device, os.O_WRONLY, 0)
+	if err != nil {
+		return errw.Wrapf(err, "opening watchdog device %s", w.Device)
+	}
+	w.file = file
+	return nil
+}
+
+// Pet resets the watchdog timer.
+func (w *LinuxWatchdogPetter) Pet() error {
+	if w.file == nil {
+		return errw.Errorf("watchdog device %s not open", w.Device)
+	}
+	_, err := w.file.Write([]byte{0})
+	return err
+}
+
+// Close disarms the watchdog (if the driver allows it) via the "magic close" character, and closes the device.
+func (w *LinuxWatchdogPetter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	_, err := w.file.Write([]byte("V"))
+	if closeErr := w.file.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	return err
+}
+
+// HardwareWatchdog pets a WatchdogPetter on an interval, but only while subsystems are reported healthy.
+// If the agent hangs (health isn't reported within the max check age), or subsystems stay unhealthy, the watchdog will
+// eventually reboot the host.
+type HardwareWatchdog struct {
+	logger   logging.Logger
+	petter   WatchdogPetter
+	interval time.Duration
+	healthy  atomic.Bool
+	// when SetHealthy was last called, in unix nanoseconds, zero if it hasn't been
+	lastCheck atomic.Int64
+	// see SetMaxCheckAge, zero for no limit
+	maxCheckAge atomic.Int64
+
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// NewHardwareWatchdog returns a HardwareWatchdog. It is considered healthy until told otherwise via SetHealthy.
+func NewHardwareWatchdog(logger logging.Logger, petter WatchdogPetter, interval time.Duration) *HardwareWatchdog {
+	w := &HardwareWatchdog{logger: logger, petter: petter, interval: interval}
+	w.healthy.Store(true)
+	return w
+}
+
+// Start opens the watchdog and begins petting it in the background.
+func (w *HardwareWatchdog) Start(ctx context.Context) error {
+	if err := w.petter.Open(); err != nil {
+		return err
+	}
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.workers.Add(1)
+	go func() {
+		defer w.workers.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			if age, stale := w.checkAge(); stale {
+				w.logger.Warnf("no subsystem health checks for %s, agent may be hung, not petting watchdog", age.Round(time.Second))
+			} else if w.healthy.Load() {
+				if err := w.petter.Pet(); err != nil {
+					w.logger.Error(errw.Wrap(err, "petting watchdog"))
+				}
+			} else {
+				w.logger.Warn("subsystems unhealthy, not petting watchdog")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// SetHealthy records the result of the latest round of subsystem health checks.
+func (w *HardwareWatchdog) SetHealthy(healthy bool) {
+	w.healthy.Store(healthy)
+	w.lastCheck.Store(time.Now().UnixNano())
+}
+
+// SetMaxCheckAge stops petting once the latest round of health checks (from SetHealthy) is older than maxAge, as
+// whatever runs them has hung. Nothing is stale until SetHealthy is first called, so startup isn't limited.
+func (w *HardwareWatchdog) SetMaxCheckAge(maxAge time.Duration) {
+	w.maxCheckAge.Store(int64(maxAge))
+}
+
+// checkAge returns how old the latest round of health checks is, and whether that's over the max check age.
+func (w *HardwareWatchdog) checkAge() (time.Duration, bool) {
+	last := w.lastCheck.Load()
+	maxAge := time.Duration(w.maxCheckAge.Load())
+	if last == 0 || maxAge <= 0 {
+		return 0, false
+	}
+	age := time.Since(time.Unix(0, last))
+	return age, age > maxAge
+}
+
+// Close stops petting and disarms the watchdog.
+func (w *HardwareWatchdog) Close() error {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.workers.Wait()
+	return w.petter.Close()
+}
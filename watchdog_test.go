@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/test"
+)
+
+type fakePetter struct {
+	opened, closed bool
+	pets           atomic.Int32
+}
+
+func (f *fakePetter) Open() error  { f.opened = true; return nil }
+func (f *fakePetter) Pet() error   { f.pets.Add(1); return nil }
+func (f *fakePetter) Close() error { f.closed = true; return nil }
+
+func TestHardwareWatchdog(t *testing.T) {
+	petter := &fakePetter{}
+	watchdog := NewHardwareWatchdog(logging.NewTestLogger(t), petter, time.Millisecond*10)
+	test.That(t, watchdog.Start(context.Background()), test.ShouldBeNil)
+	test.That(t, petter.opened, test.ShouldBeTrue)
+
+	time.Sleep(time.Millisecond * 50)
+	test.That(t, petter.pets.Load(), test.ShouldBeGreaterThan, 0)
+
+	// no petting while unhealthy
+	watchdog.SetHealthy(false)
+	time.Sleep(time.Millisecond * 20)
+	pets := petter.pets.Load()
+	time.Sleep(time.Millisecond * 50)
+	test.That(t, petter.pets.Load(), test.ShouldEqual, pets)
+
+	watchdog.SetHealthy(true)
+	time.Sleep(time.Millisecond * 50)
+	test.That(t, petter.pets.Load(), test.ShouldBeGreaterThan, pets)
+
+	test.That(t, watchdog.Close(), test.ShouldBeNil)
+	test.That(t, petter.closed, test.ShouldBeTrue)
+}
+
+func TestHardwareWatchdogStale(t *testing.T) {
+	petter := &fakePetter{}
+	watchdog := NewHardwareWatchdog(logging.NewTestLogger(t), petter, time.Millisecond*10)
+	watchdog.SetMaxCheckAge(time.Millisecond * 100)
+	test.That(t, watchdog.Start(context.Background()), test.ShouldBeNil)
+	defer func() { test.That(t, watchdog.Close(), test.ShouldBeNil) }()
+
+	// not stale before the first check, however long that takes
+	time.Sleep(time.Millisecond * 150)
+	test.That(t, petter.pets.Load(), test.ShouldBeGreaterThan, 10)
+
+	// healthy, but no check since, as if the manager loop hung
+	watchdog.SetHealthy(true)
+	time.Sleep(time.Millisecond * 150)
+	pets := petter.pets.Load()
+	time.Sleep(time.Millisecond * 50)
+	test.That(t, petter.pets.Load(), test.ShouldEqual, pets)
+
+	// resumes once checks do
+	watchdog.SetHealthy(true)
+	time.Sleep(time.Millisecond * 50)
+	test.That(t, petter.pets.Load(), test.ShouldBeGreaterThan, pets)
+}